@@ -0,0 +1,91 @@
+package client
+
+import (
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/balancer/roundrobin"
+)
+
+// the load balancing policies of the connections opened by Dial
+const (
+	PickFirst    = "pick_first" // the first reachable server gets every call, the others are used on failure
+	RoundRobin   = roundrobin.Name
+	LeastRequest = "least_request" // the server with the fewest calls in flight gets the next call
+)
+
+func init() {
+	balancer.Register(leastRequestBuilder{})
+}
+
+// leastRequestBuilder builds a balancer per connection, so that the calls in flight are counted per connection
+type leastRequestBuilder struct{}
+
+func (leastRequestBuilder) Name() string {
+	return LeastRequest
+}
+
+func (leastRequestBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pickerBuilder := &leastRequestPickerBuilder{inFlight: make(map[balancer.SubConn]*int64)}
+	return base.NewBalancerBuilder(LeastRequest, pickerBuilder, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+// leastRequestPickerBuilder keeps the counters of the servers which stay ready across the pickers
+type leastRequestPickerBuilder struct {
+	inFlight map[balancer.SubConn]*int64
+}
+
+func (builder *leastRequestPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	picker := &leastRequestPicker{}
+	inFlight := make(map[balancer.SubConn]*int64, len(info.ReadySCs))
+	for subConn := range info.ReadySCs {
+		counter, ok := builder.inFlight[subConn]
+		if !ok {
+			counter = new(int64)
+		}
+		inFlight[subConn] = counter
+
+		picker.subConns = append(picker.subConns, subConn)
+		picker.inFlight = append(picker.inFlight, counter)
+	}
+	builder.inFlight = inFlight
+
+	return picker
+}
+
+type leastRequestPicker struct {
+	subConns []balancer.SubConn
+	inFlight []*int64
+	next     uint32
+}
+
+// Pick returns the server with the fewest calls in flight,
+// the search starts after the last pick so that the idle servers take turns
+func (picker *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	start := int(atomic.AddUint32(&picker.next, 1))
+
+	picked := -1
+	least := int64(0)
+	for i := 0; i < len(picker.subConns); i++ {
+		index := (start + i) % len(picker.subConns)
+		inFlight := atomic.LoadInt64(picker.inFlight[index])
+		if picked == -1 || inFlight < least {
+			picked, least = index, inFlight
+		}
+	}
+
+	counter := picker.inFlight[picked]
+	atomic.AddInt64(counter, 1)
+
+	return balancer.PickResult{
+		SubConn: picker.subConns[picked],
+		Done: func(balancer.DoneInfo) {
+			atomic.AddInt64(counter, -1)
+		},
+	}, nil
+}
@@ -0,0 +1,277 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/client"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const blockedLaptopId = "blocked"
+
+// balancedServer is one of the servers the calls are spread over, it counts the calls it receives
+type balancedServer struct {
+	address string
+	health  *health.Server
+	calls   int32
+	blocked chan struct{} // the calls getting the blocked laptop wait until it is closed
+}
+
+func startBalancedServers(t *testing.T, n int) []*balancedServer {
+	servers := make([]*balancedServer, n)
+	for i := range servers {
+		server := &balancedServer{health: health.NewServer(), blocked: make(chan struct{})}
+		t.Cleanup(func() { server.release() })
+
+		grpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			atomic.AddInt32(&server.calls, 1)
+			if req, ok := req.(*pb.GetLaptopRequest); ok && req.GetId() == blockedLaptopId {
+				<-server.blocked
+			}
+			return handler(ctx, req)
+		}))
+		healthpb.RegisterHealthServer(grpcServer, server.health)
+
+		server.address = serve(t, grpcServer, service.NewLaptopServer(service.NewInMemoryLaptopStore(), nil, nil))
+		servers[i] = server
+	}
+	return servers
+}
+
+func (server *balancedServer) callCount() int {
+	return int(atomic.LoadInt32(&server.calls))
+}
+
+func (server *balancedServer) release() {
+	select {
+	case <-server.blocked:
+	default:
+		close(server.blocked)
+	}
+}
+
+func addresses(servers []*balancedServer) []string {
+	addresses := []string{}
+	for _, server := range servers {
+		addresses = append(addresses, server.address)
+	}
+	return addresses
+}
+
+// callCounts returns how many calls every server got, and resets the counts
+func callCounts(servers []*balancedServer) []int {
+	counts := []int{}
+	for _, server := range servers {
+		counts = append(counts, int(atomic.SwapInt32(&server.calls, 0)))
+	}
+	return counts
+}
+
+// getLaptop calls a server, the laptop is not found as the stores are empty
+func getLaptop(t *testing.T, laptopClient *client.Client) {
+	_, err := laptopClient.GetLaptop(context.Background(), "4bd5e3d1-ff23-4ad6-a5b2-4dd84f8ba2cb", client.NoRetry())
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// waitForServers calls the servers until every one of them got a call, i.e. until they are all ready
+func waitForServers(t *testing.T, laptopClient *client.Client, servers []*balancedServer) {
+	require.Eventually(t, func() bool {
+		getLaptop(t, laptopClient)
+		for _, server := range servers {
+			if server.callCount() == 0 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, time.Millisecond)
+
+	callCounts(servers)
+}
+
+func TestDialRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 3)
+
+	laptopClient, err := client.Dial(strings.Join(addresses(servers), ","), client.WithBalancing(client.RoundRobin))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	waitForServers(t, laptopClient, servers)
+
+	for i := 0; i < 9; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{3, 3, 3}, callCounts(servers))
+}
+
+func TestDialPickFirst(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 2)
+
+	laptopClient, err := client.Dial(strings.Join(addresses(servers), ","), client.WithBalancing(client.PickFirst))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{4, 0}, callCounts(servers))
+}
+
+func TestDialLeastRequest(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 2)
+
+	laptopClient, err := client.Dial(strings.Join(addresses(servers), ","), client.WithBalancing(client.LeastRequest))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	waitForServers(t, laptopClient, servers)
+
+	// the idle servers take turns
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{2, 2}, callCounts(servers))
+
+	// a server busy with a call gets no other call until it is done
+	blockedDone := make(chan error)
+	go func() {
+		_, err := laptopClient.GetLaptop(context.Background(), blockedLaptopId, client.NoRetry(), client.Timeout(0))
+		blockedDone <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		return servers[0].callCount()+servers[1].callCount() == 1
+	}, 5*time.Second, time.Millisecond)
+	busy := 0
+	if servers[1].callCount() == 1 {
+		busy = 1
+	}
+	callCounts(servers)
+
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Zero(t, servers[busy].callCount())
+	require.Equal(t, 4, servers[1-busy].callCount())
+
+	servers[busy].release()
+	require.Equal(t, codes.InvalidArgument, status.Code(<-blockedDone))
+}
+
+func TestDialHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 2)
+	serviceName := pb.LaptopService_ServiceDesc.ServiceName
+	servers[0].health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	servers[1].health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+
+	laptopClient, err := client.Dial(strings.Join(addresses(servers), ","), client.WithHealthCheck(serviceName))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{0, 4}, callCounts(servers))
+
+	servers[0].health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+	servers[1].health.SetServingStatus(serviceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	require.Eventually(t, func() bool {
+		callCounts(servers)
+		getLaptop(t, laptopClient)
+		getLaptop(t, laptopClient)
+		return servers[0].callCount() == 2
+	}, 5*time.Second, time.Millisecond)
+	callCounts(servers)
+
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{4, 0}, callCounts(servers))
+}
+
+func TestDialFileResolver(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 3)
+
+	filename := filepath.Join(t.TempDir(), "servers.txt")
+	content := "# the first two servers\n" + servers[0].address + "\n\n" + servers[1].address + "\n"
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+
+	laptopClient, err := client.Dial("file://"+filename, client.WithRefreshInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	waitForServers(t, laptopClient, servers[:2])
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{2, 2, 0}, callCounts(servers))
+
+	// the servers listed in the file are replaced
+	require.NoError(t, os.WriteFile(filename, []byte(servers[2].address+"\n"), 0o644))
+
+	require.Eventually(t, func() bool {
+		callCounts(servers)
+		getLaptop(t, laptopClient)
+		return servers[2].callCount() == 1
+	}, 5*time.Second, time.Millisecond)
+	callCounts(servers)
+
+	for i := 0; i < 4; i++ {
+		getLaptop(t, laptopClient)
+	}
+	require.Equal(t, []int{0, 0, 4}, callCounts(servers))
+
+	// an invalid file keeps the servers
+	require.NoError(t, os.WriteFile(filename, []byte("# no server\n"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	getLaptop(t, laptopClient)
+	require.Equal(t, []int{0, 0, 1}, callCounts(servers))
+}
+
+func TestDialErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := client.Dial("localhost:8080", client.WithBalancing("unknown"))
+	require.Error(t, err)
+
+	_, err = client.Dial("file://" + filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestDialDNS(t *testing.T) {
+	t.Parallel()
+
+	servers := startBalancedServers(t, 1)
+	_, port, found := strings.Cut(servers[0].address, "]:")
+	require.True(t, found)
+
+	laptopClient, err := client.Dial("dns:///localhost:" + port)
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	getLaptop(t, laptopClient)
+	require.Equal(t, []int{1}, callCounts(servers))
+}
@@ -0,0 +1,220 @@
+// Package client is the Go SDK of the laptop service: it dials the server, applies the deadlines,
+// retries the idempotent calls and hides the streams of the search and of the images.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // enables the health checks of WithHealthCheck
+	"google.golang.org/grpc/status"
+)
+
+type Client struct {
+	conn         *grpc.ClientConn // nil if the connection is owned by the caller
+	laptopClient pb.LaptopServiceClient
+	options      options
+}
+
+// Dial connects to the laptop server, the client must be closed once it is not used anymore.
+// The address is either:
+//   - host:port
+//   - a comma separated list of host:port
+//   - dns:///host:port, every address of the host is used
+//   - file:///path, the file lists one host:port per line and is read again every refresh interval
+func Dial(address string, opts ...Option) (*Client, error) {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	serviceConfig, err := options.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+	target, resolverOptions := options.target(address)
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}
+	if options.tlsConfig != nil {
+		dialOptions[0] = grpc.WithTransportCredentials(credentials.NewTLS(options.tlsConfig))
+	}
+	if options.bearerToken != "" {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(bearerToken{
+			token:  options.bearerToken,
+			secure: options.tlsConfig != nil,
+		}))
+	}
+	if options.perRPC != nil {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(options.perRPC))
+	}
+
+	dialOptions = append(dialOptions, resolverOptions...)
+
+	conn, err := grpc.Dial(target, append(dialOptions, options.dialOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{conn: conn, laptopClient: pb.NewLaptopServiceClient(conn), options: options}, nil
+}
+
+// serviceConfig returns the gRPC service config of the balancing and health check options
+func (options options) serviceConfig() (string, error) {
+	if balancer.Get(options.balancing) == nil {
+		return "", fmt.Errorf("unknown balancing policy %q", options.balancing)
+	}
+
+	serviceConfig := map[string]interface{}{
+		"loadBalancingConfig": []map[string]interface{}{{options.balancing: struct{}{}}},
+	}
+	if options.healthCheck != "" {
+		serviceConfig["healthCheckConfig"] = map[string]string{"serviceName": options.healthCheck}
+	}
+
+	data, err := json.Marshal(serviceConfig)
+	return string(data), err
+}
+
+// New wraps a connection owned by the caller, the TLS, credentials and dial options are ignored
+func New(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &Client{laptopClient: pb.NewLaptopServiceClient(conn), options: options}
+}
+
+// Close closes the connection opened by Dial
+func (client *Client) Close() error {
+	if client.conn == nil {
+		return nil
+	}
+	return client.conn.Close()
+}
+
+// LaptopService returns the generated client, for the calls the SDK doesn't wrap
+func (client *Client) LaptopService() pb.LaptopServiceClient {
+	return client.laptopClient
+}
+
+// CreateLaptop saves the laptop and returns its id, it is not retried as the laptop may have been created
+func (client *Client) CreateLaptop(ctx context.Context, laptop *pb.Laptop, opts ...CallOption) (string, error) {
+	var res *pb.CreateLaptopResponse
+	err := client.call(ctx, false, opts, func(ctx context.Context) (err error) {
+		res, err = client.laptopClient.CreateLaptop(ctx, &pb.CreateLaptopRequest{Laptop: laptop})
+		return err
+	})
+	return res.GetId(), err
+}
+
+func (client *Client) GetLaptop(ctx context.Context, laptopId string, opts ...CallOption) (*pb.Laptop, error) {
+	var res *pb.GetLaptopResponse
+	err := client.call(ctx, true, opts, func(ctx context.Context) (err error) {
+		res, err = client.laptopClient.GetLaptop(ctx, &pb.GetLaptopRequest{Id: laptopId})
+		return err
+	})
+	return res.GetLaptop(), err
+}
+
+// UpdateLaptop replaces the laptop having the same id, saving the same laptop twice is harmless so it is retried
+func (client *Client) UpdateLaptop(ctx context.Context, laptop *pb.Laptop, opts ...CallOption) error {
+	return client.call(ctx, true, opts, func(ctx context.Context) error {
+		_, err := client.laptopClient.UpdateLaptop(ctx, &pb.UpdateLaptopRequest{Laptop: laptop})
+		return err
+	})
+}
+
+// DeleteLaptop is not retried, a retry would fail with NotFound if the first attempt deleted the laptop
+func (client *Client) DeleteLaptop(ctx context.Context, laptopId string, opts ...CallOption) error {
+	return client.call(ctx, false, opts, func(ctx context.Context) error {
+		_, err := client.laptopClient.DeleteLaptop(ctx, &pb.DeleteLaptopRequest{Id: laptopId})
+		return err
+	})
+}
+
+// call runs the call with the deadline of the options, and retries it if it is idempotent
+func (client *Client) call(ctx context.Context, idempotent bool, opts []CallOption, call func(ctx context.Context) error) error {
+	options := client.callOptions(opts)
+
+	ctx, cancel := withTimeout(ctx, options.timeout)
+	defer cancel()
+
+	retrier := client.newRetrier(idempotent && !options.noRetry)
+	for {
+		err := call(ctx)
+		if err == nil || !retrier.wait(ctx, err) {
+			return err
+		}
+	}
+}
+
+// withTimeout sets the deadline of the context unless it already has one
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// retrier waits between the attempts of a call with an exponential backoff
+type retrier struct {
+	policy  RetryPolicy
+	attempt int
+	backoff time.Duration
+}
+
+func (client *Client) newRetrier(enabled bool) *retrier {
+	policy := client.options.retryPolicy
+	if !enabled {
+		policy.MaxAttempts = 1
+	}
+	return &retrier{policy: policy, attempt: 1, backoff: policy.InitialBackoff}
+}
+
+// wait sleeps before the next attempt and returns true, or returns false if the error must not be retried
+func (retrier *retrier) wait(ctx context.Context, err error) bool {
+	if retrier.attempt >= retrier.policy.MaxAttempts || !retrier.retryable(err) {
+		return false
+	}
+
+	// the jitter spreads the retries of the clients failing at the same time
+	delay := retrier.backoff/2 + time.Duration(rand.Int63n(int64(retrier.backoff/2)+1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	}
+
+	retrier.attempt++
+	retrier.backoff = time.Duration(float64(retrier.backoff) * retrier.policy.Multiplier)
+	if retrier.backoff > retrier.policy.MaxBackoff {
+		retrier.backoff = retrier.policy.MaxBackoff
+	}
+	return true
+}
+
+func (retrier *retrier) retryable(err error) bool {
+	code := status.Code(err)
+	for _, retryable := range retrier.policy.Codes {
+		if code == retryable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/client"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPolicy retries quickly so that the tests don't wait
+var testPolicy = client.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Multiplier:     2,
+	Codes:          []codes.Code{codes.Unavailable},
+}
+
+func TestClientLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	address := startTestServer(t, service.NewLaptopServer(laptopStore, nil, nil))
+
+	laptopClient, err := client.Dial(address)
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	ctx := context.Background()
+	laptop := sample.NewLaptop()
+
+	id, err := laptopClient.CreateLaptop(ctx, laptop)
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), id)
+
+	found, err := laptopClient.GetLaptop(ctx, id)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(laptop, found))
+
+	laptop.Name = "Renamed"
+	require.NoError(t, laptopClient.UpdateLaptop(ctx, laptop))
+
+	found, err = laptopClient.GetLaptop(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "Renamed", found.GetName())
+
+	require.NoError(t, laptopClient.DeleteLaptop(ctx, id))
+
+	_, err = laptopClient.GetLaptop(ctx, id)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClientSearchLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	expected := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+		expected[laptop.GetId()] = true
+	}
+
+	laptopClient := client.New(dialTestServer(t, service.NewLaptopServer(laptopStore, nil, nil)))
+
+	req := &pb.SearchLaptopRequest{
+		Filter:     &pb.Filter{MaxPriceUsd: 5000},
+		PriceOrder: pb.SearchLaptopRequest_ASCENDING,
+	}
+
+	laptops, err := laptopClient.SearchLaptop(context.Background(), req).Collect()
+	require.NoError(t, err)
+	require.Len(t, laptops, len(expected))
+	for i, laptop := range laptops {
+		require.True(t, expected[laptop.GetId()])
+		if i > 0 {
+			require.LessOrEqual(t, laptops[i-1].GetPriceUsd(), laptop.GetPriceUsd())
+		}
+	}
+
+	// stopping early cancels the search
+	it := laptopClient.SearchLaptop(context.Background(), req)
+	require.True(t, it.Next())
+	it.Close()
+	require.False(t, it.Next())
+	require.NoError(t, it.Err())
+
+	// invalid filters are not retried
+	it = laptopClient.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: &pb.Filter{MaxPriceUsd: -1}})
+	require.False(t, it.Next())
+	require.Equal(t, codes.InvalidArgument, status.Code(it.Err()))
+}
+
+func TestClientRetry(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	server := &flakyServer{LaptopServer: service.NewLaptopServer(laptopStore, nil, nil)}
+	conn := dialTestServer(t, server)
+
+	// the idempotent calls are retried
+	atomic.StoreInt32(&server.failures, 2)
+	laptopClient := client.New(conn, client.WithRetryPolicy(testPolicy))
+	found, err := laptopClient.GetLaptop(context.Background(), laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), found.GetId())
+
+	atomic.StoreInt32(&server.failures, 2)
+	laptops, err := laptopClient.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: &pb.Filter{MaxPriceUsd: 5000}}).Collect()
+	require.NoError(t, err)
+	require.Len(t, laptops, 1)
+
+	// until the attempts are exhausted
+	atomic.StoreInt32(&server.failures, 3)
+	_, err = laptopClient.GetLaptop(context.Background(), laptop.GetId())
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	// creations are not idempotent
+	atomic.StoreInt32(&server.failures, 1)
+	_, err = laptopClient.CreateLaptop(context.Background(), sample.NewLaptop())
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	atomic.StoreInt32(&server.failures, 1)
+	_, err = laptopClient.GetLaptop(context.Background(), laptop.GetId(), client.NoRetry())
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	atomic.StoreInt32(&server.failures, 1)
+	_, err = client.New(conn, client.WithoutRetry()).GetLaptop(context.Background(), laptop.GetId())
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestClientTimeout(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	server := &flakyServer{LaptopServer: service.NewLaptopServer(laptopStore, nil, nil), delay: 200 * time.Millisecond}
+	conn := dialTestServer(t, server)
+
+	laptopClient := client.New(conn, client.WithTimeout(20*time.Millisecond))
+	_, err := laptopClient.GetLaptop(context.Background(), laptop.GetId())
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	// the timeout of a call overrides the one of the client
+	_, err = laptopClient.GetLaptop(context.Background(), laptop.GetId(), client.Timeout(time.Second))
+	require.NoError(t, err)
+
+	// the deadline of the context is kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = laptopClient.GetLaptop(ctx, laptop.GetId())
+	require.NoError(t, err)
+}
+
+func TestClientBearerToken(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	authorization := []string{}
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mutex.Lock()
+		authorization = append(authorization, md.Get("authorization")...)
+		mutex.Unlock()
+		return handler(ctx, req)
+	}))
+	address := serve(t, grpcServer, service.NewLaptopServer(service.NewInMemoryLaptopStore(), nil, nil))
+
+	laptopClient, err := client.Dial(address, client.WithBearerToken("secret"))
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	_, err = laptopClient.CreateLaptop(context.Background(), sample.NewLaptop())
+	require.NoError(t, err)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, []string{"Bearer secret"}, authorization)
+}
+
+func TestClientImages(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	laptopClient := client.New(dialTestServer(t, service.NewLaptopServer(laptopStore, imageStore, nil)))
+
+	image := bytes.Repeat([]byte("0123456789"), 1000)
+	progress := []int64{}
+
+	res, err := laptopClient.UploadImage(context.Background(), laptop.GetId(), ".png", bytes.NewReader(image),
+		client.ChunkSize(4000),
+		client.OnProgress(func(sent int64) { progress = append(progress, sent) }),
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, len(image), res.GetSize())
+	require.Equal(t, []int64{4000, 8000, 10000}, progress)
+
+	var downloaded bytes.Buffer
+	info, err := laptopClient.DownloadImage(context.Background(), res.GetId(), &downloaded)
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), info.GetLaptopId())
+	require.Equal(t, ".png", info.GetImageType())
+	require.Equal(t, image, downloaded.Bytes())
+
+	// the error of the server is returned instead of the io.EOF of the stream
+	_, err = laptopClient.UploadImage(context.Background(), sample.NewLaptop().GetId(), ".png", bytes.NewReader(image), client.ChunkSize(10))
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = laptopClient.DownloadImage(context.Background(), "unknown", &downloaded)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// flakyServer fails the next calls with Unavailable, and delays the laptop lookups
+type flakyServer struct {
+	*service.LaptopServer
+	failures int32
+	delay    time.Duration
+}
+
+func (server *flakyServer) fail() error {
+	if atomic.AddInt32(&server.failures, -1) >= 0 {
+		return status.Error(codes.Unavailable, "server is flaky")
+	}
+	return nil
+}
+
+func (server *flakyServer) CreateLaptop(ctx context.Context, req *pb.CreateLaptopRequest) (*pb.CreateLaptopResponse, error) {
+	if err := server.fail(); err != nil {
+		return nil, err
+	}
+	return server.LaptopServer.CreateLaptop(ctx, req)
+}
+
+func (server *flakyServer) GetLaptop(ctx context.Context, req *pb.GetLaptopRequest) (*pb.GetLaptopResponse, error) {
+	if err := server.fail(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(server.delay):
+	case <-ctx.Done():
+	}
+	return server.LaptopServer.GetLaptop(ctx, req)
+}
+
+func (server *flakyServer) SearchLaptop(req *pb.SearchLaptopRequest, stream pb.LaptopService_SearchLaptopServer) error {
+	if err := server.fail(); err != nil {
+		return err
+	}
+	return server.LaptopServer.SearchLaptop(req, stream)
+}
+
+func startTestServer(t *testing.T, laptopServer pb.LaptopServiceServer) string {
+	return serve(t, grpc.NewServer(), laptopServer)
+}
+
+func serve(t *testing.T, grpcServer *grpc.Server, laptopServer pb.LaptopServiceServer) string {
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+func dialTestServer(t *testing.T, laptopServer pb.LaptopServiceServer) *grpc.ClientConn {
+	conn, err := grpc.Dial(startTestServer(t, laptopServer), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
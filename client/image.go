@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// UploadImage sends the image read from r in chunks, imageType is the extension of the image such as ".png".
+// The upload is not retried since the reader is consumed
+func (client *Client) UploadImage(ctx context.Context, laptopId string, imageType string, r io.Reader, opts ...CallOption) (*pb.UploadImageResponse, error) {
+	options := client.callOptions(opts)
+	if options.chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", options.chunkSize)
+	}
+
+	ctx, cancel := withTimeout(ctx, options.timeout)
+	defer cancel()
+
+	stream, err := client.laptopClient.UploadImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadImageRequest{
+		Data: &pb.UploadImageRequest_Info{
+			Info: &pb.ImageInfo{LaptopId: laptopId, ImageType: imageType},
+		},
+	})
+	if err != nil {
+		return nil, closeUpload(stream, err)
+	}
+
+	buffer := make([]byte, options.chunkSize)
+	sent := int64(0)
+
+	for {
+		n, err := io.ReadFull(r, buffer)
+		if n > 0 {
+			sendErr := stream.Send(&pb.UploadImageRequest{
+				Data: &pb.UploadImageRequest_ChunkData{ChunkData: buffer[:n]},
+			})
+			if sendErr != nil {
+				return nil, closeUpload(stream, sendErr)
+			}
+
+			sent += int64(n)
+			if options.progress != nil {
+				options.progress(sent)
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read image: %w", err)
+		}
+	}
+
+	return stream.CloseAndRecv()
+}
+
+// closeUpload returns the error of the server once it stopped the upload, Send only returns io.EOF
+func closeUpload(stream pb.LaptopService_UploadImageClient, err error) error {
+	if err != io.EOF {
+		return err
+	}
+
+	_, err = stream.CloseAndRecv()
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// DownloadImage writes the image to w and returns its info, the download is retried as long as nothing is written
+func (client *Client) DownloadImage(ctx context.Context, imageId string, w io.Writer, opts ...CallOption) (*pb.ImageInfo, error) {
+	options := client.callOptions(opts)
+
+	ctx, cancel := withTimeout(ctx, options.timeout)
+	defer cancel()
+
+	var info *pb.ImageInfo
+	received := int64(0)
+
+	retrier := client.newRetrier(!options.noRetry)
+	for {
+		var err error
+		info, err = client.downloadImage(ctx, imageId, w, options, &received)
+		if err == nil || received > 0 || !retrier.wait(ctx, err) {
+			return info, err
+		}
+	}
+}
+
+func (client *Client) downloadImage(ctx context.Context, imageId string, w io.Writer, options callOptions, received *int64) (*pb.ImageInfo, error) {
+	stream, err := client.laptopClient.DownloadImage(ctx, &pb.DownloadImageRequest{ImageId: imageId})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := stream.Recv()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF // the info always comes first
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := res.GetInfo()
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return info, nil
+		}
+		if err != nil {
+			return info, err
+		}
+
+		n, err := w.Write(res.GetChunkData())
+		*received += int64(n)
+		if err != nil {
+			return info, fmt.Errorf("cannot write image: %w", err)
+		}
+
+		if options.progress != nil {
+			options.progress(*received)
+		}
+	}
+}
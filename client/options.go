@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultChunkSize = 64 << 10
+)
+
+// RetryPolicy tells how the idempotent calls failing with one of the codes are retried
+type RetryPolicy struct {
+	MaxAttempts    int // including the first attempt, 1 disables the retries
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Codes          []codes.Code
+}
+
+// DefaultRetryPolicy retries the calls failing because the server is unavailable or overloaded
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+	Codes:          []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+}
+
+type options struct {
+	tlsConfig   *tls.Config
+	perRPC      credentials.PerRPCCredentials
+	bearerToken string
+	timeout     time.Duration
+	retryPolicy RetryPolicy
+	chunkSize   int
+	dialOptions []grpc.DialOption
+
+	balancing       string
+	healthCheck     string // the service whose health is checked, empty disables the health checks
+	refreshInterval time.Duration
+}
+
+func defaultOptions() options {
+	return options{
+		timeout:         DefaultTimeout,
+		retryPolicy:     DefaultRetryPolicy,
+		chunkSize:       DefaultChunkSize,
+		balancing:       RoundRobin,
+		refreshInterval: DefaultRefreshInterval,
+	}
+}
+
+// Option configures a Client
+type Option func(*options)
+
+// WithTLS connects to the server with TLS, the connection is insecure by default
+func WithTLS(config *tls.Config) Option {
+	return func(options *options) {
+		options.tlsConfig = config
+	}
+}
+
+// WithBearerToken sends the token in the authorization metadata of every call
+func WithBearerToken(token string) Option {
+	return func(options *options) {
+		options.bearerToken = token
+	}
+}
+
+// WithPerRPCCredentials attaches the credentials to every call
+func WithPerRPCCredentials(creds credentials.PerRPCCredentials) Option {
+	return func(options *options) {
+		options.perRPC = creds
+	}
+}
+
+// WithTimeout sets the deadline of every call which has none, 0 leaves the calls without deadline
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.timeout = timeout
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(options *options) {
+		options.retryPolicy = policy
+	}
+}
+
+func WithoutRetry() Option {
+	return func(options *options) {
+		options.retryPolicy.MaxAttempts = 1
+	}
+}
+
+// WithChunkSize sets the default size of the image chunks sent by UploadImage
+func WithChunkSize(size int) Option {
+	return func(options *options) {
+		options.chunkSize = size
+	}
+}
+
+// WithDialOptions adds gRPC dial options, e.g. interceptors, they are ignored by New
+func WithDialOptions(dialOptions ...grpc.DialOption) Option {
+	return func(options *options) {
+		options.dialOptions = append(options.dialOptions, dialOptions...)
+	}
+}
+
+// WithBalancing sets how the calls are spread over the addresses of the server: PickFirst, RoundRobin or LeastRequest.
+// The calls are spread with RoundRobin by default
+func WithBalancing(policy string) Option {
+	return func(options *options) {
+		options.balancing = policy
+	}
+}
+
+// WithHealthCheck stops sending calls to the servers which don't report the service as serving
+// through the gRPC health service, it is ignored by PickFirst
+func WithHealthCheck(serviceName string) Option {
+	return func(options *options) {
+		options.healthCheck = serviceName
+	}
+}
+
+// WithRefreshInterval sets how often the file of a file:/// address is read again
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.refreshInterval = interval
+	}
+}
+
+type callOptions struct {
+	timeout   time.Duration
+	noRetry   bool
+	chunkSize int
+	progress  func(bytes int64)
+}
+
+// CallOption changes the client options for a single call
+type CallOption func(*callOptions)
+
+// Timeout sets the deadline of the call, 0 leaves the call without deadline
+func Timeout(timeout time.Duration) CallOption {
+	return func(options *callOptions) {
+		options.timeout = timeout
+	}
+}
+
+// NoRetry disables the retries of the call
+func NoRetry() CallOption {
+	return func(options *callOptions) {
+		options.noRetry = true
+	}
+}
+
+// ChunkSize sets the size of the image chunks sent by UploadImage
+func ChunkSize(size int) CallOption {
+	return func(options *callOptions) {
+		options.chunkSize = size
+	}
+}
+
+// OnProgress is called with the total number of bytes sent or received after every image chunk
+func OnProgress(progress func(bytes int64)) CallOption {
+	return func(options *callOptions) {
+		options.progress = progress
+	}
+}
+
+func (client *Client) callOptions(opts []CallOption) callOptions {
+	options := callOptions{
+		timeout:   client.options.timeout,
+		chunkSize: client.options.chunkSize,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// bearerToken implements credentials.PerRPCCredentials with an authorization metadata
+type bearerToken struct {
+	token  string
+	secure bool
+}
+
+func (creds bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + creds.token}, nil
+}
+
+func (creds bearerToken) RequireTransportSecurity() bool {
+	return creds.secure
+}
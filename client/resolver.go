@@ -0,0 +1,197 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	listScheme = "pcbook-list"
+	fileScheme = "file"
+)
+
+// DefaultRefreshInterval is how often the file of a file:/// address is read again
+const DefaultRefreshInterval = 10 * time.Second
+
+// target returns the gRPC target of the address and the dial options of its resolver:
+// a comma separated list of addresses and file:/// addresses are resolved by the client,
+// the other addresses, such as dns:///host:port, by the resolvers of gRPC
+func (options options) target(address string) (string, []grpc.DialOption) {
+	switch {
+	case strings.HasPrefix(address, fileScheme+":"):
+		return address, []grpc.DialOption{grpc.WithResolvers(&fileResolverBuilder{interval: options.refreshInterval})}
+	case strings.Contains(address, ",") && !strings.Contains(address, "://"):
+		return listScheme + ":///" + address, []grpc.DialOption{grpc.WithResolvers(listResolverBuilder{})}
+	}
+	return address, nil
+}
+
+func toResolverAddresses(addresses []string) []resolver.Address {
+	resolved := make([]resolver.Address, 0, len(addresses))
+	for _, address := range addresses {
+		resolved = append(resolved, resolver.Address{Addr: address})
+	}
+	return resolved
+}
+
+// listResolverBuilder resolves the comma separated addresses of the target once
+type listResolverBuilder struct{}
+
+func (listResolverBuilder) Scheme() string {
+	return listScheme
+}
+
+func (listResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	addresses := []string{}
+	for _, address := range strings.Split(target.Endpoint(), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+
+	err := cc.UpdateState(resolver.State{Addresses: toResolverAddresses(addresses)})
+	if err != nil {
+		return nil, err
+	}
+	return listResolver{}, nil
+}
+
+type listResolver struct{}
+
+func (listResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (listResolver) Close() {}
+
+// fileResolverBuilder resolves the addresses listed in a file, one address per line,
+// the file is read again every interval and whenever gRPC fails to reach an address
+type fileResolverBuilder struct {
+	interval time.Duration
+}
+
+func (*fileResolverBuilder) Scheme() string {
+	return fileScheme
+}
+
+func (builder *fileResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	path := target.URL.Path
+	if path == "" {
+		path = target.URL.Opaque // file:relative/path
+	}
+	if path == "" {
+		return nil, fmt.Errorf("missing path in %s address", fileScheme)
+	}
+
+	r := &fileResolver{
+		path:       path,
+		cc:         cc,
+		resolveNow: make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	// a missing file fails the dial, later read failures keep the previous addresses
+	err := r.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	go r.watch(builder.interval)
+	return r, nil
+}
+
+type fileResolver struct {
+	path       string
+	cc         resolver.ClientConn
+	addresses  []string
+	resolveNow chan struct{}
+	done       chan struct{}
+}
+
+func (r *fileResolver) watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.resolveNow:
+		}
+
+		err := r.resolve()
+		if err != nil {
+			r.cc.ReportError(err)
+		}
+	}
+}
+
+// resolve reads the file and updates the addresses of the connection if they changed
+func (r *fileResolver) resolve() error {
+	addresses, err := readAddressFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	if equalAddresses(addresses, r.addresses) {
+		return nil
+	}
+	r.addresses = addresses
+
+	return r.cc.UpdateState(resolver.State{Addresses: toResolverAddresses(addresses)})
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveNow <- struct{}{}:
+	default: // a resolution is already pending
+	}
+}
+
+func (r *fileResolver) Close() {
+	close(r.done)
+}
+
+// readAddressFile returns the addresses of the file, the empty lines and the lines starting with # are skipped
+func readAddressFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read address file: %w", err)
+	}
+
+	addresses := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			addresses = append(addresses, line)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no address in file %s", path)
+	}
+	return addresses, nil
+}
+
+func equalAddresses(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
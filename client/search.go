@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// LaptopIterator goes through the laptops found by a search:
+//
+//	laptops := client.SearchLaptop(ctx, req)
+//	defer laptops.Close()
+//	for laptops.Next() {
+//		laptop := laptops.Laptop()
+//	}
+//	err := laptops.Err()
+type LaptopIterator struct {
+	client  *Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	req     *pb.SearchLaptopRequest
+	retrier *retrier
+
+	stream   pb.LaptopService_SearchLaptopClient
+	laptop   *pb.Laptop
+	received int
+	err      error
+	done     bool
+}
+
+// SearchLaptop starts the search, the search is retried as long as no laptop is received.
+// The timeout of the options covers the whole search
+func (client *Client) SearchLaptop(ctx context.Context, req *pb.SearchLaptopRequest, opts ...CallOption) *LaptopIterator {
+	options := client.callOptions(opts)
+	ctx, cancel := withTimeout(ctx, options.timeout)
+
+	return &LaptopIterator{
+		client:  client,
+		ctx:     ctx,
+		cancel:  cancel,
+		req:     req,
+		retrier: client.newRetrier(!options.noRetry),
+	}
+}
+
+// Next receives the next laptop, it returns false at the end of the search or on error
+func (it *LaptopIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	for {
+		if it.stream == nil {
+			stream, err := it.client.laptopClient.SearchLaptop(it.ctx, it.req)
+			if err != nil {
+				if it.retrier.wait(it.ctx, err) {
+					continue
+				}
+				it.finish(err)
+				return false
+			}
+			it.stream = stream
+		}
+
+		res, err := it.stream.Recv()
+		if err == nil {
+			it.laptop = res.GetLaptop()
+			it.received++
+			return true
+		}
+		if err == io.EOF {
+			it.finish(nil)
+			return false
+		}
+
+		// the laptops already received would be received twice if the search were retried
+		if it.received == 0 && it.retrier.wait(it.ctx, err) {
+			it.stream = nil
+			continue
+		}
+
+		it.finish(err)
+		return false
+	}
+}
+
+// Laptop returns the laptop received by the last call to Next
+func (it *LaptopIterator) Laptop() *pb.Laptop {
+	return it.laptop
+}
+
+// Err returns the error which stopped the search, nil once every laptop is received
+func (it *LaptopIterator) Err() error {
+	return it.err
+}
+
+// Close stops the search, it must be called if the iteration stops before Next returns false
+func (it *LaptopIterator) Close() {
+	it.finish(nil)
+}
+
+func (it *LaptopIterator) finish(err error) {
+	if !it.done {
+		it.done = true
+		it.err = err
+		it.laptop = nil
+		it.cancel()
+	}
+}
+
+// Collect returns every laptop found by the search
+func (it *LaptopIterator) Collect() ([]*pb.Laptop, error) {
+	defer it.Close()
+
+	laptops := []*pb.Laptop{}
+	for it.Next() {
+		laptops = append(laptops, it.Laptop())
+	}
+	return laptops, it.Err()
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const usage = `usage: admin -address host:port <command> [flags]
+
+commands:
+  export          write every laptop of the catalog to a file
+  import          create the laptops read from a file
+  members         list the members of the cluster of the server
+  add-member      add a member to the cluster of the server
+  remove-member   remove a member from the cluster of the server
+
+the member commands go to the internal address of the server, the other ones to its server port
+`
+
+func exportCatalog(conn grpc.ClientConnInterface, args []string) error {
+	laptopClient := pb.NewLaptopServiceClient(conn)
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	filename := flags.String("file", "-", "the file to write, - writes to the standard output")
+	format := flags.String("format", "", "jsonl, csv or binary, guessed from the file extension by default")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = serializer.FormatFromFilename(*filename)
+	}
+
+	var w io.Writer = os.Stdout
+	if *filename != "-" {
+		file, err := os.Create(*filename)
+		if err != nil {
+			return fmt.Errorf("cannot create export file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	// the filter has to let every laptop through
+	filter := &pb.Filter{MaxPriceUsd: math.MaxFloat64}
+
+	stream, err := laptopClient.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: filter})
+	if err != nil {
+		return fmt.Errorf("cannot search laptops: %w", err)
+	}
+
+	count, err := serializer.ExportLaptops(w, *format, func(found func(*pb.Laptop) error) error {
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = found(res.GetLaptop())
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("exported %d laptops", count)
+	return nil
+}
+
+func importCatalog(conn grpc.ClientConnInterface, args []string) error {
+	laptopClient := pb.NewLaptopServiceClient(conn)
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	filename := flags.String("file", "-", "the file to read, - reads from the standard input")
+	format := flags.String("format", "", "jsonl, csv or binary, guessed from the file extension by default")
+	atomic := flags.Bool("atomic", false, "create either every laptop or none of them")
+	batchSize := flags.Uint("batch-size", 0, "how many laptops the server saves at once, 0 uses the server default")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = serializer.FormatFromFilename(*filename)
+	}
+
+	var r io.Reader = os.Stdin
+	if *filename != "-" {
+		file, err := os.Open(*filename)
+		if err != nil {
+			return fmt.Errorf("cannot open import file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	stream, err := laptopClient.BulkCreateLaptops(context.Background())
+	if err != nil {
+		return fmt.Errorf("cannot bulk create laptops: %w", err)
+	}
+
+	err = stream.Send(&pb.BulkCreateLaptopsRequest{
+		Data: &pb.BulkCreateLaptopsRequest_Options{
+			Options: &pb.BulkCreateOptions{Atomic: *atomic, BatchSize: uint32(*batchSize)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot send bulk create options: %w", err)
+	}
+
+	_, err = serializer.ImportLaptops(r, *format, func(laptop *pb.Laptop) error {
+		return stream.Send(&pb.BulkCreateLaptopsRequest{
+			Data: &pb.BulkCreateLaptopsRequest_Laptop{Laptop: laptop},
+		})
+	})
+	if err != nil {
+		stream.CloseSend()
+		return err
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("cannot receive bulk create response: %w", err)
+	}
+
+	for _, result := range res.GetResults() {
+		if codes.Code(result.GetStatus().GetCode()) != codes.OK {
+			log.Printf("laptop %d (id: %s) is not created: %s: %s",
+				result.GetIndex()+1, result.GetId(), codes.Code(result.GetStatus().GetCode()), result.GetStatus().GetMessage())
+		}
+	}
+
+	log.Printf("imported %d laptops, %d failed", res.GetCreatedCount(), res.GetFailedCount())
+	if res.GetFailedCount() > 0 {
+		return fmt.Errorf("%d laptops are not imported", res.GetFailedCount())
+	}
+	return nil
+}
+
+func listMembers(conn grpc.ClientConnInterface, args []string) error {
+	flags := flag.NewFlagSet("members", flag.ExitOnError)
+	flags.Parse(args)
+
+	res, err := pb.NewClusterServiceClient(conn).ListMembers(context.Background(), &pb.ListMembersRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot list members: %w", err)
+	}
+
+	for _, member := range res.GetMembers() {
+		role := "follower"
+		if member.GetLeader() {
+			role = "leader"
+		} else if !member.GetVoter() {
+			role = "non-voter"
+		}
+		fmt.Printf("%s\traft: %s\t%s\n", member.GetId(), member.GetRaftAddress(), role)
+	}
+	return nil
+}
+
+func addMember(conn grpc.ClientConnInterface, args []string) error {
+	flags := flag.NewFlagSet("add-member", flag.ExitOnError)
+	id := flags.String("id", "", "the address of the server of the new member")
+	raftAddress := flags.String("raft-address", "", "the raft address of the new member")
+	flags.Parse(args)
+
+	_, err := pb.NewClusterServiceClient(conn).AddMember(context.Background(), &pb.AddMemberRequest{Id: *id, RaftAddress: *raftAddress})
+	if err != nil {
+		return fmt.Errorf("cannot add member: %w", err)
+	}
+
+	log.Printf("added member %s", *id)
+	return nil
+}
+
+func removeMember(conn grpc.ClientConnInterface, args []string) error {
+	flags := flag.NewFlagSet("remove-member", flag.ExitOnError)
+	id := flags.String("id", "", "the address of the server of the member")
+	flags.Parse(args)
+
+	_, err := pb.NewClusterServiceClient(conn).RemoveMember(context.Background(), &pb.RemoveMemberRequest{Id: *id})
+	if err != nil {
+		return fmt.Errorf("cannot remove member: %w", err)
+	}
+
+	log.Printf("removed member %s", *id)
+	return nil
+}
+
+func main() {
+	serverAddress := flag.String("address", "", "the server address")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]func(grpc.ClientConnInterface, []string) error{
+		"export":        exportCatalog,
+		"import":        importCatalog,
+		"members":       listMembers,
+		"add-member":    addMember,
+		"remove-member": removeMember,
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn, err := grpc.Dial(*serverAddress, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal("cannot dial server: ", err)
+	}
+	defer conn.Close()
+
+	err = command(conn, flag.Args()[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
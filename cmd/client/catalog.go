@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"google.golang.org/grpc/codes"
+)
+
+// everyLaptopFilter is a search filter letting every laptop through
+func everyLaptopFilter() *pb.Filter {
+	return &pb.Filter{MaxPriceUsd: math.MaxFloat64}
+}
+
+func exportCatalog(flags *flag.FlagSet) runFunc {
+	filename := flags.String("file", "-", "the file to write, - writes to the standard output")
+	format := flags.String("format", "", "jsonl, csv or binary, guessed from the file extension by default")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 0)
+		if err != nil {
+			return err
+		}
+
+		if *format == "" {
+			*format = serializer.FormatFromFilename(*filename)
+		}
+
+		var w io.Writer = c.out.writer
+		if *filename != "-" {
+			file, err := os.Create(*filename)
+			if err != nil {
+				return fmt.Errorf("cannot create export file: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		stream, err := c.laptopClient.SearchLaptop(ctx, &pb.SearchLaptopRequest{Filter: everyLaptopFilter()})
+		if err != nil {
+			return fmt.Errorf("cannot search laptops: %w", err)
+		}
+
+		count, err := serializer.ExportLaptops(w, *format, func(found func(*pb.Laptop) error) error {
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				err = found(res.GetLaptop())
+				if err != nil {
+					return err
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stderr, "exported %d laptops\n", count)
+		return nil
+	}
+}
+
+func importCatalog(flags *flag.FlagSet) runFunc {
+	filename := flags.String("file", "-", "the file to read, - reads from the standard input")
+	format := flags.String("format", "", "jsonl, csv or binary, guessed from the file extension by default")
+	atomic := flags.Bool("atomic", false, "create either every laptop or none of them")
+	batchSize := flags.Uint("batch-size", 0, "how many laptops the server saves at once, 0 uses the server default")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 0)
+		if err != nil {
+			return err
+		}
+
+		if *format == "" {
+			*format = serializer.FormatFromFilename(*filename)
+		}
+
+		r := c.stdin
+		if *filename != "-" {
+			file, err := os.Open(*filename)
+			if err != nil {
+				return fmt.Errorf("cannot open import file: %w", err)
+			}
+			defer file.Close()
+			r = file
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		stream, err := c.laptopClient.BulkCreateLaptops(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot bulk create laptops: %w", err)
+		}
+
+		err = stream.Send(&pb.BulkCreateLaptopsRequest{
+			Data: &pb.BulkCreateLaptopsRequest_Options{
+				Options: &pb.BulkCreateOptions{Atomic: *atomic, BatchSize: uint32(*batchSize)},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("cannot send bulk create options: %w", err)
+		}
+
+		_, err = serializer.ImportLaptops(r, *format, func(laptop *pb.Laptop) error {
+			return stream.Send(&pb.BulkCreateLaptopsRequest{
+				Data: &pb.BulkCreateLaptopsRequest_Laptop{Laptop: laptop},
+			})
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		res, err := stream.CloseAndRecv()
+		if err != nil {
+			return fmt.Errorf("cannot receive bulk create response: %w", err)
+		}
+
+		for _, result := range res.GetResults() {
+			err = c.out.Print(result, []string{"INDEX", "ID", "STATUS", "MESSAGE"},
+				fmt.Sprint(result.GetIndex()+1),
+				result.GetId(),
+				codes.Code(result.GetStatus().GetCode()).String(),
+				result.GetStatus().GetMessage(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(c.stderr, "imported %d laptops, %d failed\n", res.GetCreatedCount(), res.GetFailedCount())
+		if res.GetFailedCount() > 0 {
+			return fmt.Errorf("%d laptops are not imported", res.GetFailedCount())
+		}
+		return nil
+	}
+}
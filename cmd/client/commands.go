@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type command struct {
+	name        string // the words of the command, e.g. "laptop create"
+	usage       string // the flags and the arguments of the command
+	description string
+	// flags declares the flags of the command and returns the function running it, so that the shell
+	// can list the flags without running the command
+	flags func(flags *flag.FlagSet) runFunc
+}
+
+// runFunc parses the arguments of a command with its flag set and runs it
+type runFunc func(c *cli, args []string) error
+
+var commands = []*command{
+	{
+		name:        "laptop create",
+		usage:       "[-from-file file | -sample]",
+		description: "create a laptop read from a JSON or YAML file, or a random sample laptop",
+		flags:       createLaptop,
+	},
+	{
+		name:        "laptop get",
+		usage:       "<laptop-id>",
+		description: "print a laptop",
+		flags:       getLaptop,
+	},
+	{
+		name:        "laptop search",
+		usage:       "[-max-price price] [-currency code] [-min-cores n] [-min-ghz ghz] [-min-ram size] [-order asc|desc] [-limit n]",
+		description: "print the laptops matching the filter",
+		flags:       searchLaptop,
+	},
+	{
+		name:        "laptop rate",
+		usage:       "<laptop-id> <score> [<laptop-id> <score> ...]",
+		description: "rate laptops from 1 to 10 and print their average scores",
+		flags:       rateLaptop,
+	},
+	{
+		name:        "image upload",
+		usage:       "<laptop-id> <image-file>",
+		description: "upload an image of a laptop",
+		flags:       uploadImage,
+	},
+	{
+		name:        "image download",
+		usage:       "[-file file] <image-id>",
+		description: "download an image, to the image id with its extension by default",
+		flags:       downloadImage,
+	},
+	{
+		name:        "export",
+		usage:       "[-file file] [-format jsonl|csv|binary]",
+		description: "write every laptop of the catalog to a file",
+		flags:       exportCatalog,
+	},
+	{
+		name:        "import",
+		usage:       "[-file file] [-format jsonl|csv|binary] [-atomic] [-batch-size n]",
+		description: "create the laptops read from a file",
+		flags:       importCatalog,
+	},
+}
+
+// the shell runs the other commands, it is added in init to break the initialization cycle
+func init() {
+	commands = append(commands, &command{
+		name:        "shell",
+		usage:       "[-page-size n] [-history-file file]",
+		description: "run the commands interactively with a single connection, completion and history",
+		flags:       runShell,
+	})
+}
+
+// findCommand returns the command named by the first arguments, followed by the arguments of the command
+func findCommand(args []string) (*command, []string) {
+	for _, command := range commands {
+		words := strings.Fields(command.name)
+		if len(args) < len(words) {
+			continue
+		}
+
+		matches := true
+		for i, word := range words {
+			matches = matches && args[i] == word
+		}
+		if matches {
+			return command, args[len(words):]
+		}
+	}
+
+	return nil, nil
+}
+
+func printCommands(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, command := range commands {
+		fmt.Fprintf(table, "  %s\t%s\n", command.name, command.description)
+	}
+	table.Flush()
+}
+
+func (command *command) flagSet(output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(command.name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.Usage = func() {
+		fmt.Fprintf(output, "usage: client %s %s\n\n%s\n", command.name, command.usage, command.description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the flags of a command, the flag set prints the invalid flags itself
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return &usageError{}
+	}
+	return err
+}
+
+// parseArgs parses the flags of a command which takes exactly n arguments
+func parseArgs(flags *flag.FlagSet, args []string, n int) error {
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != n {
+		return usageErrorf("%s takes %d arguments, got %d", flags.Name(), n, flags.NArg())
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/daffarg/grpc-pcbook/client"
+)
+
+const imageChunkSize = 1024
+
+func uploadImage(flags *flag.FlagSet) runFunc {
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 2)
+		if err != nil {
+			return err
+		}
+		laptopId, imagePath := flags.Arg(0), flags.Arg(1)
+
+		file, err := os.Open(imagePath)
+		if err != nil {
+			return fmt.Errorf("cannot open image file: %w", err)
+		}
+		defer file.Close()
+
+		res, err := c.client.UploadImage(context.Background(), laptopId, filepath.Ext(imagePath), file, client.ChunkSize(imageChunkSize))
+		if err != nil {
+			return fmt.Errorf("cannot upload image: %w", err)
+		}
+
+		return c.out.Print(res, []string{"ID", "SIZE"}, res.GetId(), strconv.FormatUint(uint64(res.GetSize()), 10))
+	}
+}
+
+func downloadImage(flags *flag.FlagSet) runFunc {
+	filename := flags.String("file", "", "the file to write, - writes to the standard output")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 1)
+		if err != nil {
+			return err
+		}
+		imageId := flags.Arg(0)
+
+		if *filename == "-" {
+			// the output is the image itself
+			_, err = c.client.DownloadImage(context.Background(), imageId, c.out.writer)
+			if err != nil {
+				return fmt.Errorf("cannot download image: %w", err)
+			}
+			return nil
+		}
+
+		// the default name needs the image type, the image is written to a temporary file renamed at the end
+		file, err := os.CreateTemp(filepath.Dir(*filename), ".download-*")
+		if err != nil {
+			return fmt.Errorf("cannot create image file: %w", err)
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		size := int64(0)
+		info, err := c.client.DownloadImage(context.Background(), imageId, file, client.OnProgress(func(received int64) { size = received }))
+		if err != nil {
+			return fmt.Errorf("cannot download image: %w", err)
+		}
+
+		err = file.Close()
+		if err != nil {
+			return fmt.Errorf("cannot write image: %w", err)
+		}
+
+		if *filename == "" {
+			*filename = imageId + info.GetImageType()
+		}
+		err = os.Rename(file.Name(), *filename)
+		if err != nil {
+			return fmt.Errorf("cannot create image file: %w", err)
+		}
+
+		return c.out.Print(info, []string{"LAPTOP ID", "TYPE", "FILE", "SIZE"},
+			info.GetLaptopId(), info.GetImageType(), *filename, strconv.FormatInt(size, 10))
+	}
+}
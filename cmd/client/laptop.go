@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/daffarg/grpc-pcbook/memunit"
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/serializer"
+)
+
+func createLaptop(flags *flag.FlagSet) runFunc {
+	fromFile := flags.String("from-file", "", "the JSON or YAML file of the laptop, - reads from the standard input")
+	random := flags.Bool("sample", false, "create a random sample laptop")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 0)
+		if err != nil {
+			return err
+		}
+
+		var laptop *pb.Laptop
+		switch {
+		case *fromFile != "" && *random:
+			return usageErrorf("-from-file and -sample cannot be used together")
+		case *fromFile != "":
+			laptop, err = readLaptopFile(c, *fromFile)
+			if err != nil {
+				return err
+			}
+		case *random:
+			laptop = sample.NewLaptop()
+		default:
+			return usageErrorf("either -from-file or -sample is required")
+		}
+
+		id, err := c.client.CreateLaptop(context.Background(), laptop)
+		if err != nil {
+			return fmt.Errorf("cannot create laptop: %w", err)
+		}
+		c.rememberLaptop(id)
+
+		return c.out.Print(&pb.CreateLaptopResponse{Id: id}, []string{"ID"}, id)
+	}
+}
+
+// readLaptopFile reads a laptop from a JSON file, or from a YAML file for the other extensions
+func readLaptopFile(c *cli, filename string) (*pb.Laptop, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read laptop file: %w", err)
+	}
+
+	laptop := &pb.Laptop{}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = serializer.JSONToProtobuf(data, laptop)
+	} else {
+		err = serializer.YAMLToProtobuf(data, laptop) // YAML is a superset of JSON
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse laptop file: %w", err)
+	}
+
+	return laptop, nil
+}
+
+func getLaptop(flags *flag.FlagSet) runFunc {
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 1)
+		if err != nil {
+			return err
+		}
+
+		laptop, err := c.client.GetLaptop(context.Background(), flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("cannot get laptop: %w", err)
+		}
+		c.rememberLaptop(laptop.GetId())
+
+		return c.out.PrintLaptop(laptop)
+	}
+}
+
+func searchLaptop(flags *flag.FlagSet) runFunc {
+	maxPrice := flags.Float64("max-price", 0, "the maximum price, 0 for no limit")
+	currency := flags.String("currency", money.USD, "the currency of the maximum price and of the price order")
+	minCores := flags.Uint("min-cores", 0, "the minimum number of CPU cores")
+	minGhz := flags.Float64("min-ghz", 0, "the minimum CPU frequency")
+	minRam := flags.String("min-ram", "", "the minimum RAM, such as 8GB")
+	order := flags.String("order", "", "sort the laptops by price: asc or desc")
+	limit := flags.Uint("limit", 0, "the maximum number of laptops, 0 for no limit")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 0)
+		if err != nil {
+			return err
+		}
+
+		filter := &pb.Filter{
+			MinCpuCores: uint32(*minCores),
+			MinCpuGhz:   *minGhz,
+		}
+
+		// the price of every laptop is lower than the maximum float
+		switch {
+		case *maxPrice < 0:
+			return usageErrorf("-max-price must not be negative")
+		case *maxPrice == 0 && *currency == money.USD:
+			filter.MaxPriceUsd = math.MaxFloat64
+		case *maxPrice == 0:
+			filter.MaxPrice = &pb.Money{CurrencyCode: *currency, Units: math.MaxInt64}
+		case *currency == money.USD:
+			filter.MaxPriceUsd = *maxPrice
+		default:
+			filter.MaxPrice = money.FromFloat(*currency, *maxPrice)
+		}
+
+		if *minRam != "" {
+			filter.MinRam, err = memunit.Parse(*minRam)
+			if err != nil {
+				return usageErrorf("invalid -min-ram: %v", err)
+			}
+		}
+
+		req := &pb.SearchLaptopRequest{Filter: filter, Limit: uint32(*limit)}
+		switch strings.ToLower(*order) {
+		case "":
+		case "asc":
+			req.PriceOrder = pb.SearchLaptopRequest_ASCENDING
+		case "desc":
+			req.PriceOrder = pb.SearchLaptopRequest_DESCENDING
+		default:
+			return usageErrorf("invalid -order %q, it must be asc or desc", *order)
+		}
+
+		if c.pageSize > 0 {
+			return c.startPager(req)
+		}
+
+		laptops := c.client.SearchLaptop(context.Background(), req)
+		defer laptops.Close()
+
+		for laptops.Next() {
+			laptop := laptops.Laptop()
+			c.rememberLaptop(laptop.GetId())
+			err = c.out.PrintLaptop(laptop)
+			if err != nil {
+				return err
+			}
+		}
+
+		if laptops.Err() != nil {
+			return fmt.Errorf("cannot search laptop: %w", laptops.Err())
+		}
+		return nil
+	}
+}
+
+func rateLaptop(flags *flag.FlagSet) runFunc {
+	return func(c *cli, args []string) error {
+		err := parseFlags(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if flags.NArg() == 0 || flags.NArg()%2 != 0 {
+			return usageErrorf("every laptop id must be followed by its score")
+		}
+
+		laptopIds := []string{}
+		scores := []float64{}
+		for i := 0; i < flags.NArg(); i += 2 {
+			score, err := strconv.ParseFloat(flags.Arg(i+1), 64)
+			if err != nil {
+				return usageErrorf("invalid score %q of laptop %s", flags.Arg(i+1), flags.Arg(i))
+			}
+			laptopIds = append(laptopIds, flags.Arg(i))
+			scores = append(scores, score)
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		stream, err := c.laptopClient.RateLaptop(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot rate laptop: %w", err)
+		}
+
+		waitResponse := make(chan error)
+		// receive the running averages in the background while the ratings are sent
+		go func() {
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					waitResponse <- nil
+					return
+				}
+				if err != nil {
+					waitResponse <- fmt.Errorf("cannot receive stream response: %w", err)
+					return
+				}
+
+				err = c.out.Print(res, []string{"LAPTOP ID", "RATED", "AVERAGE SCORE"},
+					res.GetLaptopId(),
+					strconv.FormatUint(uint64(res.GetRatedCount()), 10),
+					strconv.FormatFloat(res.GetAverageScore(), 'f', 2, 64),
+				)
+				if err != nil {
+					waitResponse <- err
+					return
+				}
+			}
+		}()
+
+		for i, laptopId := range laptopIds {
+			err := stream.Send(&pb.RateLaptopRequest{LaptopId: laptopId, Score: scores[i]})
+			if err != nil {
+				// the reason of the failure is given by the response stream
+				break
+			}
+		}
+
+		err = stream.CloseSend()
+		if err != nil {
+			return fmt.Errorf("cannot close send: %w", err)
+		}
+
+		return <-waitResponse
+	}
+}
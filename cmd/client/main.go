@@ -1,186 +1,251 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
-	"log"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/daffarg/grpc-pcbook/client"
 	"github.com/daffarg/grpc-pcbook/pb"
-	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func createLaptop(laptopClient pb.LaptopServiceClient, laptop *pb.Laptop) {
-	createLaptopReq := &pb.CreateLaptopRequest{
-		Laptop: laptop,
-	}
+// exit codes of the client
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitNotFound    = 3
+	exitUnavailable = 4
+)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+const usageHeader = `usage: client [flags] <command> [command flags] [arguments]
 
-	res, err := laptopClient.CreateLaptop(ctx, createLaptopReq)
-	if err != nil {
-		st, ok := status.FromError(err)
-		if ok && st.Code() == codes.AlreadyExists {
-			log.Print("laptop already exists")
-		} else {
-			log.Fatal("cannot create laptop: ", err)
-		}
-	}
+exit codes: 0 success, 1 failure, 2 invalid usage, 3 not found, 4 server unavailable
+
+commands:
+`
 
-	log.Printf("created laptop with id: %s", res.Id)
+// usageError is returned for invalid command lines, the usage of the command is printed with it
+type usageError struct {
+	message string
 }
 
-func searchLaptop(laptopClient pb.LaptopServiceClient, filter *pb.Filter) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (err *usageError) Error() string {
+	return err.message
+}
 
-	searchLaptopReq := &pb.SearchLaptopRequest{
-		Filter: filter,
-	}
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
 
-	stream, err := laptopClient.SearchLaptop(ctx, searchLaptopReq)
+// describeError appends the google.rpc error details of a gRPC status to its message
+func describeError(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
 
-	if err != nil {
-		log.Fatal("cannot search laptop: ", err)
+	st, ok := status.FromError(err)
+	if !ok {
+		return err.Error()
 	}
 
-	for {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			return
-		}
-		if err != nil {
-			log.Fatal("cannot receive response: ", err)
+	var description strings.Builder
+	description.WriteString(err.Error())
+
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			fmt.Fprintf(&description, "\n - reason: %s (%s)", detail.GetReason(), detail.GetDomain())
+			for key, value := range detail.GetMetadata() {
+				fmt.Fprintf(&description, "\n   %s: %s", key, value)
+			}
+		case *errdetails.BadRequest:
+			for _, violation := range detail.GetFieldViolations() {
+				fmt.Fprintf(&description, "\n - invalid field %s: %s", violation.GetField(), violation.GetDescription())
+			}
+		case *errdetails.ResourceInfo:
+			fmt.Fprintf(&description, "\n - resource %s %s", detail.GetResourceType(), detail.GetResourceName())
+			if detail.GetDescription() != "" {
+				fmt.Fprintf(&description, ": %s", detail.GetDescription())
+			}
+		case *errdetails.QuotaFailure:
+			for _, violation := range detail.GetViolations() {
+				fmt.Fprintf(&description, "\n - quota exceeded for %s: %s", violation.GetSubject(), violation.GetDescription())
+			}
+		default:
+			fmt.Fprintf(&description, "\n - %v", detail)
 		}
-
-		laptop := res.GetLaptop()
-		log.Print("Found a laptop with ID: ", laptop.GetId())
-		log.Print(" - Brand: ", laptop.GetBrand())
-		log.Print(" - Name: ", laptop.GetName())
-		log.Print(" - CPU Cores: ", laptop.GetCpu().GetNumberCores())
-		log.Print(" - CPU Min GHz: ", laptop.GetCpu().GetMinGhz())
-		log.Print(" - RAM: ", laptop.GetRam().GetValue(), laptop.GetRam().GetUnit())
-		log.Print(" - Price: ", laptop.GetPriceUsd())
 	}
+
+	return description.String()
 }
 
-func uploadImage(laptopClient pb.LaptopServiceClient, laptopId string, imagePath string) {
-	// open an image from folder
-	file, err := os.Open(imagePath)
-	if err != nil {
-		log.Fatalf("cannot open image file : %v", err)
+// exitCode returns the exit code of the client for the error of a command
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
 	}
-	defer file.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
-	defer cancel()
+	// the status of a wrapped gRPC error is found too
+	switch status.Code(err) {
+	case codes.NotFound:
+		return exitNotFound
+	case codes.Unavailable:
+		return exitUnavailable
+	}
+	return exitFailure
+}
 
-	stream, err := laptopClient.UploadImage(ctx)
-	if err != nil {
-		log.Fatalf("cannot upload image : %v", err)
+// cli holds what the commands share: the laptop client, the output and the timeout of the calls
+type cli struct {
+	client       *client.Client
+	laptopClient pb.LaptopServiceClient // for the calls the client doesn't wrap
+	out          *output
+	stdin        io.Reader
+	stderr       io.Writer // progress messages, the results go to the output
+	timeout      time.Duration
+
+	// set by the shell only
+	pageSize  int             // how many search results are printed at once, 0 prints them all
+	pager     *pager          // the search being paged
+	laptopIds map[string]bool // the laptop ids seen in the session, for the completion
+}
+
+// rememberLaptop keeps the laptop id for the completion of the shell
+func (c *cli) rememberLaptop(laptopId string) {
+	if c.laptopIds != nil {
+		c.laptopIds[laptopId] = true
 	}
+}
 
-	// first send an image info to the server
-	req := &pb.UploadImageRequest {
-		Data: &pb.UploadImageRequest_Info {
-			Info: &pb.ImageInfo {
-			LaptopId: laptopId,
-			ImageType: filepath.Ext(imagePath),
-			},
-		},
+// context returns the context of a call, without deadline if the timeout is 0
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(context.Background())
 	}
+	return context.WithTimeout(context.Background(), c.timeout)
+}
 
-	err = stream.Send(req)
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	serverAddress := flags.String("address", "localhost:8080", "the server address, a comma separated list of addresses, dns:///host:port or file:///path listing one address per line")
+	balancing := flags.String("balancing", client.RoundRobin, "how the calls are spread over the servers: round_robin, least_request or pick_first")
+	healthCheck := flags.Bool("health-check", false, "send no call to the servers reporting the laptop service as not serving")
+	outputFormat := flags.String("output", formatTable, "how the results are printed: table, json or yaml")
+	timeout := flags.Duration("timeout", 30*time.Second, "the timeout of every call, 0 disables it")
+	traceExporter := flags.String("trace-exporter", tracing.ExporterNone, "where to export traces: none, stdout or otlp")
+	otlpEndpoint := flags.String("otlp-endpoint", "localhost:4317", "the OTLP/gRPC collector address used by the otlp trace exporter")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usageHeader)
+		printCommands(stderr)
+		fmt.Fprintln(stderr, "\nflags:")
+		flags.PrintDefaults()
+	}
 
+	err := flags.Parse(args)
 	if err != nil {
-		log.Fatalf("cannot send image info : %v %v", err, stream.RecvMsg(nil))
+		return exitCode(err)
 	}
 
-	reader := bufio.NewReader(file)
-	buffer := make([]byte, 1024) // create empty buffers (1 megabyte)
-	
-	for {
-		n, err := reader.Read(buffer) // read 1 megabyte image chunk data into buffer
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			log.Fatalf("cannot read chunk into buffer : %v", err)
-		}
-
-		req := &pb.UploadImageRequest{
-			Data: &pb.UploadImageRequest_ChunkData{
-				ChunkData: buffer[:n],
-			},
+	command, commandArgs := findCommand(flags.Args())
+	if command == nil {
+		if flags.NArg() > 0 {
+			fmt.Fprintf(stderr, "unknown command %q\n", strings.Join(flags.Args(), " "))
 		}
+		flags.Usage()
+		return exitUsage
+	}
 
-		err = stream.Send(req)
-		if err != nil {
-			log.Fatalf("cannot send chunk to server : %v %v", err, stream.RecvMsg(nil))
-		}
+	out, err := newOutput(stdout, *outputFormat)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
 	}
 
-	res, err := stream.CloseAndRecv()
+	switch *balancing {
+	case client.RoundRobin, client.LeastRequest, client.PickFirst:
+	default:
+		fmt.Fprintf(stderr, "invalid balancing %q, it must be round_robin, least_request or pick_first\n", *balancing)
+		return exitUsage
+	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "pcbook-client", *traceExporter, *otlpEndpoint)
 	if err != nil {
-		log.Fatalf("cannot receive response from server : %v", err)
+		fmt.Fprintln(stderr, "cannot setup tracing:", err)
+		return exitFailure
+	}
+	defer shutdownTracing(context.Background())
+
+	dialOptions := []client.Option{
+		client.WithTimeout(*timeout),
+		client.WithBalancing(*balancing),
+		client.WithDialOptions(
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+		),
+	}
+	if *healthCheck {
+		dialOptions = append(dialOptions, client.WithHealthCheck(pb.LaptopService_ServiceDesc.ServiceName))
 	}
 
-	log.Printf("successfully uploaded image with ID = %s and size = %d", res.GetId(), res.GetSize())
-}
+	laptopClient, err := client.Dial(*serverAddress, dialOptions...)
+	if err != nil {
+		fmt.Fprintln(stderr, "cannot dial server:", err)
+		return exitUnavailable
+	}
+	defer laptopClient.Close()
+
+	c := &cli{
+		client:       laptopClient,
+		laptopClient: laptopClient.LaptopService(),
+		out:          out,
+		stdin:        stdin,
+		stderr:       stderr,
+		timeout:      *timeout,
+	}
 
-func testCreateLaptop(laptopClient pb.LaptopServiceClient) {
-	laptop := sample.NewLaptop()
-	createLaptop(laptopClient, laptop)
+	return runCommand(c, command, commandArgs)
 }
 
-func testSearchLaptop(laptopClient pb.LaptopServiceClient) {
-	for i := 0; i < 10; i ++ {
-		createLaptop(laptopClient, sample.NewLaptop())
+// runCommand runs the command and flushes its output, the errors are printed with their details
+func runCommand(c *cli, command *command, args []string) int {
+	err := command.flags(command.flagSet(c.stderr))(c, args)
+	if flushErr := c.out.Flush(); err == nil {
+		err = flushErr
 	}
 
-	filter := &pb.Filter{
-		MaxPriceUsd: 3000,
-		MinCpuCores: 4,
-		MinCpuGhz: 2.5,
-		MinRam: &pb.Memory{
-			Unit: pb.Memory_GIGABYTE,
-			Value: 4,
-		},
+	var usageErr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.As(err, &usageErr):
+		// the flag errors are already printed by the flag set
+		if usageErr.message != "" {
+			fmt.Fprintln(c.stderr, err)
+			fmt.Fprintf(c.stderr, "usage: client %s %s\n", command.name, command.usage)
+		}
+	default:
+		fmt.Fprintln(c.stderr, describeError(err))
 	}
 
-	searchLaptop(laptopClient, filter)
-}
-
-func testUploadImage(laptopClient pb.LaptopServiceClient) {
-	laptop := sample.NewLaptop()
-	log.Printf("make a request to server to create laptop with ID = %s", laptop.Id)
-	createLaptop(laptopClient, laptop)
-	uploadImage(laptopClient, laptop.Id, "tmp/laptop.png")
+	return exitCode(err)
 }
 
 func main() {
-	serverAddress := flag.String("address", "", "the server address")
-	flag.Parse()
-	log.Printf("dial server %s", *serverAddress)
-
-	conn, err := grpc.Dial(*serverAddress, grpc.WithInsecure())
-	if err != nil {
-		log.Fatal("cannot dial server: ", err)
-	}
-
-	laptopClient := pb.NewLaptopServiceClient(conn)
-
-	testCreateLaptop(laptopClient)
-	testSearchLaptop(laptopClient)
-	testUploadImage(laptopClient)
-}
\ No newline at end of file
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
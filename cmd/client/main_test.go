@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	serverAddress, laptopStore := startTestServer(t)
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	laptopJson, err := serializer.ProtobufToJSON(sample.NewLaptop())
+	require.NoError(t, err)
+
+	// nothing listens on the address of a closed listener
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	closedAddress := listener.Addr().String()
+	listener.Close()
+
+	testCases := []struct {
+		name    string
+		args    []string
+		stdin   string
+		code    int
+		stderr  string
+		address string
+	}{
+		{name: "get", args: []string{"laptop", "get", laptop.GetId()}, code: exitOK},
+		{name: "create from stdin", args: []string{"laptop", "create", "-from-file", "-"}, stdin: laptopJson, code: exitOK},
+		{name: "command help", args: []string{"laptop", "get", "-h"}, code: exitOK, stderr: "usage: client laptop get <laptop-id>"},
+		{name: "no command", args: []string{}, code: exitUsage, stderr: "commands:"},
+		{name: "unknown command", args: []string{"laptop", "buy"}, code: exitUsage, stderr: `unknown command "laptop buy"`},
+		{name: "unknown flag", args: []string{"laptop", "search", "-cheap"}, code: exitUsage, stderr: "flag provided but not defined: -cheap"},
+		{name: "missing argument", args: []string{"laptop", "get"}, code: exitUsage, stderr: "laptop get takes 1 arguments, got 0"},
+		{name: "invalid flag value", args: []string{"laptop", "search", "-order", "sideways"}, code: exitUsage, stderr: `invalid -order "sideways"`},
+		{name: "invalid output", args: []string{"-output", "xml", "laptop", "get", laptop.GetId()}, code: exitUsage, stderr: `unknown output format "xml"`},
+		{name: "not found", args: []string{"laptop", "get", uuid.NewString()}, code: exitNotFound, stderr: "reason: LAPTOP_NOT_FOUND"},
+		{name: "invalid argument", args: []string{"laptop", "get", "not-a-uuid"}, code: exitFailure, stderr: "invalid field id: must be a valid UUID"},
+		{name: "unavailable", args: []string{"-timeout", "5s", "laptop", "get", laptop.GetId()}, address: closedAddress, code: exitUnavailable},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			address := serverAddress
+			if tc.address != "" {
+				address = tc.address
+			}
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-address", address}, tc.args...)
+			code := run(args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			require.Equal(t, tc.code, code, stderr.String())
+			require.Contains(t, stderr.String(), tc.stderr)
+		})
+	}
+}
+
+func TestRunOutputFormats(t *testing.T) {
+	t.Parallel()
+
+	serverAddress, laptopStore := startTestServer(t)
+	laptops := []*pb.Laptop{sample.NewLaptop(), sample.NewLaptop()}
+	for _, laptop := range laptops {
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	search := func(format string) string {
+		var stdout, stderr bytes.Buffer
+		args := []string{"-address", serverAddress, "-output", format, "laptop", "search", "-order", "asc"}
+		code := run(args, strings.NewReader(""), &stdout, &stderr)
+		require.Equal(t, exitOK, code, stderr.String())
+		return stdout.String()
+	}
+
+	// a header, then a row per laptop
+	rows := strings.Split(strings.TrimSpace(search(formatTable)), "\n")
+	require.Len(t, rows, 3)
+	require.Equal(t, laptopHeader, strings.Fields(rows[0]))
+	for _, laptop := range laptops {
+		require.Contains(t, rows[1]+rows[2], laptop.GetId())
+	}
+
+	// a JSON document per laptop
+	ids := []string{}
+	decoder := json.NewDecoder(strings.NewReader(search(formatJSON)))
+	for decoder.More() {
+		var document json.RawMessage
+		require.NoError(t, decoder.Decode(&document))
+
+		laptop := &pb.Laptop{}
+		require.NoError(t, serializer.JSONToProtobuf(document, laptop))
+		ids = append(ids, laptop.GetId())
+	}
+	require.ElementsMatch(t, []string{laptops[0].GetId(), laptops[1].GetId()}, ids)
+
+	// YAML documents separated by ---
+	ids = []string{}
+	for _, document := range strings.Split(search(formatYAML), "---\n") {
+		laptop := &pb.Laptop{}
+		require.NoError(t, serializer.YAMLToProtobuf([]byte(document), laptop))
+		ids = append(ids, laptop.GetId())
+	}
+	require.ElementsMatch(t, []string{laptops[0].GetId(), laptops[1].GetId()}, ids)
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err  error
+		code int
+	}{
+		{nil, exitOK},
+		{flag.ErrHelp, exitOK},
+		{usageErrorf("invalid"), exitUsage},
+		{&usageError{}, exitUsage},
+		{fmt.Errorf("cannot get laptop: %w", status.Error(codes.NotFound, "not found")), exitNotFound},
+		{status.Error(codes.Unavailable, "unavailable"), exitUnavailable},
+		{status.Error(codes.InvalidArgument, "invalid"), exitFailure},
+		{errors.New("failure"), exitFailure},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.code, exitCode(tc.err), "%v", tc.err)
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "<nil>", describeError(nil))
+	require.Equal(t, "failure", describeError(errors.New("failure")))
+
+	st, err := status.New(codes.InvalidArgument, "laptop is invalid").WithDetails(
+		&errdetails.ErrorInfo{Reason: "INVALID_LAPTOP", Domain: "pcbook", Metadata: map[string]string{"laptop_id": "42"}},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "laptop.price_usd", Description: "must not be negative"},
+		}},
+		&errdetails.ResourceInfo{ResourceType: "laptop", ResourceName: "42", Description: "laptop doesn't exist"},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: "laptop:42", Description: "image is too large"},
+		}},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, "rpc error: code = InvalidArgument desc = laptop is invalid"+
+		"\n - reason: INVALID_LAPTOP (pcbook)"+
+		"\n   laptop_id: 42"+
+		"\n - invalid field laptop.price_usd: must not be negative"+
+		"\n - resource laptop 42: laptop doesn't exist"+
+		"\n - quota exceeded for laptop:42: image is too large",
+		describeError(st.Err()))
+}
+
+// startTestServer serves the laptop service of an in-memory store, it is stopped with the test
+func startTestServer(t *testing.T) (string, *service.InMemoryLaptopStore) {
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptopServer := service.NewLaptopServer(laptopStore, service.NewDiskImageStore(t.TempDir()), service.NewInMemoryRatingStore())
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String(), laptopStore
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/daffarg/grpc-pcbook/memunit"
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"google.golang.org/protobuf/proto"
+)
+
+// output formats of the results
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+var laptopHeader = []string{"ID", "BRAND", "NAME", "CPU", "CORES", "GHZ", "RAM", "PRICE", "YEAR"}
+
+// output prints the results of the commands, Flush must be called once every result is printed
+type output struct {
+	format string
+	writer io.Writer
+	table  *tabwriter.Writer
+	header []string // the header of the table being printed
+	count  int
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return &output{format: format, writer: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, it must be table, json or yaml", format)
+	}
+}
+
+// Print prints the message as a JSON or YAML document, or as a row of a table with the header
+func (out *output) Print(message proto.Message, header []string, row ...string) error {
+	out.count++
+
+	switch out.format {
+	case formatJSON:
+		data, err := serializer.ProtobufToJSON(message)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out.writer, data)
+		return err
+	case formatYAML:
+		data, err := serializer.ProtobufToYAML(message)
+		if err != nil {
+			return err
+		}
+		if out.count > 1 {
+			data = "---\n" + data
+		}
+		_, err = io.WriteString(out.writer, data)
+		return err
+	}
+
+	// a new table starts when the header changes
+	if out.table == nil || strings.Join(out.header, "\t") != strings.Join(header, "\t") {
+		err := out.Flush()
+		if err != nil {
+			return err
+		}
+
+		out.table = tabwriter.NewWriter(out.writer, 0, 0, 2, ' ', 0)
+		out.header = header
+		fmt.Fprintln(out.table, strings.Join(header, "\t"))
+	}
+
+	_, err := fmt.Fprintln(out.table, strings.Join(row, "\t"))
+	return err
+}
+
+func (out *output) PrintLaptop(laptop *pb.Laptop) error {
+	cpu := laptop.GetCpu()
+	return out.Print(laptop, laptopHeader,
+		laptop.GetId(),
+		laptop.GetBrand(),
+		laptop.GetName(),
+		strings.TrimSpace(cpu.GetBrand()+" "+cpu.GetName()),
+		strconv.FormatUint(uint64(cpu.GetNumberCores()), 10),
+		fmt.Sprintf("%.2f-%.2f", cpu.GetMinGhz(), cpu.GetMaxGhz()),
+		memunit.Format(laptop.GetRam()),
+		formatPrice(laptop),
+		strconv.FormatUint(uint64(laptop.GetReleaseYear()), 10),
+	)
+}
+
+func formatPrice(laptop *pb.Laptop) string {
+	if laptop.GetPrice() != nil {
+		return money.Format(laptop.GetPrice())
+	}
+	return money.Format(money.FromFloat(money.USD, laptop.GetPriceUsd()))
+}
+
+func (out *output) Flush() error {
+	if out.table == nil {
+		return nil
+	}
+
+	err := out.table.Flush()
+	out.table = nil
+	out.header = nil
+	return err
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/daffarg/grpc-pcbook/client"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/peterh/liner"
+)
+
+const shellHelp = `type a command without "client", e.g. laptop search -min-ram 8GB, tab completes the commands, their flags and the laptop ids
+
+shell commands:
+  next      print the next page of the last search
+  history   print the command history
+  help      print this help
+  exit      leave the shell
+`
+
+// the commands whose first argument is a laptop id
+var laptopIdCommands = map[string]bool{
+	"laptop get":   true,
+	"laptop rate":  true,
+	"image upload": true,
+}
+
+// pager prints the laptops of a search page by page, the search stream stays open between the pages
+type pager struct {
+	laptops *client.LaptopIterator
+	shown   int
+}
+
+// startPager starts the search and prints its first page, the previous search is stopped
+func (c *cli) startPager(req *pb.SearchLaptopRequest) error {
+	c.stopPager()
+
+	// the next pages may be asked for long after the first one, the search has no timeout
+	laptops := c.client.SearchLaptop(context.Background(), req, client.Timeout(0))
+	c.pager = &pager{laptops: laptops}
+	return c.nextPage()
+}
+
+// nextPage prints the next page of the search being paged
+func (c *cli) nextPage() error {
+	if c.pager == nil {
+		return usageErrorf("there is no search to page through")
+	}
+
+	for i := 0; i < c.pageSize; i++ {
+		if !c.pager.laptops.Next() {
+			err := c.pager.laptops.Err()
+			shown := c.pager.shown
+			c.stopPager()
+			if err != nil {
+				return fmt.Errorf("cannot search laptop: %w", err)
+			}
+
+			c.out.Flush()
+			fmt.Fprintf(c.stderr, "-- %d laptops found --\n", shown)
+			return nil
+		}
+
+		laptop := c.pager.laptops.Laptop()
+		c.pager.shown++
+		c.rememberLaptop(laptop.GetId())
+		err := c.out.PrintLaptop(laptop)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.out.Flush()
+	fmt.Fprintf(c.stderr, "-- %d laptops shown, type next for more --\n", c.pager.shown)
+	return nil
+}
+
+func (c *cli) stopPager() {
+	if c.pager != nil {
+		c.pager.laptops.Close()
+		c.pager = nil
+	}
+}
+
+func runShell(flags *flag.FlagSet) runFunc {
+	pageSize := flags.Int("page-size", 20, "how many search results are printed at once")
+	historyFile := flags.String("history-file", defaultHistoryFile(), "the file keeping the command history, empty keeps no history")
+
+	return func(c *cli, args []string) error {
+		err := parseArgs(flags, args, 0)
+		if err != nil {
+			return err
+		}
+
+		if *pageSize <= 0 {
+			return usageErrorf("-page-size must be greater than 0")
+		}
+
+		c.pageSize = *pageSize
+		c.laptopIds = make(map[string]bool)
+		defer c.stopPager()
+
+		err = c.loadLaptopIds()
+		if err != nil {
+			fmt.Fprintln(c.stderr, "cannot load the laptop ids for the completion:", describeError(err))
+		}
+
+		line := liner.NewLiner()
+		defer line.Close()
+
+		line.SetCtrlCAborts(true)
+		line.SetTabCompletionStyle(liner.TabPrints)
+		line.SetWordCompleter(c.complete)
+
+		if *historyFile != "" {
+			if file, err := os.Open(*historyFile); err == nil {
+				line.ReadHistory(file)
+				file.Close()
+			}
+			defer saveHistory(c, line, *historyFile)
+		}
+
+		fmt.Fprintln(c.stderr, `pcbook shell, type help for the commands`)
+
+		for {
+			input, err := line.Prompt("pcbook> ")
+			if err == liner.ErrPromptAborted {
+				continue // ctrl-c clears the line
+			}
+			if err == io.EOF {
+				fmt.Fprintln(c.stderr)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("cannot read command: %w", err)
+			}
+
+			if strings.TrimSpace(input) == "" {
+				continue
+			}
+			line.AppendHistory(input)
+
+			words, err := splitArgs(input)
+			if err != nil {
+				fmt.Fprintln(c.stderr, err)
+				continue
+			}
+
+			switch words[0] {
+			case "exit", "quit":
+				return nil
+			case "help":
+				fmt.Fprint(c.stderr, shellHelp, "\ncommands:\n")
+				printCommands(c.stderr)
+				continue
+			case "history":
+				line.WriteHistory(c.out.writer)
+				continue
+			case "next":
+				err = c.nextPage()
+				if err != nil {
+					fmt.Fprintln(c.stderr, describeError(err))
+				}
+				continue
+			}
+
+			command, commandArgs := findCommand(words)
+			if command == nil || command.name == "shell" {
+				fmt.Fprintf(c.stderr, "unknown command %q, type help for the commands\n", input)
+				continue
+			}
+
+			runCommand(c, command, commandArgs)
+		}
+	}
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".pcbook_history")
+}
+
+func saveHistory(c *cli, line *liner.State, filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cannot save the command history:", err)
+		return
+	}
+	defer file.Close()
+
+	_, err = line.WriteHistory(file)
+	if err != nil {
+		fmt.Fprintln(c.stderr, "cannot save the command history:", err)
+	}
+}
+
+// loadLaptopIds searches every laptop of the catalog so that their ids can be completed
+func (c *cli) loadLaptopIds() error {
+	laptops := c.client.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: everyLaptopFilter()})
+	defer laptops.Close()
+
+	for laptops.Next() {
+		c.rememberLaptop(laptops.Laptop().GetId())
+	}
+	return laptops.Err()
+}
+
+// complete returns the completions of the word under the cursor: the commands, their flags or the laptop ids
+func (c *cli) complete(input string, pos int) (head string, completions []string, tail string) {
+	start := strings.LastIndexAny(input[:pos], " \t") + 1
+	head, word, tail := input[:start], input[start:pos], input[pos:]
+	words := strings.Fields(head)
+
+	candidates := []string{}
+	command, args := findCommand(words)
+	switch {
+	case command == nil:
+		// the next word of a command name, or a shell command
+		for _, name := range append(commandNames(), "next", "history", "help", "exit") {
+			if name == "shell" {
+				continue
+			}
+			nameWords := strings.Fields(name)
+			if len(nameWords) > len(words) && strings.Join(nameWords[:len(words)], " ") == strings.Join(words, " ") {
+				candidates = append(candidates, nameWords[len(words)])
+			}
+		}
+	case strings.HasPrefix(word, "-"):
+		candidates = commandFlags(command)
+	case laptopIdCommands[command.name] && len(args) == 0:
+		for laptopId := range c.laptopIds {
+			candidates = append(candidates, laptopId)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && !seen[candidate] {
+			seen[candidate] = true
+			completions = append(completions, candidate+" ")
+		}
+	}
+	sort.Strings(completions)
+
+	return head, completions, tail
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, command.name)
+	}
+	return names
+}
+
+// commandFlags returns the flags of the command, e.g. the filter fields of the search
+func commandFlags(command *command) []string {
+	flags := flag.NewFlagSet(command.name, flag.ContinueOnError)
+	command.flags(flags)
+
+	names := []string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+// splitArgs splits a command line into words, the single and double quotes keep the spaces of a word
+func splitArgs(input string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	quote := rune(0)
+
+	for _, r := range input {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
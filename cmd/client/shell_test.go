@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/client"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input string
+		words []string
+	}{
+		{"", []string{}},
+		{"  laptop   get\tid ", []string{"laptop", "get", "id"}},
+		{`laptop create -from-file "my laptop.json"`, []string{"laptop", "create", "-from-file", "my laptop.json"}},
+		{`image upload id 'a "quoted" name.jpg'`, []string{"image", "upload", "id", `a "quoted" name.jpg`}},
+		{`export -file ""`, []string{"export", "-file", ""}},
+		{`a"b c"d`, []string{"ab cd"}},
+	}
+
+	for _, tc := range testCases {
+		words, err := splitArgs(tc.input)
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.words, words, tc.input)
+	}
+
+	_, err := splitArgs(`laptop create -from-file "my laptop.json`)
+	require.EqualError(t, err, "unterminated quote \"")
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	c := &cli{laptopIds: map[string]bool{"abc": true, "abd": true, "xyz": true}}
+
+	testCases := []struct {
+		input       string
+		pos         int
+		head        string
+		completions []string
+		tail        string
+	}{
+		{"", 0, "", []string{"exit ", "export ", "help ", "history ", "image ", "import ", "laptop ", "next "}, ""},
+		{"lap", 3, "", []string{"laptop "}, ""},
+		{"laptop s", 8, "laptop ", []string{"search "}, ""},
+		{"laptop search -m", 16, "laptop search ", []string{"-max-price ", "-min-cores ", "-min-ghz ", "-min-ram "}, ""},
+		{"laptop get ab", 13, "laptop get ", []string{"abc ", "abd "}, ""},
+		{"laptop get ab rest", 13, "laptop get ", []string{"abc ", "abd "}, " rest"},
+		{"laptop get abc x", 16, "laptop get abc ", nil, ""},
+		{"export x", 8, "export ", nil, ""},
+		{"shell", 5, "", nil, ""},
+	}
+
+	for _, tc := range testCases {
+		head, completions, tail := c.complete(tc.input, tc.pos)
+		require.Equal(t, tc.head, head, tc.input)
+		require.Equal(t, tc.completions, completions, tc.input)
+		require.Equal(t, tc.tail, tail, tc.input)
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	t.Parallel()
+
+	// the flags are listed without running the commands
+	for _, command := range commands {
+		require.NotPanics(t, func() { commandFlags(command) }, command.name)
+	}
+
+	search, _ := findCommand([]string{"laptop", "search"})
+	require.ElementsMatch(t,
+		[]string{"-max-price", "-currency", "-min-cores", "-min-ghz", "-min-ram", "-order", "-limit"},
+		commandFlags(search))
+
+	get, _ := findCommand([]string{"laptop", "get"})
+	require.Empty(t, commandFlags(get))
+}
+
+func TestPager(t *testing.T) {
+	t.Parallel()
+
+	serverAddress, laptopStore := startTestServer(t)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, laptopStore.Save(sample.NewLaptop()))
+	}
+
+	laptopClient, err := client.Dial(serverAddress)
+	require.NoError(t, err)
+	defer laptopClient.Close()
+
+	var stdout, stderr bytes.Buffer
+	out, err := newOutput(&stdout, formatTable)
+	require.NoError(t, err)
+
+	c := &cli{client: laptopClient, out: out, stderr: &stderr, pageSize: 2, laptopIds: make(map[string]bool)}
+	rows := func() int {
+		return strings.Count(stdout.String(), "\n") - strings.Count(stdout.String(), laptopHeader[0]+" ")
+	}
+
+	err = c.startPager(&pb.SearchLaptopRequest{Filter: &pb.Filter{MaxPriceUsd: math.MaxFloat64}})
+	require.NoError(t, err)
+	require.Equal(t, 2, rows())
+	require.Contains(t, stderr.String(), "-- 2 laptops shown, type next for more --")
+
+	require.NoError(t, c.nextPage())
+	require.Equal(t, 4, rows())
+	require.Contains(t, stderr.String(), "-- 4 laptops shown, type next for more --")
+
+	// the last page ends the search
+	require.NoError(t, c.nextPage())
+	require.Equal(t, 5, rows())
+	require.Contains(t, stderr.String(), "-- 5 laptops found --")
+	require.Nil(t, c.pager)
+	require.Len(t, c.laptopIds, 5)
+
+	var usageErr *usageError
+	require.True(t, errors.As(c.nextPage(), &usageErr))
+}
@@ -1,36 +1,396 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/daffarg/grpc-pcbook/gateway"
+	"github.com/daffarg/grpc-pcbook/money"
 	"github.com/daffarg/grpc-pcbook/pb"
 	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/daffarg/grpc-pcbook/tracing"
+	"github.com/daffarg/grpc-pcbook/web"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
 	port := flag.Int("port", 0, "the server port")
+	internalAddress := flag.String("internal-address", "localhost:0", "the host:port of the admin, replication and cluster services, they are not served on the server port")
+	traceExporter := flag.String("trace-exporter", tracing.ExporterNone, "where to export traces: none, stdout or otlp")
+	otlpEndpoint := flag.String("otlp-endpoint", "localhost:4317", "the OTLP/gRPC collector address used by the otlp trace exporter")
+	drainTimeout := flag.Duration("drain-timeout", 30*time.Second, "how long to wait for in-flight requests on shutdown")
+	healthInterval := flag.Duration("health-interval", 5*time.Second, "how often the store readiness is checked")
+	eventHistory := flag.Int("event-history", 10000, "how many laptop events are kept for watchers resuming with a token")
+	enableReflection := flag.Bool("reflection", false, "register the gRPC server reflection service")
+	httpPort := flag.Int("http-port", 0, "the port of the REST/JSON gateway, 0 disables the gateway")
+	webPort := flag.Int("web-port", 0, "the port serving gRPC-Web and Connect for browsers, 0 disables it")
+	corsOrigins := flag.String("cors-origins", "", "comma separated origins allowed to call the web port, * allows every origin")
+	replicateFrom := flag.String("replicate-from", "", "the internal address of the leader to replicate the laptops from, the server is a read-only follower; empty makes the server a leader")
+	shardCount := flag.Int("shards", 1, "how many in-memory stores the laptops are partitioned over by the hash of their ID")
+	cacheEntries := flag.Int("cache-entries", 0, "how many laptops and searches are cached, 0 is no bound")
+	cacheBytes := flag.Uint64("cache-bytes", 0, "how many bytes of laptops are cached, 0 is no bound")
+	cacheTTL := flag.Duration("cache-ttl", 0, "how long the laptops and searches are cached, 0 disables the cache unless it is bounded")
+	raftAddress := flag.String("raft-address", "", "the host:port of the raft messages of the cluster, empty disables clustering")
+	raftDir := flag.String("raft-dir", "raft", "the directory of the raft log and snapshots")
+	clusterAddress := flag.String("cluster-address", "", "the address the other members reach the internal services of this server at, it identifies the member; the internal address by default")
+	bootstrap := flag.Bool("bootstrap", false, "start a new cluster whose first member is this server")
+	join := flag.String("join", "", "the internal address of a member of the cluster this server joins")
+	exchangeRates := flag.String("exchange-rates", "", "the JSON file of the exchange rates used for the prices in other currencies than USD, reloaded on SIGHUP")
 	flag.Parse()
 	log.Printf("Starting server at port %d ", *port)
 
-	laptopStore := service.NewInMemoryLaptopStore()
+	shutdownTracing, err := tracing.Setup(context.Background(), "pcbook-server", *traceExporter, *otlpEndpoint)
+	if err != nil {
+		log.Fatal("cannot setup tracing: ", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	if *shardCount < 1 {
+		log.Fatal("there must be at least one shard")
+	}
+
+	// the searches go to every shard concurrently
+	inMemoryLaptopStores := []*service.InMemoryLaptopStore{}
+	shards := []service.LaptopStore{}
+	for i := 0; i < *shardCount; i++ {
+		inMemoryLaptopStore := service.NewInMemoryLaptopStore()
+		inMemoryLaptopStores = append(inMemoryLaptopStores, inMemoryLaptopStore)
+		shards = append(shards, inMemoryLaptopStore)
+	}
+
+	var catalogLaptopStore interface {
+		service.LaptopStore
+		service.ReadinessChecker
+	} = inMemoryLaptopStores[0]
+	var shardedLaptopStore *service.ShardedLaptopStore
+	if *shardCount > 1 {
+		shardedLaptopStore, err = service.NewShardedLaptopStore(shards...)
+		if err != nil {
+			log.Fatal("cannot create the sharded store: ", err)
+		}
+		catalogLaptopStore = shardedLaptopStore
+	}
+
+	// the replicated and the raft writes are applied below the cache, so they invalidate it as well
+	if *cacheEntries > 0 || *cacheBytes > 0 || *cacheTTL > 0 {
+		catalogLaptopStore = service.NewCachingLaptopStore(catalogLaptopStore, service.CacheOptions{
+			MaxEntries: *cacheEntries,
+			MaxBytes:   *cacheBytes,
+			TTL:        *cacheTTL,
+		})
+	}
+
+	eventLaptopStore := service.NewEventLaptopStore(catalogLaptopStore, service.NewEventBus(*eventHistory))
 	imageStore := service.NewDiskImageStore("img")
 
-	laptopServer := service.NewLaptopServer(laptopStore, imageStore)
-	grpcServer := grpc.NewServer()
+	ratingStore := service.NewInMemoryRatingStore()
+
+	// a follower serves the laptops replicated from the leader, the followers of a follower replicate them in turn
+	var laptopStore service.LaptopStore = eventLaptopStore
+	readinessCheckers := []service.ReadinessChecker{catalogLaptopStore, imageStore}
+	var followerLaptopStore *service.FollowerLaptopStore
+	if *replicateFrom != "" {
+		conn, err := grpc.Dial(
+			*replicateFrom,
+			grpc.WithInsecure(),
+			grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+			grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+		)
+		if err != nil {
+			log.Fatal("cannot dial the leader: ", err)
+		}
+		defer conn.Close()
+
+		followerLaptopStore = service.NewFollowerLaptopStore(eventLaptopStore, *replicateFrom, conn)
+		laptopStore = followerLaptopStore
+		readinessCheckers = append(readinessCheckers, followerLaptopStore)
+	}
+
+	// the internal services let anyone change the catalog or the cluster, they are kept off the server port
+	internalListener, err := net.Listen("tcp", *internalAddress)
+	if err != nil {
+		log.Fatal("cannot start the internal server: ", err)
+	}
+	log.Printf("Starting internal server at %s", internalListener.Addr())
+
+	// the members of a cluster apply the writes committed to the raft log to their event store
+	var raftLaptopStore *service.RaftLaptopStore
+	if *raftAddress != "" {
+		if *replicateFrom != "" {
+			log.Fatal("a cluster member cannot replicate from a leader")
+		}
+		if *clusterAddress == "" {
+			*clusterAddress = internalListener.Addr().String()
+		}
+
+		raftLaptopStore, err = startRaftLaptopStore(eventLaptopStore, *clusterAddress, *raftAddress, *raftDir)
+		if err != nil {
+			log.Fatal("cannot start the cluster member: ", err)
+		}
+		defer raftLaptopStore.Shutdown()
+
+		if *bootstrap {
+			err := raftLaptopStore.Bootstrap()
+			if err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+				log.Fatal("cannot bootstrap the cluster: ", err)
+			}
+		}
+
+		laptopStore = raftLaptopStore
+		readinessCheckers = append(readinessCheckers, raftLaptopStore)
+	}
+
+	laptopServer := service.NewLaptopServer(laptopStore, imageStore, ratingStore)
+
+	if *exchangeRates != "" {
+		rates, err := money.NewFileRateProvider(*exchangeRates)
+		if err != nil {
+			log.Fatal("cannot load the exchange rates: ", err)
+		}
+		laptopServer.Rates = rates
+		for _, inMemoryLaptopStore := range inMemoryLaptopStores {
+			inMemoryLaptopStore.Rates = rates
+		}
+		if shardedLaptopStore != nil {
+			shardedLaptopStore.Rates = rates
+		}
+		go reloadRatesOnHangup(rates)
+	}
+	grpcServer := newGRPCServer()
 	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
 
+	internalServer := newGRPCServer()
+	pb.RegisterAdminServiceServer(internalServer, service.NewAdminServer(catalogLaptopStore, imageStore))
+	pb.RegisterReplicationServiceServer(internalServer, service.NewReplicationServer(eventLaptopStore))
+	if raftLaptopStore != nil {
+		pb.RegisterClusterServiceServer(internalServer, service.NewClusterServer(raftLaptopStore))
+	}
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if *enableReflection {
+		reflection.Register(grpcServer)
+		reflection.Register(internalServer)
+	}
+
+	ctx, stopHealthReporter := context.WithCancel(context.Background())
+	defer stopHealthReporter()
+	go service.NewHealthReporter(healthServer, *healthInterval, readinessCheckers...).Run(ctx)
+
+	if followerLaptopStore != nil {
+		go followerLaptopStore.Run(ctx)
+	}
+
 	address := fmt.Sprintf("0.0.0.0:%d", *port)
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Fatal("cannot start the server: ", err)
 	}
 
+	go func() {
+		err := internalServer.Serve(internalListener)
+		if err != nil {
+			log.Fatal("cannot start the internal server: ", err)
+		}
+	}()
+
+	if raftLaptopStore != nil && *join != "" {
+		go joinCluster(ctx, *join, *clusterAddress, *raftAddress)
+	}
+
+	var httpServers []*http.Server
+	if *httpPort != 0 {
+		httpServer, err := startGateway(listener.Addr().(*net.TCPAddr).Port, *httpPort)
+		if err != nil {
+			log.Fatal("cannot start the gateway: ", err)
+		}
+		httpServers = append(httpServers, httpServer)
+	}
+
+	if *webPort != 0 {
+		handler := web.NewHandler(laptopServer, strings.Split(*corsOrigins, ","))
+		httpServers = append(httpServers, startHTTPServer("gRPC-Web and Connect", *webPort, handler))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-signals
+		log.Printf("received %v, shutting down the server", sig)
+
+		// report not serving so that no new traffic is routed here while draining
+		stopHealthReporter()
+		healthServer.Shutdown()
+
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		for _, httpServer := range httpServers {
+			httpServer.Shutdown(ctx)
+		}
+
+		// the followers replicating from the internal server are drained alongside the clients
+		var wg sync.WaitGroup
+		for _, server := range []*grpc.Server{grpcServer, internalServer} {
+			wg.Add(1)
+			go func(server *grpc.Server) {
+				defer wg.Done()
+				service.StopGracefully(server, *drainTimeout)
+			}(server)
+		}
+		wg.Wait()
+	}()
+
 	err = grpcServer.Serve(listener)
 	if err != nil {
 		log.Fatal("cannot start the server: ", err)
 	}
-}
\ No newline at end of file
+
+	// Serve returns as soon as the listener is closed, wait until the in-flight requests are drained
+	<-stopped
+
+	for _, store := range []interface{}{catalogLaptopStore, imageStore} {
+		if flusher, ok := store.(service.Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				log.Print("cannot flush store: ", err)
+			}
+		}
+	}
+
+	log.Print("server stopped")
+}
+
+func newGRPCServer() *grpc.Server {
+	return grpc.NewServer(
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+}
+
+// startRaftLaptopStore starts a cluster member keeping its raft log and snapshots in the directory
+func startRaftLaptopStore(local service.LaptopStore, id string, raftAddress string, dir string) (*service.RaftLaptopStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := raft.NewTCPTransport(raftAddress, nil, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen to raft address: %w", err)
+	}
+
+	logStore, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open raft log: %w", err)
+	}
+
+	snapshotStore, err := raft.NewFileSnapshotStore(dir, 2, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open raft snapshots: %w", err)
+	}
+
+	return service.NewRaftLaptopStore(local, service.RaftConfig{
+		ID:            id,
+		Transport:     transport,
+		LogStore:      logStore,
+		StableStore:   logStore,
+		SnapshotStore: snapshotStore,
+	})
+}
+
+// joinCluster asks a member of the cluster to add this server, the request is forwarded to the leader.
+// It retries until the member accepts it, the member may be starting as well
+func joinCluster(ctx context.Context, memberAddress string, id string, raftAddress string) {
+	conn, err := grpc.Dial(memberAddress, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal("cannot dial the cluster member: ", err)
+	}
+	defer conn.Close()
+
+	clusterClient := pb.NewClusterServiceClient(conn)
+	for {
+		_, err := clusterClient.AddMember(ctx, &pb.AddMemberRequest{Id: id, RaftAddress: raftAddress})
+		if err == nil {
+			log.Printf("joined the cluster of %s", memberAddress)
+			return
+		}
+		log.Print("cannot join the cluster, retrying: ", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// startGateway serves the REST/JSON gateway on the http port, it calls the gRPC server through a local connection
+func startGateway(grpcPort int, httpPort int) (*http.Server, error) {
+	conn, err := grpc.Dial(
+		fmt.Sprintf("localhost:%d", grpcPort),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := gateway.NewHandler(context.Background(), conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return startHTTPServer("REST gateway", httpPort, handler), nil
+}
+
+func startHTTPServer(name string, port int, handler http.Handler) *http.Server {
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", port),
+		Handler: handler,
+	}
+
+	go func() {
+		log.Printf("Starting %s at port %d", name, port)
+		err := httpServer.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("cannot start the %s: %v", name, err)
+		}
+	}()
+
+	return httpServer
+}
+
+func reloadRatesOnHangup(rates *money.FileRateProvider) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		err := rates.Reload()
+		if err != nil {
+			log.Print("cannot reload the exchange rates, keeping the previous ones: ", err)
+			continue
+		}
+		log.Print("exchange rates reloaded")
+	}
+}
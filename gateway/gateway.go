@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	uploadImagePath = "/v1/laptops/{laptop_id}/images"
+	imageFormField  = "image"
+	chunkSize       = 1024
+)
+
+// NewHandler creates an HTTP/JSON handler which translates REST calls into LaptopService RPCs sent through the connection.
+//
+//	POST /v1/laptops                      -> CreateLaptop, the body is the laptop
+//	GET  /v1/laptops?max_price_usd=...    -> SearchLaptop, the laptops are streamed as newline delimited JSON, price_order sorts them and limit bounds them
+//	GET  /v1/laptops/{id}                 -> GetLaptop
+//	PUT  /v1/laptops/{id}                 -> UpdateLaptop, the body is the laptop
+//	DELETE /v1/laptops/{id}               -> DeleteLaptop
+//	GET  /v1/laptops:watch?resume_token=  -> WatchLaptops, the events are streamed as newline delimited JSON
+//	POST /v1/laptops/{laptop_id}/images   -> UploadImage, the image is sent as the "image" field of a multipart form
+func NewHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, newJSONMarshaler()),
+		runtime.SetQueryParameterParser(&filterQueryParser{}),
+	)
+
+	err := pb.RegisterLaptopServiceHandler(ctx, mux, conn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot register laptop service handler: %w", err)
+	}
+
+	err = mux.HandlePath(http.MethodPost, uploadImagePath, uploadImageHandler(mux, pb.NewLaptopServiceClient(conn)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot register upload image handler: %w", err)
+	}
+
+	return mux, nil
+}
+
+// newJSONMarshaler follows serializer.ProtobufToJSON, without indentation so that streamed messages stay on one line
+func newJSONMarshaler() *runtime.JSONPb {
+	marshalOptions := serializer.JSONMarshalOptions()
+	marshalOptions.Indent = ""
+
+	return &runtime.JSONPb{
+		MarshalOptions:   marshalOptions,
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+}
+
+// filterQueryParser lets the search and watch filter be given without the "filter." prefix,
+// e.g. /v1/laptops?max_price_usd=2000&min_ram.value=8&min_ram.unit=GIGABYTE&limit=10,
+// the keys naming a field of the request itself are left as they are
+type filterQueryParser struct {
+	runtime.DefaultQueryParser
+}
+
+func (parser *filterQueryParser) Parse(message proto.Message, values url.Values, filter *utilities.DoubleArray) error {
+	switch message.(type) {
+	case *pb.SearchLaptopRequest, *pb.WatchLaptopsRequest:
+		fields := message.ProtoReflect().Descriptor().Fields()
+		prefixed := make(url.Values, len(values))
+		for key, value := range values {
+			name := strings.SplitN(key, ".", 2)[0]
+			if fields.ByName(protoreflect.Name(name)) == nil && fields.ByJSONName(name) == nil {
+				key = "filter." + key
+			}
+			prefixed[key] = append(prefixed[key], value...)
+		}
+		values = prefixed
+	}
+
+	return parser.DefaultQueryParser.Parse(message, values, filter)
+}
+
+func uploadImageHandler(mux *runtime.ServeMux, laptopClient pb.LaptopServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		ctx, err := runtime.AnnotateContext(
+			r.Context(),
+			mux,
+			r,
+			"/pb.LaptopService/UploadImage",
+			runtime.WithHTTPPathPattern(uploadImagePath),
+		)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "expected a multipart form : %v", err))
+			return
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "missing form field %q", imageFormField))
+				return
+			}
+			if err != nil {
+				runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "cannot read multipart form : %v", err))
+				return
+			}
+
+			if part.FormName() != imageFormField {
+				continue
+			}
+
+			res, err := uploadImage(ctx, laptopClient, pathParams["laptop_id"], filepath.Ext(part.FileName()), part)
+			if err != nil {
+				runtime.HTTPError(ctx, mux, outbound, w, r, err)
+				return
+			}
+
+			data, err := outbound.Marshal(res)
+			if err != nil {
+				runtime.HTTPError(ctx, mux, outbound, w, r, err)
+				return
+			}
+
+			w.Header().Set("Content-Type", outbound.ContentType(res))
+			w.Write(data)
+			return
+		}
+	}
+}
+
+// uploadImage streams the image to the server in chunks, like cmd/client does
+func uploadImage(ctx context.Context, laptopClient pb.LaptopServiceClient, laptopId string, imageType string, image io.Reader) (*pb.UploadImageResponse, error) {
+	stream, err := laptopClient.UploadImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadImageRequest{
+		Data: &pb.UploadImageRequest_Info{
+			Info: &pb.ImageInfo{
+				LaptopId:  laptopId,
+				ImageType: imageType,
+			},
+		},
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buffer := make([]byte, chunkSize)
+	for err == nil {
+		n, readErr := image.Read(buffer)
+		if n > 0 {
+			// io.EOF means the server has ended the stream, the actual error is returned by CloseAndRecv
+			err = stream.Send(&pb.UploadImageRequest{
+				Data: &pb.UploadImageRequest_ChunkData{
+					ChunkData: buffer[:n],
+				},
+			})
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot read image : %v", readErr)
+		}
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return stream.CloseAndRecv()
+}
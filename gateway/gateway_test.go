@@ -0,0 +1,202 @@
+package gateway_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/gateway"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGatewayCreateLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	gatewayServer := startTestGateway(t, laptopStore, nil)
+
+	laptop := sample.NewLaptop()
+	body, err := serializer.ProtobufToJSON(laptop)
+	require.NoError(t, err)
+
+	res, err := http.Post(gatewayServer.URL+"/v1/laptops", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	createLaptopRes := &pb.CreateLaptopResponse{}
+	requireDecodeJSON(t, res, createLaptopRes)
+	require.Equal(t, laptop.GetId(), createLaptopRes.GetId())
+
+	savedLaptop, err := laptopStore.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, savedLaptop)
+
+	// the same laptop can't be created twice
+	res, err = http.Post(gatewayServer.URL+"/v1/laptops", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusConflict, res.StatusCode)
+}
+
+func TestGatewaySearchLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	expectedIds := make(map[string]bool)
+
+	for i := 0; i < 4; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = 1000
+		if i%2 == 0 {
+			laptop.PriceUsd = 4000
+		} else {
+			expectedIds[laptop.GetId()] = true
+		}
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	gatewayServer := startTestGateway(t, laptopStore, nil)
+
+	laptops := searchLaptops(t, gatewayServer.URL+"/v1/laptops?max_price_usd=2000&min_ram.value=1&min_ram.unit=GIGABYTE")
+	for _, laptop := range laptops {
+		require.Contains(t, expectedIds, laptop.GetId())
+	}
+	require.Len(t, laptops, len(expectedIds))
+}
+
+func TestGatewaySearchLaptopLimit(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	for i := 0; i < 4; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = float64(1000 + i*100)
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	gatewayServer := startTestGateway(t, laptopStore, nil)
+
+	laptops := searchLaptops(t, gatewayServer.URL+"/v1/laptops?max_price_usd=5000&limit=1")
+	require.Len(t, laptops, 1)
+
+	// the limit applies after sorting, so the cheapest laptops are sent
+	laptops = searchLaptops(t, gatewayServer.URL+"/v1/laptops?max_price_usd=5000&price_order=ASCENDING&limit=2")
+	require.Len(t, laptops, 2)
+	require.Equal(t, 1000.0, laptops[0].GetPriceUsd())
+	require.Equal(t, 1100.0, laptops[1].GetPriceUsd())
+}
+
+func TestGatewayUploadImage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	gatewayServer := startTestGateway(t, laptopStore, imageStore)
+	image := bytes.Repeat([]byte{0xab}, 3000)
+
+	res := postImage(t, gatewayServer.URL+"/v1/laptops/"+laptop.GetId()+"/images", "laptop.png", image)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	uploadImageRes := &pb.UploadImageResponse{}
+	requireDecodeJSON(t, res, uploadImageRes)
+	require.NotEmpty(t, uploadImageRes.GetId())
+	require.EqualValues(t, len(image), uploadImageRes.GetSize())
+	require.Equal(t, laptop.GetId(), imageStore.Images[uploadImageRes.GetId()].LaptopID)
+
+	// unknown laptop
+	res = postImage(t, gatewayServer.URL+"/v1/laptops/unknown/images", "laptop.png", image)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func startTestGateway(t *testing.T, laptopStore service.LaptopStore, imageStore service.ImageStore) *httptest.Server {
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, service.NewLaptopServer(laptopStore, imageStore, nil))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	handler, err := gateway.NewHandler(context.Background(), conn)
+	require.NoError(t, err)
+
+	gatewayServer := httptest.NewServer(handler)
+	t.Cleanup(gatewayServer.Close)
+
+	return gatewayServer
+}
+
+// searchLaptops reads the laptops streamed by the search, one JSON result per line
+func searchLaptops(t *testing.T, url string) []*pb.Laptop {
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	laptops := []*pb.Laptop{}
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+
+		searchLaptopRes := &pb.SearchLaptopResponse{}
+		require.NoError(t, protojson.Unmarshal(line.Result, searchLaptopRes))
+		laptops = append(laptops, searchLaptopRes.GetLaptop())
+	}
+	require.NoError(t, scanner.Err())
+
+	return laptops
+}
+
+func postImage(t *testing.T, url string, filename string, image []byte) *http.Response {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("image", filename)
+	require.NoError(t, err)
+	_, err = part.Write(image)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	res, err := http.Post(url, writer.FormDataContentType(), body)
+	require.NoError(t, err)
+
+	return res
+}
+
+func requireDecodeJSON(t *testing.T, res *http.Response, message proto.Message) {
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, protojson.Unmarshal(data, message), fmt.Sprintf("invalid response: %s", data))
+}
@@ -0,0 +1,235 @@
+// Package memunit converts, compares, formats and parses pb.Memory values exactly.
+// Like the rest of the catalog, the multiples are binary: a kilobyte is 1024 bytes.
+package memunit
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+var ErrUnknownUnit = errors.New("unknown memory unit")
+var ErrOverflow = errors.New("memory value overflows")
+var ErrInexact = errors.New("memory value cannot be represented exactly in the unit")
+var ErrInvalidFormat = errors.New("invalid memory format")
+
+// bitsPerUnit is the size of every unit in bits
+var bitsPerUnit = map[pb.Memory_Unit]uint64{
+	pb.Memory_BIT:      1,
+	pb.Memory_BYTE:     8,
+	pb.Memory_KILOBYTE: 8 << 10,
+	pb.Memory_MEGABYTE: 8 << 20,
+	pb.Memory_GIGABYTE: 8 << 30,
+	pb.Memory_TERABYTE: 8 << 40,
+}
+
+// units from the smallest to the largest
+var units = []pb.Memory_Unit{
+	pb.Memory_BIT,
+	pb.Memory_BYTE,
+	pb.Memory_KILOBYTE,
+	pb.Memory_MEGABYTE,
+	pb.Memory_GIGABYTE,
+	pb.Memory_TERABYTE,
+}
+
+var symbols = map[pb.Memory_Unit]string{
+	pb.Memory_BIT:      "bit",
+	pb.Memory_BYTE:     "B",
+	pb.Memory_KILOBYTE: "KB",
+	pb.Memory_MEGABYTE: "MB",
+	pb.Memory_GIGABYTE: "GB",
+	pb.Memory_TERABYTE: "TB",
+}
+
+// parsed symbols, upper cased except the lower case "b" which stands for bit
+var parsedSymbols = map[string]pb.Memory_Unit{
+	"BIT": pb.Memory_BIT, "BITS": pb.Memory_BIT,
+	"B": pb.Memory_BYTE, "BYTE": pb.Memory_BYTE, "BYTES": pb.Memory_BYTE,
+	"K": pb.Memory_KILOBYTE, "KB": pb.Memory_KILOBYTE, "KIB": pb.Memory_KILOBYTE, "KILOBYTE": pb.Memory_KILOBYTE,
+	"M": pb.Memory_MEGABYTE, "MB": pb.Memory_MEGABYTE, "MIB": pb.Memory_MEGABYTE, "MEGABYTE": pb.Memory_MEGABYTE,
+	"G": pb.Memory_GIGABYTE, "GB": pb.Memory_GIGABYTE, "GIB": pb.Memory_GIGABYTE, "GIGABYTE": pb.Memory_GIGABYTE,
+	"T": pb.Memory_TERABYTE, "TB": pb.Memory_TERABYTE, "TIB": pb.Memory_TERABYTE, "TERABYTE": pb.Memory_TERABYTE,
+}
+
+// ValidUnit reports whether the unit is one of the known units, UNKNOWN is not
+func ValidUnit(unit pb.Memory_Unit) bool {
+	_, ok := bitsPerUnit[unit]
+	return ok
+}
+
+// BitsPerUnit returns the size of the unit in bits
+func BitsPerUnit(unit pb.Memory_Unit) (uint64, error) {
+	size, ok := bitsPerUnit[unit]
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrUnknownUnit, unit)
+	}
+	return size, nil
+}
+
+// ToBits returns the memory size in bits, a nil memory is empty
+func ToBits(memory *pb.Memory) (uint64, error) {
+	hi, lo, err := toBits128(memory)
+	if err != nil {
+		return 0, err
+	}
+	if hi != 0 {
+		return 0, fmt.Errorf("%w: %d %v in bits", ErrOverflow, memory.GetValue(), memory.GetUnit())
+	}
+	return lo, nil
+}
+
+// toBits128 returns the memory size in bits as a 128 bits integer, which cannot overflow
+func toBits128(memory *pb.Memory) (hi uint64, lo uint64, err error) {
+	if memory.GetValue() == 0 {
+		return 0, 0, nil // the unit of an empty memory doesn't matter
+	}
+
+	size, err := BitsPerUnit(memory.GetUnit())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	hi, lo = bits.Mul64(memory.GetValue(), size)
+	return hi, lo, nil
+}
+
+// Convert returns the memory in the given unit, it fails if the value isn't a whole number in that unit
+func Convert(memory *pb.Memory, unit pb.Memory_Unit) (*pb.Memory, error) {
+	hi, lo, err := toBits128(memory)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := BitsPerUnit(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if hi >= size {
+		return nil, fmt.Errorf("%w: %d %v in %v", ErrOverflow, memory.GetValue(), memory.GetUnit(), unit)
+	}
+
+	value, remainder := bits.Div64(hi, lo, size)
+	if remainder != 0 {
+		return nil, fmt.Errorf("%w: %d %v in %v", ErrInexact, memory.GetValue(), memory.GetUnit(), unit)
+	}
+
+	return &pb.Memory{Value: value, Unit: unit}, nil
+}
+
+// Compare returns -1, 0 or +1 depending on whether a is smaller, equal or larger than b, nil memories are empty
+func Compare(a *pb.Memory, b *pb.Memory) (int, error) {
+	aHi, aLo, err := toBits128(a)
+	if err != nil {
+		return 0, err
+	}
+
+	bHi, bLo, err := toBits128(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case aHi < bHi || (aHi == bHi && aLo < bLo):
+		return -1, nil
+	case aHi > bHi || (aHi == bHi && aLo > bLo):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Normalize returns the memory in the largest unit in which its value is a whole number, 4096 MB becomes 4 GB
+func Normalize(memory *pb.Memory) (*pb.Memory, error) {
+	if !ValidUnit(memory.GetUnit()) {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownUnit, memory.GetUnit())
+	}
+
+	if memory.GetValue() == 0 {
+		return &pb.Memory{Value: 0, Unit: memory.GetUnit()}, nil
+	}
+
+	for i := len(units) - 1; i >= 0; i-- {
+		normalized, err := Convert(memory, units[i])
+		if err == nil {
+			return normalized, nil
+		}
+		if !errors.Is(err, ErrInexact) && !errors.Is(err, ErrOverflow) {
+			return nil, err
+		}
+	}
+
+	// unreachable, the value is always whole in its own unit
+	return &pb.Memory{Value: memory.GetValue(), Unit: memory.GetUnit()}, nil
+}
+
+// Format returns the memory as "16 GB", a nil memory is "0 B"
+func Format(memory *pb.Memory) string {
+	if memory == nil {
+		return "0 B"
+	}
+
+	symbol, ok := symbols[memory.GetUnit()]
+	if !ok {
+		symbol = memory.GetUnit().String()
+	}
+	return fmt.Sprintf("%d %s", memory.GetValue(), symbol)
+}
+
+// Parse reads a memory such as "512GiB", "16 GB" or "1.5 TB", a fractional value is converted
+// to the largest smaller unit in which it is a whole number
+func Parse(s string) (*pb.Memory, error) {
+	s = strings.TrimSpace(s)
+
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if end <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, s)
+	}
+
+	value, ok := new(big.Rat).SetString(s[:end])
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, s)
+	}
+
+	symbol := strings.TrimSpace(s[end:])
+	unit, ok := pb.Memory_BIT, symbol == "b"
+	if !ok {
+		unit, ok = parsedSymbols[strings.ToUpper(symbol)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown unit %q", ErrInvalidFormat, symbol)
+	}
+
+	// move to smaller units until the value is whole
+	for i := unitIndex(unit); !value.IsInt(); i-- {
+		if i == 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInexact, s)
+		}
+		ratio := bitsPerUnit[units[i]] / bitsPerUnit[units[i-1]]
+		value.Mul(value, new(big.Rat).SetInt64(int64(ratio)))
+		unit = units[i-1]
+	}
+
+	if !value.Num().IsUint64() {
+		return nil, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	return &pb.Memory{Value: value.Num().Uint64(), Unit: unit}, nil
+}
+
+func unitIndex(unit pb.Memory_Unit) int {
+	for i, other := range units {
+		if other == unit {
+			return i
+		}
+	}
+	return -1
+}
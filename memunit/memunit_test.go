@@ -0,0 +1,192 @@
+package memunit_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/memunit"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToBits(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		memory *pb.Memory
+		bits   uint64
+		err    error
+	}{
+		{&pb.Memory{Value: 3, Unit: pb.Memory_BIT}, 3, nil},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_BYTE}, 24, nil},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_KILOBYTE}, 3 * 8 * 1024, nil},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_MEGABYTE}, 3 * 8 * 1024 * 1024, nil},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_GIGABYTE}, 3 * 8 * 1024 * 1024 * 1024, nil},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_TERABYTE}, 3 * 8 * 1024 * 1024 * 1024 * 1024, nil},
+		{&pb.Memory{Value: 1 << 21, Unit: pb.Memory_TERABYTE}, 0, memunit.ErrOverflow},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_UNKNOWN}, 0, memunit.ErrUnknownUnit},
+		{&pb.Memory{Value: 0, Unit: pb.Memory_UNKNOWN}, 0, nil},
+		{nil, 0, nil},
+	}
+
+	for _, tc := range testCases {
+		bits, err := memunit.ToBits(tc.memory)
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, memunit.Format(tc.memory))
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.bits, bits, memunit.Format(tc.memory))
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		memory   *pb.Memory
+		unit     pb.Memory_Unit
+		expected uint64
+		err      error
+	}{
+		{&pb.Memory{Value: 16, Unit: pb.Memory_BIT}, pb.Memory_BYTE, 2, nil},
+		{&pb.Memory{Value: 2048, Unit: pb.Memory_BYTE}, pb.Memory_KILOBYTE, 2, nil},
+		{&pb.Memory{Value: 4096, Unit: pb.Memory_KILOBYTE}, pb.Memory_MEGABYTE, 4, nil},
+		{&pb.Memory{Value: 4096, Unit: pb.Memory_MEGABYTE}, pb.Memory_GIGABYTE, 4, nil},
+		{&pb.Memory{Value: 1024, Unit: pb.Memory_GIGABYTE}, pb.Memory_TERABYTE, 1, nil},
+		{&pb.Memory{Value: 1, Unit: pb.Memory_TERABYTE}, pb.Memory_MEGABYTE, 1 << 20, nil},
+		{&pb.Memory{Value: 2, Unit: pb.Memory_TERABYTE}, pb.Memory_BIT, 2 << 43, nil},
+		{&pb.Memory{Value: 1000, Unit: pb.Memory_MEGABYTE}, pb.Memory_GIGABYTE, 0, memunit.ErrInexact},
+		{&pb.Memory{Value: 12, Unit: pb.Memory_BIT}, pb.Memory_BYTE, 0, memunit.ErrInexact},
+		{&pb.Memory{Value: math.MaxUint64, Unit: pb.Memory_TERABYTE}, pb.Memory_GIGABYTE, 0, memunit.ErrOverflow},
+		{&pb.Memory{Value: 1, Unit: pb.Memory_GIGABYTE}, pb.Memory_UNKNOWN, 0, memunit.ErrUnknownUnit},
+	}
+
+	for _, tc := range testCases {
+		converted, err := memunit.Convert(tc.memory, tc.unit)
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, memunit.Format(tc.memory))
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.unit, converted.GetUnit())
+		require.Equal(t, tc.expected, converted.GetValue(), memunit.Format(tc.memory))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b     *pb.Memory
+		expected int
+	}{
+		// 4096 MB used to compare wrongly against 4 GB
+		{&pb.Memory{Value: 4096, Unit: pb.Memory_MEGABYTE}, &pb.Memory{Value: 4, Unit: pb.Memory_GIGABYTE}, 0},
+		{&pb.Memory{Value: 4095, Unit: pb.Memory_MEGABYTE}, &pb.Memory{Value: 4, Unit: pb.Memory_GIGABYTE}, -1},
+		{&pb.Memory{Value: 1025, Unit: pb.Memory_KILOBYTE}, &pb.Memory{Value: 1, Unit: pb.Memory_MEGABYTE}, 1},
+		{&pb.Memory{Value: 8, Unit: pb.Memory_BIT}, &pb.Memory{Value: 1, Unit: pb.Memory_BYTE}, 0},
+		{&pb.Memory{Value: 1, Unit: pb.Memory_TERABYTE}, &pb.Memory{Value: 1024, Unit: pb.Memory_GIGABYTE}, 0},
+		// values which overflow in bits still compare
+		{&pb.Memory{Value: math.MaxUint64, Unit: pb.Memory_TERABYTE}, &pb.Memory{Value: math.MaxUint64, Unit: pb.Memory_GIGABYTE}, 1},
+		{nil, &pb.Memory{Value: 1, Unit: pb.Memory_BIT}, -1},
+		{nil, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		cmp, err := memunit.Compare(tc.a, tc.b)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, cmp, "%s vs %s", memunit.Format(tc.a), memunit.Format(tc.b))
+	}
+
+	_, err := memunit.Compare(&pb.Memory{Value: 1, Unit: pb.Memory_UNKNOWN}, nil)
+	require.ErrorIs(t, err, memunit.ErrUnknownUnit)
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		memory   *pb.Memory
+		expected *pb.Memory
+	}{
+		{&pb.Memory{Value: 16, Unit: pb.Memory_BIT}, &pb.Memory{Value: 2, Unit: pb.Memory_BYTE}},
+		{&pb.Memory{Value: 12, Unit: pb.Memory_BIT}, &pb.Memory{Value: 12, Unit: pb.Memory_BIT}},
+		{&pb.Memory{Value: 1536, Unit: pb.Memory_BYTE}, &pb.Memory{Value: 1536, Unit: pb.Memory_BYTE}},
+		{&pb.Memory{Value: 2048, Unit: pb.Memory_KILOBYTE}, &pb.Memory{Value: 2, Unit: pb.Memory_MEGABYTE}},
+		{&pb.Memory{Value: 4096, Unit: pb.Memory_MEGABYTE}, &pb.Memory{Value: 4, Unit: pb.Memory_GIGABYTE}},
+		{&pb.Memory{Value: 2048, Unit: pb.Memory_GIGABYTE}, &pb.Memory{Value: 2, Unit: pb.Memory_TERABYTE}},
+		{&pb.Memory{Value: 3, Unit: pb.Memory_TERABYTE}, &pb.Memory{Value: 3, Unit: pb.Memory_TERABYTE}},
+		{&pb.Memory{Value: 0, Unit: pb.Memory_GIGABYTE}, &pb.Memory{Value: 0, Unit: pb.Memory_GIGABYTE}},
+	}
+
+	for _, tc := range testCases {
+		normalized, err := memunit.Normalize(tc.memory)
+		require.NoError(t, err)
+		require.Equal(t, memunit.Format(tc.expected), memunit.Format(normalized))
+	}
+
+	_, err := memunit.Normalize(&pb.Memory{Value: 1})
+	require.ErrorIs(t, err, memunit.ErrUnknownUnit)
+}
+
+func TestFormatAndParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		memory    *pb.Memory
+		formatted string
+	}{
+		{&pb.Memory{Value: 12, Unit: pb.Memory_BIT}, "12 bit"},
+		{&pb.Memory{Value: 512, Unit: pb.Memory_BYTE}, "512 B"},
+		{&pb.Memory{Value: 64, Unit: pb.Memory_KILOBYTE}, "64 KB"},
+		{&pb.Memory{Value: 256, Unit: pb.Memory_MEGABYTE}, "256 MB"},
+		{&pb.Memory{Value: 16, Unit: pb.Memory_GIGABYTE}, "16 GB"},
+		{&pb.Memory{Value: 2, Unit: pb.Memory_TERABYTE}, "2 TB"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.formatted, memunit.Format(tc.memory))
+
+		parsed, err := memunit.Parse(tc.formatted)
+		require.NoError(t, err)
+		require.Equal(t, tc.memory.GetValue(), parsed.GetValue())
+		require.Equal(t, tc.memory.GetUnit(), parsed.GetUnit())
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input    string
+		expected *pb.Memory
+		err      error
+	}{
+		{"512GiB", &pb.Memory{Value: 512, Unit: pb.Memory_GIGABYTE}, nil},
+		{"16gb", &pb.Memory{Value: 16, Unit: pb.Memory_GIGABYTE}, nil},
+		{" 8 G ", &pb.Memory{Value: 8, Unit: pb.Memory_GIGABYTE}, nil},
+		{"1.5 TB", &pb.Memory{Value: 1536, Unit: pb.Memory_GIGABYTE}, nil},
+		{"0.5KiB", &pb.Memory{Value: 512, Unit: pb.Memory_BYTE}, nil},
+		{"64b", &pb.Memory{Value: 64, Unit: pb.Memory_BIT}, nil},
+		{"64 bits", &pb.Memory{Value: 64, Unit: pb.Memory_BIT}, nil},
+		{"4 MEGABYTE", &pb.Memory{Value: 4, Unit: pb.Memory_MEGABYTE}, nil},
+		{"0.3 bit", nil, memunit.ErrInexact},
+		{"18446744073709551616 B", nil, memunit.ErrOverflow},
+		{"GB", nil, memunit.ErrInvalidFormat},
+		{"12 parsecs", nil, memunit.ErrInvalidFormat},
+		{"1.2.3 GB", nil, memunit.ErrInvalidFormat},
+		{"-4 GB", nil, memunit.ErrInvalidFormat},
+	}
+
+	for _, tc := range testCases {
+		parsed, err := memunit.Parse(tc.input)
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, tc.input)
+			continue
+		}
+		require.NoError(t, err, tc.input)
+		require.Equal(t, tc.expected.GetValue(), parsed.GetValue(), tc.input)
+		require.Equal(t, tc.expected.GetUnit(), parsed.GetUnit(), tc.input)
+	}
+}
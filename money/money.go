@@ -0,0 +1,81 @@
+// Package money converts the pb.Money prices between currencies.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// USD is the currency of the legacy price_usd fields
+const USD = "USD"
+
+const nanosPerUnit = 1e9
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCurrencyCode reports whether the code looks like an ISO 4217 code
+func ValidCurrencyCode(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// RateProvider gives the exchange rates between currencies
+type RateProvider interface {
+	// Rate returns how much one unit of the from currency is worth in the to currency
+	Rate(from string, to string) (float64, error)
+}
+
+// FromFloat returns the amount as money, rounded to the nano unit
+func FromFloat(currency string, amount float64) *pb.Money {
+	units, fraction := math.Modf(amount)
+	nanos := math.Round(fraction * nanosPerUnit)
+	if math.Abs(nanos) >= nanosPerUnit {
+		units, nanos = units+math.Copysign(1, nanos), 0 // the fraction was rounded up to a whole unit
+	}
+
+	return &pb.Money{
+		CurrencyCode: currency,
+		Units:        int64(units),
+		Nanos:        int32(nanos),
+	}
+}
+
+func ToFloat(money *pb.Money) float64 {
+	return float64(money.GetUnits()) + float64(money.GetNanos())/nanosPerUnit
+}
+
+// Amount returns the money converted into the currency, the rates may be nil if no conversion is needed
+func Amount(money *pb.Money, currency string, rates RateProvider) (float64, error) {
+	if money.GetCurrencyCode() == currency {
+		return ToFloat(money), nil
+	}
+
+	if rates == nil {
+		return 0, fmt.Errorf("%w: no exchange rate from %s to %s", ErrUnknownCurrency, money.GetCurrencyCode(), currency)
+	}
+
+	rate, err := rates.Rate(money.GetCurrencyCode(), currency)
+	if err != nil {
+		return 0, err
+	}
+
+	return ToFloat(money) * rate, nil
+}
+
+func Convert(money *pb.Money, currency string, rates RateProvider) (*pb.Money, error) {
+	amount, err := Amount(money, currency, rates)
+	if err != nil {
+		return nil, err
+	}
+	return FromFloat(currency, amount), nil
+}
+
+// Format returns the money as "1299.99 EUR"
+func Format(money *pb.Money) string {
+	return fmt.Sprintf("%.2f %s", ToFloat(money), money.GetCurrencyCode())
+}
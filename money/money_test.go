@@ -0,0 +1,108 @@
+package money_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFloat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		amount   float64
+		expected *pb.Money
+	}{
+		{1299.99, &pb.Money{CurrencyCode: money.USD, Units: 1299, Nanos: 990000000}},
+		{0.5, &pb.Money{CurrencyCode: money.USD, Units: 0, Nanos: 500000000}},
+		{-1.25, &pb.Money{CurrencyCode: money.USD, Units: -1, Nanos: -250000000}},
+		{2.9999999999, &pb.Money{CurrencyCode: money.USD, Units: 3, Nanos: 0}},
+		{0, &pb.Money{CurrencyCode: money.USD}},
+	}
+
+	for _, tc := range testCases {
+		price := money.FromFloat(money.USD, tc.amount)
+		require.Equal(t, tc.expected.GetUnits(), price.GetUnits(), tc.amount)
+		require.Equal(t, tc.expected.GetNanos(), price.GetNanos(), tc.amount)
+		require.InDelta(t, tc.amount, money.ToFloat(price), 1e-9)
+	}
+}
+
+func TestAmount(t *testing.T) {
+	t.Parallel()
+
+	rates := &money.Rates{Base: money.USD, Rates: map[string]float64{"EUR": 0.5, "IDR": 15000}}
+	price := &pb.Money{CurrencyCode: "EUR", Units: 100}
+
+	amount, err := money.Amount(price, "EUR", nil)
+	require.NoError(t, err)
+	require.Equal(t, 100.0, amount)
+
+	amount, err = money.Amount(price, money.USD, rates)
+	require.NoError(t, err)
+	require.InDelta(t, 200, amount, 1e-9)
+
+	amount, err = money.Amount(price, "IDR", rates)
+	require.NoError(t, err)
+	require.InDelta(t, 3000000, amount, 1e-6)
+
+	converted, err := money.Convert(price, money.USD, rates)
+	require.NoError(t, err)
+	require.Equal(t, "200.00 USD", money.Format(converted))
+
+	_, err = money.Amount(price, money.USD, nil)
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+
+	_, err = money.Amount(price, "JPY", rates)
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
+
+func TestFileRateProvider(t *testing.T) {
+	t.Parallel()
+
+	provider, err := money.NewFileRateProvider("testdata/rates.json")
+	require.NoError(t, err)
+
+	rate, err := provider.Rate("EUR", "JPY")
+	require.NoError(t, err)
+	require.InDelta(t, 149.5/0.92, rate, 1e-9)
+
+	rate, err = provider.Rate(money.USD, "IDR")
+	require.NoError(t, err)
+	require.Equal(t, 15600.0, rate)
+
+	_, err = provider.Rate(money.USD, "GBP")
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+
+	_, err = money.NewFileRateProvider("testdata/missing.json")
+	require.Error(t, err)
+}
+
+func TestFileRateProviderReload(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"base":"USD","rates":{"EUR":0.9}}`), 0o644))
+
+	provider, err := money.NewFileRateProvider(filename)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filename, []byte(`{"base":"USD","rates":{"EUR":0.8}}`), 0o644))
+	require.NoError(t, provider.Reload())
+
+	rate, err := provider.Rate(money.USD, "EUR")
+	require.NoError(t, err)
+	require.Equal(t, 0.8, rate)
+
+	// invalid rates are rejected and the previous ones are kept
+	require.NoError(t, os.WriteFile(filename, []byte(`{"base":"USD","rates":{"eur":-1}}`), 0o644))
+	require.Error(t, provider.Reload())
+
+	rate, err = provider.Rate(money.USD, "EUR")
+	require.NoError(t, err)
+	require.Equal(t, 0.8, rate)
+}
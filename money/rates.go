@@ -0,0 +1,95 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// Rates holds how much one unit of the base currency is worth in every other currency
+type Rates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (rates *Rates) Rate(from string, to string) (float64, error) {
+	fromRate, err := rates.baseRate(from)
+	if err != nil {
+		return 0, err
+	}
+
+	toRate, err := rates.baseRate(to)
+	if err != nil {
+		return 0, err
+	}
+
+	return toRate / fromRate, nil
+}
+
+func (rates *Rates) baseRate(currency string) (float64, error) {
+	if currency == rates.Base {
+		return 1, nil
+	}
+
+	rate, ok := rates.Rates[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return rate, nil
+}
+
+// FileRateProvider reads the rates from a JSON file such as
+// {"base": "USD", "rates": {"EUR": 0.92, "IDR": 15600, "JPY": 149.5}}
+type FileRateProvider struct {
+	mutex    sync.RWMutex
+	filename string
+	rates    *Rates
+}
+
+func NewFileRateProvider(filename string) (*FileRateProvider, error) {
+	provider := &FileRateProvider{filename: filename}
+
+	err := provider.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// Reload reads the file again, the previous rates are kept if the file is invalid
+func (provider *FileRateProvider) Reload() error {
+	data, err := ioutil.ReadFile(provider.filename)
+	if err != nil {
+		return fmt.Errorf("cannot read exchange rates file %w", err)
+	}
+
+	rates := &Rates{}
+	err = json.Unmarshal(data, rates)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshall exchange rates %w", err)
+	}
+
+	if !ValidCurrencyCode(rates.Base) {
+		return fmt.Errorf("invalid base currency %q", rates.Base)
+	}
+	for currency, rate := range rates.Rates {
+		if !ValidCurrencyCode(currency) || rate <= 0 {
+			return fmt.Errorf("invalid exchange rate %v for %q", rate, currency)
+		}
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.rates = rates
+	return nil
+}
+
+func (provider *FileRateProvider) Rate(from string, to string) (float64, error) {
+	provider.mutex.RLock()
+	defer provider.mutex.RUnlock()
+
+	return provider.rates.Rate(from, to)
+}
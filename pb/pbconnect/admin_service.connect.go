@@ -0,0 +1,159 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: admin_service.proto
+
+package pbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	pb "github.com/daffarg/grpc-pcbook/pb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion0_1_0
+
+const (
+	// AdminServiceName is the fully-qualified name of the AdminService service.
+	AdminServiceName = "pb.AdminService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// AdminServiceGetStoreStatsProcedure is the fully-qualified name of the AdminService's
+	// GetStoreStats RPC.
+	AdminServiceGetStoreStatsProcedure = "/pb.AdminService/GetStoreStats"
+	// AdminServiceSetLogLevelProcedure is the fully-qualified name of the AdminService's SetLogLevel
+	// RPC.
+	AdminServiceSetLogLevelProcedure = "/pb.AdminService/SetLogLevel"
+	// AdminServiceCompactStoreProcedure is the fully-qualified name of the AdminService's CompactStore
+	// RPC.
+	AdminServiceCompactStoreProcedure = "/pb.AdminService/CompactStore"
+)
+
+// AdminServiceClient is a client for the pb.AdminService service.
+type AdminServiceClient interface {
+	GetStoreStats(context.Context, *connect.Request[pb.GetStoreStatsRequest]) (*connect.Response[pb.GetStoreStatsResponse], error)
+	SetLogLevel(context.Context, *connect.Request[pb.SetLogLevelRequest]) (*connect.Response[pb.SetLogLevelResponse], error)
+	CompactStore(context.Context, *connect.Request[pb.CompactStoreRequest]) (*connect.Response[pb.CompactStoreResponse], error)
+}
+
+// NewAdminServiceClient constructs a client for the pb.AdminService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewAdminServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) AdminServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &adminServiceClient{
+		getStoreStats: connect.NewClient[pb.GetStoreStatsRequest, pb.GetStoreStatsResponse](
+			httpClient,
+			baseURL+AdminServiceGetStoreStatsProcedure,
+			opts...,
+		),
+		setLogLevel: connect.NewClient[pb.SetLogLevelRequest, pb.SetLogLevelResponse](
+			httpClient,
+			baseURL+AdminServiceSetLogLevelProcedure,
+			opts...,
+		),
+		compactStore: connect.NewClient[pb.CompactStoreRequest, pb.CompactStoreResponse](
+			httpClient,
+			baseURL+AdminServiceCompactStoreProcedure,
+			opts...,
+		),
+	}
+}
+
+// adminServiceClient implements AdminServiceClient.
+type adminServiceClient struct {
+	getStoreStats *connect.Client[pb.GetStoreStatsRequest, pb.GetStoreStatsResponse]
+	setLogLevel   *connect.Client[pb.SetLogLevelRequest, pb.SetLogLevelResponse]
+	compactStore  *connect.Client[pb.CompactStoreRequest, pb.CompactStoreResponse]
+}
+
+// GetStoreStats calls pb.AdminService.GetStoreStats.
+func (c *adminServiceClient) GetStoreStats(ctx context.Context, req *connect.Request[pb.GetStoreStatsRequest]) (*connect.Response[pb.GetStoreStatsResponse], error) {
+	return c.getStoreStats.CallUnary(ctx, req)
+}
+
+// SetLogLevel calls pb.AdminService.SetLogLevel.
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, req *connect.Request[pb.SetLogLevelRequest]) (*connect.Response[pb.SetLogLevelResponse], error) {
+	return c.setLogLevel.CallUnary(ctx, req)
+}
+
+// CompactStore calls pb.AdminService.CompactStore.
+func (c *adminServiceClient) CompactStore(ctx context.Context, req *connect.Request[pb.CompactStoreRequest]) (*connect.Response[pb.CompactStoreResponse], error) {
+	return c.compactStore.CallUnary(ctx, req)
+}
+
+// AdminServiceHandler is an implementation of the pb.AdminService service.
+type AdminServiceHandler interface {
+	GetStoreStats(context.Context, *connect.Request[pb.GetStoreStatsRequest]) (*connect.Response[pb.GetStoreStatsResponse], error)
+	SetLogLevel(context.Context, *connect.Request[pb.SetLogLevelRequest]) (*connect.Response[pb.SetLogLevelResponse], error)
+	CompactStore(context.Context, *connect.Request[pb.CompactStoreRequest]) (*connect.Response[pb.CompactStoreResponse], error)
+}
+
+// NewAdminServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewAdminServiceHandler(svc AdminServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	adminServiceGetStoreStatsHandler := connect.NewUnaryHandler(
+		AdminServiceGetStoreStatsProcedure,
+		svc.GetStoreStats,
+		opts...,
+	)
+	adminServiceSetLogLevelHandler := connect.NewUnaryHandler(
+		AdminServiceSetLogLevelProcedure,
+		svc.SetLogLevel,
+		opts...,
+	)
+	adminServiceCompactStoreHandler := connect.NewUnaryHandler(
+		AdminServiceCompactStoreProcedure,
+		svc.CompactStore,
+		opts...,
+	)
+	return "/pb.AdminService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case AdminServiceGetStoreStatsProcedure:
+			adminServiceGetStoreStatsHandler.ServeHTTP(w, r)
+		case AdminServiceSetLogLevelProcedure:
+			adminServiceSetLogLevelHandler.ServeHTTP(w, r)
+		case AdminServiceCompactStoreProcedure:
+			adminServiceCompactStoreHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedAdminServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedAdminServiceHandler struct{}
+
+func (UnimplementedAdminServiceHandler) GetStoreStats(context.Context, *connect.Request[pb.GetStoreStatsRequest]) (*connect.Response[pb.GetStoreStatsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.AdminService.GetStoreStats is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) SetLogLevel(context.Context, *connect.Request[pb.SetLogLevelRequest]) (*connect.Response[pb.SetLogLevelResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.AdminService.SetLogLevel is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) CompactStore(context.Context, *connect.Request[pb.CompactStoreRequest]) (*connect.Response[pb.CompactStoreResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.AdminService.CompactStore is not implemented"))
+}
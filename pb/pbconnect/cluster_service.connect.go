@@ -0,0 +1,186 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: cluster_service.proto
+
+package pbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	pb "github.com/daffarg/grpc-pcbook/pb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion0_1_0
+
+const (
+	// ClusterServiceName is the fully-qualified name of the ClusterService service.
+	ClusterServiceName = "pb.ClusterService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// ClusterServiceApplyCommandProcedure is the fully-qualified name of the ClusterService's
+	// ApplyCommand RPC.
+	ClusterServiceApplyCommandProcedure = "/pb.ClusterService/ApplyCommand"
+	// ClusterServiceAddMemberProcedure is the fully-qualified name of the ClusterService's AddMember
+	// RPC.
+	ClusterServiceAddMemberProcedure = "/pb.ClusterService/AddMember"
+	// ClusterServiceRemoveMemberProcedure is the fully-qualified name of the ClusterService's
+	// RemoveMember RPC.
+	ClusterServiceRemoveMemberProcedure = "/pb.ClusterService/RemoveMember"
+	// ClusterServiceListMembersProcedure is the fully-qualified name of the ClusterService's
+	// ListMembers RPC.
+	ClusterServiceListMembersProcedure = "/pb.ClusterService/ListMembers"
+)
+
+// ClusterServiceClient is a client for the pb.ClusterService service.
+type ClusterServiceClient interface {
+	ApplyCommand(context.Context, *connect.Request[pb.ApplyCommandRequest]) (*connect.Response[pb.ApplyCommandResponse], error)
+	AddMember(context.Context, *connect.Request[pb.AddMemberRequest]) (*connect.Response[pb.AddMemberResponse], error)
+	RemoveMember(context.Context, *connect.Request[pb.RemoveMemberRequest]) (*connect.Response[pb.RemoveMemberResponse], error)
+	ListMembers(context.Context, *connect.Request[pb.ListMembersRequest]) (*connect.Response[pb.ListMembersResponse], error)
+}
+
+// NewClusterServiceClient constructs a client for the pb.ClusterService service. By default, it
+// uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewClusterServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) ClusterServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &clusterServiceClient{
+		applyCommand: connect.NewClient[pb.ApplyCommandRequest, pb.ApplyCommandResponse](
+			httpClient,
+			baseURL+ClusterServiceApplyCommandProcedure,
+			opts...,
+		),
+		addMember: connect.NewClient[pb.AddMemberRequest, pb.AddMemberResponse](
+			httpClient,
+			baseURL+ClusterServiceAddMemberProcedure,
+			opts...,
+		),
+		removeMember: connect.NewClient[pb.RemoveMemberRequest, pb.RemoveMemberResponse](
+			httpClient,
+			baseURL+ClusterServiceRemoveMemberProcedure,
+			opts...,
+		),
+		listMembers: connect.NewClient[pb.ListMembersRequest, pb.ListMembersResponse](
+			httpClient,
+			baseURL+ClusterServiceListMembersProcedure,
+			opts...,
+		),
+	}
+}
+
+// clusterServiceClient implements ClusterServiceClient.
+type clusterServiceClient struct {
+	applyCommand *connect.Client[pb.ApplyCommandRequest, pb.ApplyCommandResponse]
+	addMember    *connect.Client[pb.AddMemberRequest, pb.AddMemberResponse]
+	removeMember *connect.Client[pb.RemoveMemberRequest, pb.RemoveMemberResponse]
+	listMembers  *connect.Client[pb.ListMembersRequest, pb.ListMembersResponse]
+}
+
+// ApplyCommand calls pb.ClusterService.ApplyCommand.
+func (c *clusterServiceClient) ApplyCommand(ctx context.Context, req *connect.Request[pb.ApplyCommandRequest]) (*connect.Response[pb.ApplyCommandResponse], error) {
+	return c.applyCommand.CallUnary(ctx, req)
+}
+
+// AddMember calls pb.ClusterService.AddMember.
+func (c *clusterServiceClient) AddMember(ctx context.Context, req *connect.Request[pb.AddMemberRequest]) (*connect.Response[pb.AddMemberResponse], error) {
+	return c.addMember.CallUnary(ctx, req)
+}
+
+// RemoveMember calls pb.ClusterService.RemoveMember.
+func (c *clusterServiceClient) RemoveMember(ctx context.Context, req *connect.Request[pb.RemoveMemberRequest]) (*connect.Response[pb.RemoveMemberResponse], error) {
+	return c.removeMember.CallUnary(ctx, req)
+}
+
+// ListMembers calls pb.ClusterService.ListMembers.
+func (c *clusterServiceClient) ListMembers(ctx context.Context, req *connect.Request[pb.ListMembersRequest]) (*connect.Response[pb.ListMembersResponse], error) {
+	return c.listMembers.CallUnary(ctx, req)
+}
+
+// ClusterServiceHandler is an implementation of the pb.ClusterService service.
+type ClusterServiceHandler interface {
+	ApplyCommand(context.Context, *connect.Request[pb.ApplyCommandRequest]) (*connect.Response[pb.ApplyCommandResponse], error)
+	AddMember(context.Context, *connect.Request[pb.AddMemberRequest]) (*connect.Response[pb.AddMemberResponse], error)
+	RemoveMember(context.Context, *connect.Request[pb.RemoveMemberRequest]) (*connect.Response[pb.RemoveMemberResponse], error)
+	ListMembers(context.Context, *connect.Request[pb.ListMembersRequest]) (*connect.Response[pb.ListMembersResponse], error)
+}
+
+// NewClusterServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewClusterServiceHandler(svc ClusterServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	clusterServiceApplyCommandHandler := connect.NewUnaryHandler(
+		ClusterServiceApplyCommandProcedure,
+		svc.ApplyCommand,
+		opts...,
+	)
+	clusterServiceAddMemberHandler := connect.NewUnaryHandler(
+		ClusterServiceAddMemberProcedure,
+		svc.AddMember,
+		opts...,
+	)
+	clusterServiceRemoveMemberHandler := connect.NewUnaryHandler(
+		ClusterServiceRemoveMemberProcedure,
+		svc.RemoveMember,
+		opts...,
+	)
+	clusterServiceListMembersHandler := connect.NewUnaryHandler(
+		ClusterServiceListMembersProcedure,
+		svc.ListMembers,
+		opts...,
+	)
+	return "/pb.ClusterService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case ClusterServiceApplyCommandProcedure:
+			clusterServiceApplyCommandHandler.ServeHTTP(w, r)
+		case ClusterServiceAddMemberProcedure:
+			clusterServiceAddMemberHandler.ServeHTTP(w, r)
+		case ClusterServiceRemoveMemberProcedure:
+			clusterServiceRemoveMemberHandler.ServeHTTP(w, r)
+		case ClusterServiceListMembersProcedure:
+			clusterServiceListMembersHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedClusterServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedClusterServiceHandler struct{}
+
+func (UnimplementedClusterServiceHandler) ApplyCommand(context.Context, *connect.Request[pb.ApplyCommandRequest]) (*connect.Response[pb.ApplyCommandResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.ClusterService.ApplyCommand is not implemented"))
+}
+
+func (UnimplementedClusterServiceHandler) AddMember(context.Context, *connect.Request[pb.AddMemberRequest]) (*connect.Response[pb.AddMemberResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.ClusterService.AddMember is not implemented"))
+}
+
+func (UnimplementedClusterServiceHandler) RemoveMember(context.Context, *connect.Request[pb.RemoveMemberRequest]) (*connect.Response[pb.RemoveMemberResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.ClusterService.RemoveMember is not implemented"))
+}
+
+func (UnimplementedClusterServiceHandler) ListMembers(context.Context, *connect.Request[pb.ListMembersRequest]) (*connect.Response[pb.ListMembersResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.ClusterService.ListMembers is not implemented"))
+}
@@ -0,0 +1,347 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: laptop_service.proto
+
+package pbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	pb "github.com/daffarg/grpc-pcbook/pb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion0_1_0
+
+const (
+	// LaptopServiceName is the fully-qualified name of the LaptopService service.
+	LaptopServiceName = "pb.LaptopService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// LaptopServiceCreateLaptopProcedure is the fully-qualified name of the LaptopService's
+	// CreateLaptop RPC.
+	LaptopServiceCreateLaptopProcedure = "/pb.LaptopService/CreateLaptop"
+	// LaptopServiceSearchLaptopProcedure is the fully-qualified name of the LaptopService's
+	// SearchLaptop RPC.
+	LaptopServiceSearchLaptopProcedure = "/pb.LaptopService/SearchLaptop"
+	// LaptopServiceGetLaptopProcedure is the fully-qualified name of the LaptopService's GetLaptop RPC.
+	LaptopServiceGetLaptopProcedure = "/pb.LaptopService/GetLaptop"
+	// LaptopServiceUploadImageProcedure is the fully-qualified name of the LaptopService's UploadImage
+	// RPC.
+	LaptopServiceUploadImageProcedure = "/pb.LaptopService/UploadImage"
+	// LaptopServiceDownloadImageProcedure is the fully-qualified name of the LaptopService's
+	// DownloadImage RPC.
+	LaptopServiceDownloadImageProcedure = "/pb.LaptopService/DownloadImage"
+	// LaptopServiceRateLaptopProcedure is the fully-qualified name of the LaptopService's RateLaptop
+	// RPC.
+	LaptopServiceRateLaptopProcedure = "/pb.LaptopService/RateLaptop"
+	// LaptopServiceUpdateLaptopProcedure is the fully-qualified name of the LaptopService's
+	// UpdateLaptop RPC.
+	LaptopServiceUpdateLaptopProcedure = "/pb.LaptopService/UpdateLaptop"
+	// LaptopServiceDeleteLaptopProcedure is the fully-qualified name of the LaptopService's
+	// DeleteLaptop RPC.
+	LaptopServiceDeleteLaptopProcedure = "/pb.LaptopService/DeleteLaptop"
+	// LaptopServiceWatchLaptopsProcedure is the fully-qualified name of the LaptopService's
+	// WatchLaptops RPC.
+	LaptopServiceWatchLaptopsProcedure = "/pb.LaptopService/WatchLaptops"
+	// LaptopServiceBulkCreateLaptopsProcedure is the fully-qualified name of the LaptopService's
+	// BulkCreateLaptops RPC.
+	LaptopServiceBulkCreateLaptopsProcedure = "/pb.LaptopService/BulkCreateLaptops"
+)
+
+// LaptopServiceClient is a client for the pb.LaptopService service.
+type LaptopServiceClient interface {
+	CreateLaptop(context.Context, *connect.Request[pb.CreateLaptopRequest]) (*connect.Response[pb.CreateLaptopResponse], error)
+	SearchLaptop(context.Context, *connect.Request[pb.SearchLaptopRequest]) (*connect.ServerStreamForClient[pb.SearchLaptopResponse], error)
+	GetLaptop(context.Context, *connect.Request[pb.GetLaptopRequest]) (*connect.Response[pb.GetLaptopResponse], error)
+	UploadImage(context.Context) *connect.ClientStreamForClient[pb.UploadImageRequest, pb.UploadImageResponse]
+	DownloadImage(context.Context, *connect.Request[pb.DownloadImageRequest]) (*connect.ServerStreamForClient[pb.DownloadImageResponse], error)
+	RateLaptop(context.Context) *connect.BidiStreamForClient[pb.RateLaptopRequest, pb.RateLaptopResponse]
+	UpdateLaptop(context.Context, *connect.Request[pb.UpdateLaptopRequest]) (*connect.Response[pb.UpdateLaptopResponse], error)
+	DeleteLaptop(context.Context, *connect.Request[pb.DeleteLaptopRequest]) (*connect.Response[pb.DeleteLaptopResponse], error)
+	WatchLaptops(context.Context, *connect.Request[pb.WatchLaptopsRequest]) (*connect.ServerStreamForClient[pb.WatchLaptopsResponse], error)
+	BulkCreateLaptops(context.Context) *connect.ClientStreamForClient[pb.BulkCreateLaptopsRequest, pb.BulkCreateLaptopsResponse]
+}
+
+// NewLaptopServiceClient constructs a client for the pb.LaptopService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewLaptopServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) LaptopServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &laptopServiceClient{
+		createLaptop: connect.NewClient[pb.CreateLaptopRequest, pb.CreateLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceCreateLaptopProcedure,
+			opts...,
+		),
+		searchLaptop: connect.NewClient[pb.SearchLaptopRequest, pb.SearchLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceSearchLaptopProcedure,
+			opts...,
+		),
+		getLaptop: connect.NewClient[pb.GetLaptopRequest, pb.GetLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceGetLaptopProcedure,
+			opts...,
+		),
+		uploadImage: connect.NewClient[pb.UploadImageRequest, pb.UploadImageResponse](
+			httpClient,
+			baseURL+LaptopServiceUploadImageProcedure,
+			opts...,
+		),
+		downloadImage: connect.NewClient[pb.DownloadImageRequest, pb.DownloadImageResponse](
+			httpClient,
+			baseURL+LaptopServiceDownloadImageProcedure,
+			opts...,
+		),
+		rateLaptop: connect.NewClient[pb.RateLaptopRequest, pb.RateLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceRateLaptopProcedure,
+			opts...,
+		),
+		updateLaptop: connect.NewClient[pb.UpdateLaptopRequest, pb.UpdateLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceUpdateLaptopProcedure,
+			opts...,
+		),
+		deleteLaptop: connect.NewClient[pb.DeleteLaptopRequest, pb.DeleteLaptopResponse](
+			httpClient,
+			baseURL+LaptopServiceDeleteLaptopProcedure,
+			opts...,
+		),
+		watchLaptops: connect.NewClient[pb.WatchLaptopsRequest, pb.WatchLaptopsResponse](
+			httpClient,
+			baseURL+LaptopServiceWatchLaptopsProcedure,
+			opts...,
+		),
+		bulkCreateLaptops: connect.NewClient[pb.BulkCreateLaptopsRequest, pb.BulkCreateLaptopsResponse](
+			httpClient,
+			baseURL+LaptopServiceBulkCreateLaptopsProcedure,
+			opts...,
+		),
+	}
+}
+
+// laptopServiceClient implements LaptopServiceClient.
+type laptopServiceClient struct {
+	createLaptop      *connect.Client[pb.CreateLaptopRequest, pb.CreateLaptopResponse]
+	searchLaptop      *connect.Client[pb.SearchLaptopRequest, pb.SearchLaptopResponse]
+	getLaptop         *connect.Client[pb.GetLaptopRequest, pb.GetLaptopResponse]
+	uploadImage       *connect.Client[pb.UploadImageRequest, pb.UploadImageResponse]
+	downloadImage     *connect.Client[pb.DownloadImageRequest, pb.DownloadImageResponse]
+	rateLaptop        *connect.Client[pb.RateLaptopRequest, pb.RateLaptopResponse]
+	updateLaptop      *connect.Client[pb.UpdateLaptopRequest, pb.UpdateLaptopResponse]
+	deleteLaptop      *connect.Client[pb.DeleteLaptopRequest, pb.DeleteLaptopResponse]
+	watchLaptops      *connect.Client[pb.WatchLaptopsRequest, pb.WatchLaptopsResponse]
+	bulkCreateLaptops *connect.Client[pb.BulkCreateLaptopsRequest, pb.BulkCreateLaptopsResponse]
+}
+
+// CreateLaptop calls pb.LaptopService.CreateLaptop.
+func (c *laptopServiceClient) CreateLaptop(ctx context.Context, req *connect.Request[pb.CreateLaptopRequest]) (*connect.Response[pb.CreateLaptopResponse], error) {
+	return c.createLaptop.CallUnary(ctx, req)
+}
+
+// SearchLaptop calls pb.LaptopService.SearchLaptop.
+func (c *laptopServiceClient) SearchLaptop(ctx context.Context, req *connect.Request[pb.SearchLaptopRequest]) (*connect.ServerStreamForClient[pb.SearchLaptopResponse], error) {
+	return c.searchLaptop.CallServerStream(ctx, req)
+}
+
+// GetLaptop calls pb.LaptopService.GetLaptop.
+func (c *laptopServiceClient) GetLaptop(ctx context.Context, req *connect.Request[pb.GetLaptopRequest]) (*connect.Response[pb.GetLaptopResponse], error) {
+	return c.getLaptop.CallUnary(ctx, req)
+}
+
+// UploadImage calls pb.LaptopService.UploadImage.
+func (c *laptopServiceClient) UploadImage(ctx context.Context) *connect.ClientStreamForClient[pb.UploadImageRequest, pb.UploadImageResponse] {
+	return c.uploadImage.CallClientStream(ctx)
+}
+
+// DownloadImage calls pb.LaptopService.DownloadImage.
+func (c *laptopServiceClient) DownloadImage(ctx context.Context, req *connect.Request[pb.DownloadImageRequest]) (*connect.ServerStreamForClient[pb.DownloadImageResponse], error) {
+	return c.downloadImage.CallServerStream(ctx, req)
+}
+
+// RateLaptop calls pb.LaptopService.RateLaptop.
+func (c *laptopServiceClient) RateLaptop(ctx context.Context) *connect.BidiStreamForClient[pb.RateLaptopRequest, pb.RateLaptopResponse] {
+	return c.rateLaptop.CallBidiStream(ctx)
+}
+
+// UpdateLaptop calls pb.LaptopService.UpdateLaptop.
+func (c *laptopServiceClient) UpdateLaptop(ctx context.Context, req *connect.Request[pb.UpdateLaptopRequest]) (*connect.Response[pb.UpdateLaptopResponse], error) {
+	return c.updateLaptop.CallUnary(ctx, req)
+}
+
+// DeleteLaptop calls pb.LaptopService.DeleteLaptop.
+func (c *laptopServiceClient) DeleteLaptop(ctx context.Context, req *connect.Request[pb.DeleteLaptopRequest]) (*connect.Response[pb.DeleteLaptopResponse], error) {
+	return c.deleteLaptop.CallUnary(ctx, req)
+}
+
+// WatchLaptops calls pb.LaptopService.WatchLaptops.
+func (c *laptopServiceClient) WatchLaptops(ctx context.Context, req *connect.Request[pb.WatchLaptopsRequest]) (*connect.ServerStreamForClient[pb.WatchLaptopsResponse], error) {
+	return c.watchLaptops.CallServerStream(ctx, req)
+}
+
+// BulkCreateLaptops calls pb.LaptopService.BulkCreateLaptops.
+func (c *laptopServiceClient) BulkCreateLaptops(ctx context.Context) *connect.ClientStreamForClient[pb.BulkCreateLaptopsRequest, pb.BulkCreateLaptopsResponse] {
+	return c.bulkCreateLaptops.CallClientStream(ctx)
+}
+
+// LaptopServiceHandler is an implementation of the pb.LaptopService service.
+type LaptopServiceHandler interface {
+	CreateLaptop(context.Context, *connect.Request[pb.CreateLaptopRequest]) (*connect.Response[pb.CreateLaptopResponse], error)
+	SearchLaptop(context.Context, *connect.Request[pb.SearchLaptopRequest], *connect.ServerStream[pb.SearchLaptopResponse]) error
+	GetLaptop(context.Context, *connect.Request[pb.GetLaptopRequest]) (*connect.Response[pb.GetLaptopResponse], error)
+	UploadImage(context.Context, *connect.ClientStream[pb.UploadImageRequest]) (*connect.Response[pb.UploadImageResponse], error)
+	DownloadImage(context.Context, *connect.Request[pb.DownloadImageRequest], *connect.ServerStream[pb.DownloadImageResponse]) error
+	RateLaptop(context.Context, *connect.BidiStream[pb.RateLaptopRequest, pb.RateLaptopResponse]) error
+	UpdateLaptop(context.Context, *connect.Request[pb.UpdateLaptopRequest]) (*connect.Response[pb.UpdateLaptopResponse], error)
+	DeleteLaptop(context.Context, *connect.Request[pb.DeleteLaptopRequest]) (*connect.Response[pb.DeleteLaptopResponse], error)
+	WatchLaptops(context.Context, *connect.Request[pb.WatchLaptopsRequest], *connect.ServerStream[pb.WatchLaptopsResponse]) error
+	BulkCreateLaptops(context.Context, *connect.ClientStream[pb.BulkCreateLaptopsRequest]) (*connect.Response[pb.BulkCreateLaptopsResponse], error)
+}
+
+// NewLaptopServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewLaptopServiceHandler(svc LaptopServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	laptopServiceCreateLaptopHandler := connect.NewUnaryHandler(
+		LaptopServiceCreateLaptopProcedure,
+		svc.CreateLaptop,
+		opts...,
+	)
+	laptopServiceSearchLaptopHandler := connect.NewServerStreamHandler(
+		LaptopServiceSearchLaptopProcedure,
+		svc.SearchLaptop,
+		opts...,
+	)
+	laptopServiceGetLaptopHandler := connect.NewUnaryHandler(
+		LaptopServiceGetLaptopProcedure,
+		svc.GetLaptop,
+		opts...,
+	)
+	laptopServiceUploadImageHandler := connect.NewClientStreamHandler(
+		LaptopServiceUploadImageProcedure,
+		svc.UploadImage,
+		opts...,
+	)
+	laptopServiceDownloadImageHandler := connect.NewServerStreamHandler(
+		LaptopServiceDownloadImageProcedure,
+		svc.DownloadImage,
+		opts...,
+	)
+	laptopServiceRateLaptopHandler := connect.NewBidiStreamHandler(
+		LaptopServiceRateLaptopProcedure,
+		svc.RateLaptop,
+		opts...,
+	)
+	laptopServiceUpdateLaptopHandler := connect.NewUnaryHandler(
+		LaptopServiceUpdateLaptopProcedure,
+		svc.UpdateLaptop,
+		opts...,
+	)
+	laptopServiceDeleteLaptopHandler := connect.NewUnaryHandler(
+		LaptopServiceDeleteLaptopProcedure,
+		svc.DeleteLaptop,
+		opts...,
+	)
+	laptopServiceWatchLaptopsHandler := connect.NewServerStreamHandler(
+		LaptopServiceWatchLaptopsProcedure,
+		svc.WatchLaptops,
+		opts...,
+	)
+	laptopServiceBulkCreateLaptopsHandler := connect.NewClientStreamHandler(
+		LaptopServiceBulkCreateLaptopsProcedure,
+		svc.BulkCreateLaptops,
+		opts...,
+	)
+	return "/pb.LaptopService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LaptopServiceCreateLaptopProcedure:
+			laptopServiceCreateLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceSearchLaptopProcedure:
+			laptopServiceSearchLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceGetLaptopProcedure:
+			laptopServiceGetLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceUploadImageProcedure:
+			laptopServiceUploadImageHandler.ServeHTTP(w, r)
+		case LaptopServiceDownloadImageProcedure:
+			laptopServiceDownloadImageHandler.ServeHTTP(w, r)
+		case LaptopServiceRateLaptopProcedure:
+			laptopServiceRateLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceUpdateLaptopProcedure:
+			laptopServiceUpdateLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceDeleteLaptopProcedure:
+			laptopServiceDeleteLaptopHandler.ServeHTTP(w, r)
+		case LaptopServiceWatchLaptopsProcedure:
+			laptopServiceWatchLaptopsHandler.ServeHTTP(w, r)
+		case LaptopServiceBulkCreateLaptopsProcedure:
+			laptopServiceBulkCreateLaptopsHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedLaptopServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedLaptopServiceHandler struct{}
+
+func (UnimplementedLaptopServiceHandler) CreateLaptop(context.Context, *connect.Request[pb.CreateLaptopRequest]) (*connect.Response[pb.CreateLaptopResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.CreateLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) SearchLaptop(context.Context, *connect.Request[pb.SearchLaptopRequest], *connect.ServerStream[pb.SearchLaptopResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.SearchLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) GetLaptop(context.Context, *connect.Request[pb.GetLaptopRequest]) (*connect.Response[pb.GetLaptopResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.GetLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) UploadImage(context.Context, *connect.ClientStream[pb.UploadImageRequest]) (*connect.Response[pb.UploadImageResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.UploadImage is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) DownloadImage(context.Context, *connect.Request[pb.DownloadImageRequest], *connect.ServerStream[pb.DownloadImageResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.DownloadImage is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) RateLaptop(context.Context, *connect.BidiStream[pb.RateLaptopRequest, pb.RateLaptopResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.RateLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) UpdateLaptop(context.Context, *connect.Request[pb.UpdateLaptopRequest]) (*connect.Response[pb.UpdateLaptopResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.UpdateLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) DeleteLaptop(context.Context, *connect.Request[pb.DeleteLaptopRequest]) (*connect.Response[pb.DeleteLaptopResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.DeleteLaptop is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) WatchLaptops(context.Context, *connect.Request[pb.WatchLaptopsRequest], *connect.ServerStream[pb.WatchLaptopsResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.WatchLaptops is not implemented"))
+}
+
+func (UnimplementedLaptopServiceHandler) BulkCreateLaptops(context.Context, *connect.ClientStream[pb.BulkCreateLaptopsRequest]) (*connect.Response[pb.BulkCreateLaptopsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("pb.LaptopService.BulkCreateLaptops is not implemented"))
+}
@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: replication_service.proto
+
+package pbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	pb "github.com/daffarg/grpc-pcbook/pb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion0_1_0
+
+const (
+	// ReplicationServiceName is the fully-qualified name of the ReplicationService service.
+	ReplicationServiceName = "pb.ReplicationService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// ReplicationServiceReplicateProcedure is the fully-qualified name of the ReplicationService's
+	// Replicate RPC.
+	ReplicationServiceReplicateProcedure = "/pb.ReplicationService/Replicate"
+)
+
+// ReplicationServiceClient is a client for the pb.ReplicationService service.
+type ReplicationServiceClient interface {
+	Replicate(context.Context, *connect.Request[pb.ReplicateRequest]) (*connect.ServerStreamForClient[pb.ReplicateResponse], error)
+}
+
+// NewReplicationServiceClient constructs a client for the pb.ReplicationService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewReplicationServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) ReplicationServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &replicationServiceClient{
+		replicate: connect.NewClient[pb.ReplicateRequest, pb.ReplicateResponse](
+			httpClient,
+			baseURL+ReplicationServiceReplicateProcedure,
+			opts...,
+		),
+	}
+}
+
+// replicationServiceClient implements ReplicationServiceClient.
+type replicationServiceClient struct {
+	replicate *connect.Client[pb.ReplicateRequest, pb.ReplicateResponse]
+}
+
+// Replicate calls pb.ReplicationService.Replicate.
+func (c *replicationServiceClient) Replicate(ctx context.Context, req *connect.Request[pb.ReplicateRequest]) (*connect.ServerStreamForClient[pb.ReplicateResponse], error) {
+	return c.replicate.CallServerStream(ctx, req)
+}
+
+// ReplicationServiceHandler is an implementation of the pb.ReplicationService service.
+type ReplicationServiceHandler interface {
+	Replicate(context.Context, *connect.Request[pb.ReplicateRequest], *connect.ServerStream[pb.ReplicateResponse]) error
+}
+
+// NewReplicationServiceHandler builds an HTTP handler from the service implementation. It returns
+// the path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewReplicationServiceHandler(svc ReplicationServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	replicationServiceReplicateHandler := connect.NewServerStreamHandler(
+		ReplicationServiceReplicateProcedure,
+		svc.Replicate,
+		opts...,
+	)
+	return "/pb.ReplicationService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case ReplicationServiceReplicateProcedure:
+			replicationServiceReplicateHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedReplicationServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedReplicationServiceHandler struct{}
+
+func (UnimplementedReplicationServiceHandler) Replicate(context.Context, *connect.Request[pb.ReplicateRequest], *connect.ServerStream[pb.ReplicateResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("pb.ReplicationService.Replicate is not implemented"))
+}
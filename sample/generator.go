@@ -1,6 +1,7 @@
 package sample
 
 import (
+	"github.com/daffarg/grpc-pcbook/money"
 	"github.com/daffarg/grpc-pcbook/pb"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/uuid"
@@ -101,6 +102,8 @@ func NewLaptop() *pb.Laptop {
 	brand := randomLaptopBrand()
 	name := randomLaptopName(brand)
 
+	price := money.FromFloat(money.USD, randomFloat64(1500, 3000))
+
 	laptop := &pb.Laptop{
 		Id: uuid.New().String(),
 		Name: name,
@@ -114,10 +117,15 @@ func NewLaptop() *pb.Laptop {
 		Weight: &pb.Laptop_WeightKg{
 			WeightKg: randomFloat64(1.0, 3.0),
 		},
-		PriceUsd: randomFloat64(1500, 3000),
+		PriceUsd: money.ToFloat(price),
 		ReleaseYear: uint32(randomInt(2015, 2023)),
 		UpdatedAt: ptypes.TimestampNow(),
+		Price: price,
 	}
 
 	return laptop
-}
\ No newline at end of file
+}
+
+func RandomLaptopScore() float64 {
+	return float64(randomInt(1, 10))
+}
@@ -0,0 +1,219 @@
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// catalog formats, one laptop per record
+const (
+	FormatJSONL  = "jsonl"
+	FormatCSV    = "csv"
+	FormatBinary = "binary" // a message stream with the checksum of every laptop, see MessageWriter
+)
+
+const maxJSONLineSize = 1 << 20
+
+// FormatFromFilename guesses the catalog format from the file extension, JSONL by default
+func FormatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".bin", ".pb":
+		return FormatBinary
+	default:
+		return FormatJSONL
+	}
+}
+
+// LaptopWriter writes laptops one by one, Flush must be called once every laptop is written
+type LaptopWriter interface {
+	Write(laptop *pb.Laptop) error
+	Flush() error
+}
+
+// LaptopReader reads laptops one by one, it returns io.EOF once every laptop is read
+type LaptopReader interface {
+	Read() (*pb.Laptop, error)
+}
+
+func NewLaptopWriter(w io.Writer, format string) (LaptopWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlLaptopWriter{writer: bufio.NewWriter(w)}, nil
+	case FormatCSV:
+		return newCSVLaptopWriter(w), nil
+	case FormatBinary:
+		writer, err := NewMessageWriter(w, StreamOptions{Checksum: true})
+		if err != nil {
+			return nil, err
+		}
+		return &binaryLaptopWriter{writer: writer}, nil
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+}
+
+func NewLaptopReader(r io.Reader, format string) (LaptopReader, error) {
+	switch format {
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+		return &jsonlLaptopReader{scanner: scanner}, nil
+	case FormatCSV:
+		return newCSVLaptopReader(r), nil
+	case FormatBinary:
+		return &binaryLaptopReader{r: r}, nil
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+}
+
+// ExportLaptops writes every laptop found by the search function, it returns how many laptops were written
+func ExportLaptops(w io.Writer, format string, search func(found func(*pb.Laptop) error) error) (int, error) {
+	writer, err := NewLaptopWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = search(func(laptop *pb.Laptop) error {
+		err := writer.Write(laptop)
+		if err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("cannot export laptops %w", err)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return count, fmt.Errorf("cannot flush exported laptops %w", err)
+	}
+
+	return count, nil
+}
+
+// ImportLaptops calls save with every laptop read, it returns how many laptops were saved
+func ImportLaptops(r io.Reader, format string, save func(*pb.Laptop) error) (int, error) {
+	reader, err := NewLaptopReader(r, format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		laptop, err := reader.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("cannot import laptop %d %w", count+1, err)
+		}
+
+		err = save(laptop)
+		if err != nil {
+			return count, fmt.Errorf("cannot save imported laptop %d %w", count+1, err)
+		}
+		count++
+	}
+}
+
+type jsonlLaptopWriter struct {
+	writer *bufio.Writer
+}
+
+func (w *jsonlLaptopWriter) Write(laptop *pb.Laptop) error {
+	marshaler := JSONMarshalOptions()
+	marshaler.Indent = "" // a laptop per line
+
+	data, err := marshaler.Marshal(laptop)
+	if err != nil {
+		return fmt.Errorf("cannot marshall proto to json %w", err)
+	}
+
+	_, err = w.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (w *jsonlLaptopWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+type jsonlLaptopReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *jsonlLaptopReader) Read() (*pb.Laptop, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		laptop := &pb.Laptop{}
+		err := protojson.Unmarshal(line, laptop)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshall json to proto %w", err)
+		}
+		return laptop, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type binaryLaptopWriter struct {
+	writer *MessageWriter
+}
+
+func (w *binaryLaptopWriter) Write(laptop *pb.Laptop) error {
+	return w.writer.Write(laptop)
+}
+
+func (w *binaryLaptopWriter) Flush() error {
+	return w.writer.Close()
+}
+
+// binaryLaptopReader reads the stream header on the first Read, an empty input has no laptops
+type binaryLaptopReader struct {
+	r      io.Reader
+	reader *MessageReader
+}
+
+func (r *binaryLaptopReader) Read() (*pb.Laptop, error) {
+	if r.reader == nil {
+		reader, err := NewMessageReader(r.r)
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.reader = reader
+	}
+
+	laptop := &pb.Laptop{}
+	err := r.reader.Read(laptop)
+	if err == io.EOF {
+		r.reader.Close()
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return laptop, nil
+}
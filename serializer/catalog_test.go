@@ -0,0 +1,122 @@
+package serializer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExportImportLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptops := []*pb.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+
+	// a laptop without optional data must survive the round trip as well
+	laptops[2].Gpus = nil
+	laptops[2].Weight = &pb.Laptop_WeightLb{WeightLb: 3.5}
+
+	// and so must the separators of the repeated CSV columns
+	laptops[0].Gpus = append(laptops[0].Gpus, sample.NewGPU())
+	laptops[0].Gpus[0].Brand = `Brand; with | separators \`
+	laptops[0].Gpus[1].Name = `RTX 4090 \| Ti;`
+
+	formats := []string{serializer.FormatJSONL, serializer.FormatCSV, serializer.FormatBinary}
+
+	for _, format := range formats {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			var buffer bytes.Buffer
+			count, err := serializer.ExportLaptops(&buffer, format, func(found func(*pb.Laptop) error) error {
+				for _, laptop := range laptops {
+					if err := found(laptop); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, len(laptops), count)
+
+			imported := []*pb.Laptop{}
+			count, err = serializer.ImportLaptops(&buffer, format, func(laptop *pb.Laptop) error {
+				imported = append(imported, laptop)
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, len(laptops), count)
+
+			for i, laptop := range laptops {
+				require.True(t, proto.Equal(laptop, imported[i]), "laptop %d differs: %v != %v", i, laptop, imported[i])
+			}
+		})
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, serializer.FormatCSV, serializer.FormatFromFilename("catalog.CSV"))
+	require.Equal(t, serializer.FormatBinary, serializer.FormatFromFilename("catalog.bin"))
+	require.Equal(t, serializer.FormatBinary, serializer.FormatFromFilename("catalog.pb"))
+	require.Equal(t, serializer.FormatJSONL, serializer.FormatFromFilename("catalog.jsonl"))
+	require.Equal(t, serializer.FormatJSONL, serializer.FormatFromFilename("-"))
+}
+
+func TestImportLaptopsCorruptedBinary(t *testing.T) {
+	t.Parallel()
+
+	// an empty input has no laptops
+	count, err := serializer.ImportLaptops(&bytes.Buffer{}, serializer.FormatBinary, func(laptop *pb.Laptop) error { return nil })
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	var buffer bytes.Buffer
+	_, err = serializer.ExportLaptops(&buffer, serializer.FormatBinary, func(found func(*pb.Laptop) error) error {
+		return found(sample.NewLaptop())
+	})
+	require.NoError(t, err)
+
+	// corrupt the last byte of the laptop, it is followed by the 4 bytes of its checksum
+	data := buffer.Bytes()
+	data[len(data)-5] ^= 0xff
+
+	_, err = serializer.ImportLaptops(bytes.NewReader(data), serializer.FormatBinary, func(laptop *pb.Laptop) error { return nil })
+	require.ErrorIs(t, err, serializer.ErrChecksumMismatch)
+}
+
+func TestImportLaptopsFromCSV(t *testing.T) {
+	t.Parallel()
+
+	// the columns can be reordered or left out
+	data := "name,id,cpu_number_cores,ram_value,ram_unit,storages\n" +
+		"Thinkpad,5f0c7c1e-4a3c-4b0e-9d59-9d3c2a0f8f10,8,16,gigabyte,SSD|512|GIGABYTE;HDD|1|TERABYTE\n"
+
+	imported := []*pb.Laptop{}
+	_, err := serializer.ImportLaptops(strings.NewReader(data), serializer.FormatCSV, func(laptop *pb.Laptop) error {
+		imported = append(imported, laptop)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, imported, 1)
+
+	laptop := imported[0]
+	require.Equal(t, "Thinkpad", laptop.GetName())
+	require.EqualValues(t, 8, laptop.GetCpu().GetNumberCores())
+	require.Equal(t, pb.Memory_GIGABYTE, laptop.GetRam().GetUnit())
+	require.Len(t, laptop.GetStorages(), 2)
+	require.Equal(t, pb.Storage_HDD, laptop.GetStorages()[1].GetDriver())
+	require.Equal(t, pb.Memory_TERABYTE, laptop.GetStorages()[1].GetMemory().GetUnit())
+
+	_, err = serializer.ImportLaptops(strings.NewReader("id,cpu_number_cores\nx,-1\n"), serializer.FormatCSV, func(*pb.Laptop) error {
+		return nil
+	})
+	require.ErrorContains(t, err, "cpu_number_cores")
+}
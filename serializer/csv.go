@@ -0,0 +1,458 @@
+package serializer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CSVHeader lists the flattened laptop columns, the repeated gpus and storages are written in a single
+// column each: the items are separated by ";" and the fields of an item by "|", a backslash escapes
+// the separators and itself in the fields
+var CSVHeader = []string{
+	"id", "name", "brand",
+	"cpu_brand", "cpu_name", "cpu_number_cores", "cpu_number_threads", "cpu_min_ghz", "cpu_max_ghz",
+	"ram_value", "ram_unit",
+	"gpus",     // brand|name|min_ghz|max_ghz|memory_value|memory_unit
+	"storages", // driver|memory_value|memory_unit
+	"screen_size_inch", "screen_resolution_width", "screen_resolution_height", "screen_panel", "screen_multitouch",
+	"keyboard_layout", "keyboard_backlit",
+	"weight_kg", "weight_lb", "price_usd", "release_year", "updated_at",
+	"price_currency_code", "price_units", "price_nanos",
+}
+
+const (
+	csvItemSeparator  = ";"
+	csvFieldSeparator = "|"
+	csvEscape         = `\`
+)
+
+var csvEscaper = strings.NewReplacer(
+	csvEscape, csvEscape+csvEscape,
+	csvItemSeparator, csvEscape+csvItemSeparator,
+	csvFieldSeparator, csvEscape+csvFieldSeparator,
+)
+
+type csvLaptopWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVLaptopWriter(w io.Writer) *csvLaptopWriter {
+	return &csvLaptopWriter{writer: csv.NewWriter(w)}
+}
+
+func (w *csvLaptopWriter) Write(laptop *pb.Laptop) error {
+	if !w.wroteHeader {
+		err := w.writer.Write(CSVHeader)
+		if err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	return w.writer.Write(laptopToCSVRecord(laptop))
+}
+
+func (w *csvLaptopWriter) Flush() error {
+	if !w.wroteHeader { // an empty catalog still has its header
+		err := w.writer.Write(CSVHeader)
+		if err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type csvLaptopReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVLaptopReader(r io.Reader) *csvLaptopReader {
+	return &csvLaptopReader{reader: csv.NewReader(r)}
+}
+
+func (r *csvLaptopReader) Read() (*pb.Laptop, error) {
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		// the columns may come in any order
+		r.columns = make(map[string]int)
+		for i, column := range header {
+			r.columns[strings.TrimSpace(column)] = i
+		}
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return csvRecordToLaptop(record, r.columns)
+}
+
+func laptopToCSVRecord(laptop *pb.Laptop) []string {
+	gpus := make([]string, 0, len(laptop.GetGpus()))
+	for _, gpu := range laptop.GetGpus() {
+		gpus = append(gpus, joinCSVFields(
+			gpu.GetBrand(),
+			gpu.GetName(),
+			formatFloat(gpu.GetMinGhz()),
+			formatFloat(gpu.GetMaxGhz()),
+			strconv.FormatUint(gpu.GetMemory().GetValue(), 10),
+			gpu.GetMemory().GetUnit().String(),
+		))
+	}
+
+	storages := make([]string, 0, len(laptop.GetStorages()))
+	for _, storage := range laptop.GetStorages() {
+		storages = append(storages, joinCSVFields(
+			storage.GetDriver().String(),
+			strconv.FormatUint(storage.GetMemory().GetValue(), 10),
+			storage.GetMemory().GetUnit().String(),
+		))
+	}
+
+	weightKg, weightLb := "", ""
+	switch weight := laptop.GetWeight().(type) {
+	case *pb.Laptop_WeightKg:
+		weightKg = formatFloat(weight.WeightKg)
+	case *pb.Laptop_WeightLb:
+		weightLb = formatFloat(weight.WeightLb)
+	}
+
+	updatedAt := ""
+	if laptop.GetUpdatedAt() != nil {
+		updatedAt = laptop.GetUpdatedAt().AsTime().Format(time.RFC3339Nano)
+	}
+
+	priceUnits, priceNanos := "", ""
+	if laptop.GetPrice() != nil {
+		priceUnits = strconv.FormatInt(laptop.GetPrice().GetUnits(), 10)
+		priceNanos = strconv.FormatInt(int64(laptop.GetPrice().GetNanos()), 10)
+	}
+
+	return []string{
+		laptop.GetId(),
+		laptop.GetName(),
+		laptop.GetBrand(),
+		laptop.GetCpu().GetBrand(),
+		laptop.GetCpu().GetName(),
+		strconv.FormatUint(uint64(laptop.GetCpu().GetNumberCores()), 10),
+		strconv.FormatUint(uint64(laptop.GetCpu().GetNumberThreads()), 10),
+		formatFloat(laptop.GetCpu().GetMinGhz()),
+		formatFloat(laptop.GetCpu().GetMaxGhz()),
+		strconv.FormatUint(laptop.GetRam().GetValue(), 10),
+		laptop.GetRam().GetUnit().String(),
+		strings.Join(gpus, csvItemSeparator),
+		strings.Join(storages, csvItemSeparator),
+		strconv.FormatFloat(float64(laptop.GetScreen().GetSizeInch()), 'g', -1, 32),
+		strconv.FormatUint(uint64(laptop.GetScreen().GetResolution().GetWidth()), 10),
+		strconv.FormatUint(uint64(laptop.GetScreen().GetResolution().GetHeight()), 10),
+		laptop.GetScreen().GetPanel().String(),
+		strconv.FormatBool(laptop.GetScreen().GetMultitouch()),
+		laptop.GetKeyboard().GetLayout().String(),
+		strconv.FormatBool(laptop.GetKeyboard().GetBacklit()),
+		weightKg,
+		weightLb,
+		formatFloat(laptop.GetPriceUsd()),
+		strconv.FormatUint(uint64(laptop.GetReleaseYear()), 10),
+		updatedAt,
+		laptop.GetPrice().GetCurrencyCode(),
+		priceUnits,
+		priceNanos,
+	}
+}
+
+func csvRecordToLaptop(record []string, columns map[string]int) (*pb.Laptop, error) {
+	p := &csvParser{record: record, columns: columns}
+
+	laptop := &pb.Laptop{
+		Id:    p.text("id"),
+		Name:  p.text("name"),
+		Brand: p.text("brand"),
+		Cpu: &pb.CPU{
+			Brand:         p.text("cpu_brand"),
+			Name:          p.text("cpu_name"),
+			NumberCores:   p.uint32("cpu_number_cores"),
+			NumberThreads: p.uint32("cpu_number_threads"),
+			MinGhz:        p.float("cpu_min_ghz"),
+			MaxGhz:        p.float("cpu_max_ghz"),
+		},
+		Ram: &pb.Memory{
+			Value: p.uint64("ram_value"),
+			Unit:  pb.Memory_Unit(p.enum("ram_unit", pb.Memory_Unit_value)),
+		},
+		Screen: &pb.Screen{
+			SizeInch: float32(p.float("screen_size_inch")),
+			Resolution: &pb.Screen_Resolution{
+				Width:  p.uint32("screen_resolution_width"),
+				Height: p.uint32("screen_resolution_height"),
+			},
+			Panel:      pb.Screen_Panel(p.enum("screen_panel", pb.Screen_Panel_value)),
+			Multitouch: p.bool("screen_multitouch"),
+		},
+		Keyboard: &pb.Keyboard{
+			Layout:  pb.Keyboard_Layout(p.enum("keyboard_layout", pb.Keyboard_Layout_value)),
+			Backlit: p.bool("keyboard_backlit"),
+		},
+		PriceUsd:    p.float("price_usd"),
+		ReleaseYear: p.uint32("release_year"),
+	}
+
+	for _, item := range p.items("gpus", 6) {
+		laptop.Gpus = append(laptop.Gpus, &pb.GPU{
+			Brand:  item.text(0),
+			Name:   item.text(1),
+			MinGhz: item.float(2),
+			MaxGhz: item.float(3),
+			Memory: &pb.Memory{
+				Value: item.uint64(4),
+				Unit:  pb.Memory_Unit(item.enum(5, pb.Memory_Unit_value)),
+			},
+		})
+	}
+
+	for _, item := range p.items("storages", 3) {
+		laptop.Storages = append(laptop.Storages, &pb.Storage{
+			Driver: pb.Storage_Driver(item.enum(0, pb.Storage_Driver_value)),
+			Memory: &pb.Memory{
+				Value: item.uint64(1),
+				Unit:  pb.Memory_Unit(item.enum(2, pb.Memory_Unit_value)),
+			},
+		})
+	}
+
+	if p.text("weight_kg") != "" {
+		laptop.Weight = &pb.Laptop_WeightKg{WeightKg: p.float("weight_kg")}
+	} else if p.text("weight_lb") != "" {
+		laptop.Weight = &pb.Laptop_WeightLb{WeightLb: p.float("weight_lb")}
+	}
+
+	if currencyCode := p.text("price_currency_code"); currencyCode != "" {
+		laptop.Price = &pb.Money{
+			CurrencyCode: currencyCode,
+			Units:        p.int64("price_units"),
+			Nanos:        int32(p.int64("price_nanos")),
+		}
+	}
+
+	if updatedAt := p.text("updated_at"); updatedAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, updatedAt)
+		if err != nil {
+			p.fail("updated_at", err)
+		}
+		laptop.UpdatedAt = timestamppb.New(t)
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return laptop, nil
+}
+
+// csvParser converts the columns of a record, it keeps the first error so that the record is checked at once
+type csvParser struct {
+	record  []string
+	columns map[string]int
+	err     error
+}
+
+func (p *csvParser) fail(column string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("invalid value of column %s : %w", column, err)
+	}
+}
+
+func (p *csvParser) text(column string) string {
+	i, ok := p.columns[column]
+	if !ok || i >= len(p.record) {
+		return "" // a missing column is left unpopulated
+	}
+	return p.record[i]
+}
+
+func (p *csvParser) uint64(column string) uint64 {
+	value := p.text(column)
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return n
+}
+
+func (p *csvParser) uint32(column string) uint32 {
+	value := p.text(column)
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return uint32(n)
+}
+
+func (p *csvParser) int64(column string) int64 {
+	value := p.text(column)
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return n
+}
+
+func (p *csvParser) float(column string) float64 {
+	value := p.text(column)
+	if value == "" {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return f
+}
+
+func (p *csvParser) bool(column string) bool {
+	value := p.text(column)
+	if value == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.fail(column, err)
+	}
+	return b
+}
+
+func (p *csvParser) enum(column string, values map[string]int32) int32 {
+	return p.enumValue(column, p.text(column), values)
+}
+
+func (p *csvParser) enumValue(column string, value string, values map[string]int32) int32 {
+	if value == "" {
+		return 0
+	}
+
+	n, ok := values[strings.ToUpper(value)]
+	if !ok {
+		p.fail(column, fmt.Errorf("unknown enum value %q", value))
+	}
+	return n
+}
+
+// items splits a column holding repeated messages into their fields
+func (p *csvParser) items(column string, fields int) []*csvItem {
+	value := p.text(column)
+	if value == "" {
+		return nil
+	}
+
+	items := []*csvItem{}
+	for _, item := range splitEscaped(value, csvItemSeparator) {
+		values := splitEscaped(item, csvFieldSeparator)
+		if len(values) != fields {
+			p.fail(column, fmt.Errorf("expected %d fields in %q but got %d", fields, item, len(values)))
+			continue
+		}
+		for i := range values {
+			values[i] = unescapeCSVField(values[i])
+		}
+		items = append(items, &csvItem{parser: p, column: column, values: values})
+	}
+	return items
+}
+
+// joinCSVFields joins the fields of an item of a repeated column, escaping their separators
+func joinCSVFields(fields ...string) string {
+	for i, field := range fields {
+		fields[i] = csvEscaper.Replace(field)
+	}
+	return strings.Join(fields, csvFieldSeparator)
+}
+
+// splitEscaped splits the value at the separators which are not escaped, the escapes are kept
+func splitEscaped(value string, separator string) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], csvEscape):
+			i += len(csvEscape) // the escaped character is never a separator
+		case strings.HasPrefix(value[i:], separator):
+			parts = append(parts, value[start:i])
+			start = i + len(separator)
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unescapeCSVField(field string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(field); i++ {
+		if strings.HasPrefix(field[i:], csvEscape) && i+len(csvEscape) < len(field) {
+			i += len(csvEscape)
+		}
+		unescaped.WriteByte(field[i])
+	}
+	return unescaped.String()
+}
+
+type csvItem struct {
+	parser *csvParser
+	column string
+	values []string
+}
+
+func (item *csvItem) text(i int) string {
+	return item.values[i]
+}
+
+func (item *csvItem) uint64(i int) uint64 {
+	n, err := strconv.ParseUint(item.values[i], 10, 64)
+	if err != nil {
+		item.parser.fail(item.column, err)
+	}
+	return n
+}
+
+func (item *csvItem) float(i int) float64 {
+	f, err := strconv.ParseFloat(item.values[i], 64)
+	if err != nil {
+		item.parser.fail(item.column, err)
+	}
+	return f
+}
+
+func (item *csvItem) enum(i int, values map[string]int32) int32 {
+	return item.parser.enumValue(item.column, item.values[i], values)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -22,8 +22,8 @@ func WriteProtobufToBinaryFile(message proto.Message, filename string) error {
 	return nil
 }
 
-func WriteProtobufToJSONFile(message proto.Message, filename string) error {
-	data, err := ProtobufToJSON(message)
+func WriteProtobufToJSONFile(message proto.Message, filename string, options ...MarshalOption) error {
+	data, err := ProtobufToJSON(message, options...)
 
 	if err != nil {
 		return fmt.Errorf("cannot marshall proto to json file %w", err)
@@ -50,4 +50,49 @@ func ReadProtobufFromBinaryFile(filename string, message proto.Message) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func ReadProtobufFromJSONFile(filename string, message proto.Message) error {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return fmt.Errorf("cannot read json data from file %w", err)
+	}
+
+	err = JSONToProtobuf(data, message)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshall json data to proto %w", err)
+	}
+
+	return nil
+}
+
+func WriteProtobufToYAMLFile(message proto.Message, filename string, options ...MarshalOption) error {
+	data, err := ProtobufToYAML(message, options...)
+
+	if err != nil {
+		return fmt.Errorf("cannot marshall proto to yaml file %w", err)
+	}
+
+	err = ioutil.WriteFile(filename, []byte(data), 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write yaml data to file %w", err)
+	}
+
+	return nil
+}
+
+func ReadProtobufFromYAMLFile(filename string, message proto.Message) error {
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return fmt.Errorf("cannot read yaml data from file %w", err)
+	}
+
+	err = YAMLToProtobuf(data, message)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshall yaml data to proto %w", err)
+	}
+
+	return nil
+}
@@ -37,3 +37,75 @@ func TestWriteProtobufToBinaryFile(t *testing.T) {
 	err = serializer.WriteProtobufToJSONFile(laptop1, jsonFile)
 	require.NoError(t, err)
 }
+
+
+func TestReadProtobufFromJSONFile(t *testing.T) {
+	t.Parallel()
+
+	jsonFile := "../tmp/laptop_camel_case.json"
+
+	laptop1 := sample.NewLaptop()
+
+	err := serializer.WriteProtobufToJSONFile(laptop1, jsonFile, serializer.WithCamelCase(), serializer.WithEnumNumbers())
+	require.NoError(t, err)
+
+	laptop2 := &pb.Laptop{}
+	err = serializer.ReadProtobufFromJSONFile(jsonFile, laptop2)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(laptop1, laptop2))
+}
+
+func TestWriteProtobufToYAMLFile(t *testing.T) {
+	t.Parallel()
+
+	yamlFile := "../tmp/laptop.yaml"
+
+	laptop1 := sample.NewLaptop()
+
+	err := serializer.WriteProtobufToYAMLFile(laptop1, yamlFile, serializer.WithoutUnpopulated())
+	require.NoError(t, err)
+
+	laptop2 := &pb.Laptop{}
+	err = serializer.ReadProtobufFromYAMLFile(yamlFile, laptop2)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(laptop1, laptop2))
+}
+
+func TestYAMLToProtobuf(t *testing.T) {
+	t.Parallel()
+
+	// a hand-authored fixture, the names and enums may use either convention
+	data := `
+name: Thinkpad X1
+brand: Lenovo
+cpu:
+  brand: Intel
+  numberCores: 8
+  number_threads: 16
+ram:
+  value: 16
+  unit: GIGABYTE
+storages:
+  - driver: SSD
+    memory: {value: 512, unit: 5}
+weight_kg: 1.2
+price_usd: 1999.99
+`
+
+	laptop := &pb.Laptop{}
+	err := serializer.YAMLToProtobuf([]byte(data), laptop)
+	require.NoError(t, err)
+	require.Equal(t, "Thinkpad X1", laptop.GetName())
+	require.EqualValues(t, 8, laptop.GetCpu().GetNumberCores())
+	require.EqualValues(t, 16, laptop.GetCpu().GetNumberThreads())
+	require.Equal(t, pb.Memory_GIGABYTE, laptop.GetStorages()[0].GetMemory().GetUnit())
+	require.Equal(t, 1.2, laptop.GetWeightKg())
+
+	yaml, err := serializer.ProtobufToYAML(laptop, serializer.WithoutUnpopulated())
+	require.NoError(t, err)
+	require.Contains(t, yaml, "unit: GIGABYTE")
+	require.Contains(t, yaml, "number_cores: 8")
+
+	err = serializer.YAMLToProtobuf([]byte("name: [unclosed"), laptop)
+	require.Error(t, err)
+}
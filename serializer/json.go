@@ -3,17 +3,91 @@ package serializer
 import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/yaml"
 )
 
-func ProtobufToJSON(message proto.Message) (string, error) {
-	marshaler := protojson.MarshalOptions{
+// JSONMarshalOptions returns the protojson conventions used for every JSON representation of the messages
+func JSONMarshalOptions() protojson.MarshalOptions {
+	return protojson.MarshalOptions{
 		UseEnumNumbers: false,
 		EmitUnpopulated: true,
 		Indent: "	",
 		UseProtoNames: true,
 	}
+}
+
+// MarshalOption changes the default JSON and YAML representation of the messages
+type MarshalOption func(*protojson.MarshalOptions)
+
+// WithEnumNumbers writes the enum values as numbers instead of names
+func WithEnumNumbers() MarshalOption {
+	return func(options *protojson.MarshalOptions) {
+		options.UseEnumNumbers = true
+	}
+}
+
+// WithCamelCase writes the lowerCamelCase JSON names of the fields instead of their proto names
+func WithCamelCase() MarshalOption {
+	return func(options *protojson.MarshalOptions) {
+		options.UseProtoNames = false
+	}
+}
+
+// WithoutUnpopulated leaves out the fields having their zero value
+func WithoutUnpopulated() MarshalOption {
+	return func(options *protojson.MarshalOptions) {
+		options.EmitUnpopulated = false
+	}
+}
+
+// WithIndent changes the indentation of the JSON, an empty indent writes the message in a single line
+func WithIndent(indent string) MarshalOption {
+	return func(options *protojson.MarshalOptions) {
+		options.Indent = indent
+	}
+}
+
+func jsonMarshaler(options []MarshalOption) protojson.MarshalOptions {
+	marshaler := JSONMarshalOptions()
+	for _, option := range options {
+		option(&marshaler)
+	}
+	return marshaler
+}
+
+func ProtobufToJSON(message proto.Message, options ...MarshalOption) (string, error) {
+	marshaler := jsonMarshaler(options)
 
 	data, err := marshaler.Marshal(message)
 
 	return string(data), err
-}
\ No newline at end of file
+}
+
+// JSONToProtobuf accepts both the proto and the camelCase names of the fields, and the enum names or numbers
+func JSONToProtobuf(data []byte, message proto.Message) error {
+	return protojson.Unmarshal(data, message)
+}
+
+// ProtobufToYAML converts the message to JSON first, so the YAML follows the protojson conventions
+func ProtobufToYAML(message proto.Message, options ...MarshalOption) (string, error) {
+	marshaler := jsonMarshaler(options)
+	marshaler.Indent = ""
+
+	data, err := marshaler.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+
+	data, err = yaml.JSONToYAML(data)
+
+	return string(data), err
+}
+
+func YAMLToProtobuf(data []byte, message proto.Message) error {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+
+	return protojson.Unmarshal(data, message)
+}
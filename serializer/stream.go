@@ -0,0 +1,244 @@
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+)
+
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZstd
+)
+
+// StreamOptions configures how a message stream is written, the reader finds them in the stream header
+type StreamOptions struct {
+	Compression Compression
+	Checksum    bool // append the CRC-32C of every record
+}
+
+// the stream header is the magic followed by the compression and the flags,
+// then come the records: the varint length of the message, the message and its optional checksum
+var streamMagic = []byte("PCBS")
+
+const (
+	streamVersion      = 1
+	flagChecksum  byte = 1 << 0
+
+	checksumSize         = 4
+	DefaultMaxRecordSize = 64 << 20
+)
+
+var ErrInvalidStream = errors.New("not a message stream")
+var ErrChecksumMismatch = errors.New("record checksum mismatch")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// MessageWriter writes varint length-delimited messages, Close must be called to flush the stream
+type MessageWriter struct {
+	options    StreamOptions
+	writer     *bufio.Writer
+	compressor io.WriteCloser // nil without compression
+	buffer     []byte
+}
+
+func NewMessageWriter(w io.Writer, options StreamOptions) (*MessageWriter, error) {
+	flags := byte(0)
+	if options.Checksum {
+		flags |= flagChecksum
+	}
+
+	header := append(append([]byte{}, streamMagic...), streamVersion, byte(options.Compression), flags)
+	_, err := w.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write stream header %w", err)
+	}
+
+	writer := &MessageWriter{options: options}
+
+	switch options.Compression {
+	case CompressionNone:
+		writer.writer = bufio.NewWriter(w)
+	case CompressionGzip:
+		writer.compressor = gzip.NewWriter(w)
+		writer.writer = bufio.NewWriter(writer.compressor)
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create zstd encoder %w", err)
+		}
+		writer.compressor = encoder
+		writer.writer = bufio.NewWriter(encoder)
+	default:
+		return nil, fmt.Errorf("unknown compression %d", options.Compression)
+	}
+
+	return writer, nil
+}
+
+func (w *MessageWriter) Write(message proto.Message) error {
+	data, err := proto.MarshalOptions{}.MarshalAppend(w.buffer[:0], message)
+	if err != nil {
+		return fmt.Errorf("cannot marshall proto to binary %w", err)
+	}
+	w.buffer = data
+
+	var record [binary.MaxVarintLen64 + checksumSize]byte
+	n := binary.PutUvarint(record[:], uint64(len(data)))
+	_, err = w.writer.Write(record[:n])
+	if err != nil {
+		return err
+	}
+
+	_, err = w.writer.Write(data)
+	if err != nil {
+		return err
+	}
+
+	if w.options.Checksum {
+		binary.LittleEndian.PutUint32(record[:checksumSize], crc32.Checksum(data, castagnoli))
+		_, err = w.writer.Write(record[:checksumSize])
+	}
+	return err
+}
+
+// Close flushes the records and the compressor, it doesn't close the underlying writer
+func (w *MessageWriter) Close() error {
+	err := w.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+	return nil
+}
+
+// MessageReader reads the messages written by a MessageWriter
+type MessageReader struct {
+	// MaxRecordSize protects from allocating a huge buffer for a corrupted length
+	MaxRecordSize int
+
+	options StreamOptions
+	reader  *bufio.Reader
+	closer  func()
+	buffer  []byte
+	records int
+}
+
+func NewMessageReader(r io.Reader) (*MessageReader, error) {
+	header := make([]byte, len(streamMagic)+3)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read stream header %w", err)
+	}
+
+	if !bytes.Equal(header[:len(streamMagic)], streamMagic) || header[len(streamMagic)] != streamVersion {
+		return nil, ErrInvalidStream
+	}
+
+	reader := &MessageReader{
+		MaxRecordSize: DefaultMaxRecordSize,
+		options: StreamOptions{
+			Compression: Compression(header[len(streamMagic)+1]),
+			Checksum:    header[len(streamMagic)+2]&flagChecksum != 0,
+		},
+		closer: func() {},
+	}
+
+	switch reader.options.Compression {
+	case CompressionNone:
+		reader.reader = bufio.NewReader(r)
+	case CompressionGzip:
+		decompressor, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create gzip reader %w", err)
+		}
+		reader.reader = bufio.NewReader(decompressor)
+		reader.closer = func() { decompressor.Close() }
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create zstd decoder %w", err)
+		}
+		reader.reader = bufio.NewReader(decoder)
+		reader.closer = decoder.Close
+	default:
+		return nil, fmt.Errorf("unknown compression %d", reader.options.Compression)
+	}
+
+	return reader, nil
+}
+
+// Options returns the options the stream was written with
+func (r *MessageReader) Options() StreamOptions {
+	return r.options
+}
+
+// Read unmarshals the next record into the message, it returns io.EOF once every record is read
+// and ErrChecksumMismatch if the record is corrupted
+func (r *MessageReader) Read(message proto.Message) error {
+	size, err := binary.ReadUvarint(r.reader)
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read length of record %d %w", r.records, err)
+	}
+
+	if size > uint64(r.MaxRecordSize) {
+		return fmt.Errorf("record %d is %d bytes, more than the maximum of %d bytes", r.records, size, r.MaxRecordSize)
+	}
+
+	recordSize := int(size)
+	if r.options.Checksum {
+		recordSize += checksumSize
+	}
+	if cap(r.buffer) < recordSize {
+		r.buffer = make([]byte, recordSize)
+	}
+	record := r.buffer[:recordSize]
+
+	_, err = io.ReadFull(r.reader, record)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("cannot read record %d %w", r.records, err)
+	}
+
+	index := r.records
+	r.records++
+
+	// a corrupted record is skipped, the next Read returns the following record
+	data := record[:size]
+	if r.options.Checksum {
+		checksum := binary.LittleEndian.Uint32(record[size:])
+		if crc32.Checksum(data, castagnoli) != checksum {
+			return fmt.Errorf("cannot read record %d %w", index, ErrChecksumMismatch)
+		}
+	}
+
+	err = proto.Unmarshal(data, message)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshall binary data to proto %w", err)
+	}
+	return nil
+}
+
+// Close releases the decompressor, it doesn't close the underlying reader
+func (r *MessageReader) Close() {
+	r.closer()
+}
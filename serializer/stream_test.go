@@ -0,0 +1,96 @@
+package serializer_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMessageStream(t *testing.T) {
+	t.Parallel()
+
+	laptops := []*pb.Laptop{}
+	for i := 0; i < 100; i++ {
+		laptops = append(laptops, sample.NewLaptop())
+	}
+
+	testCases := []struct {
+		name    string
+		options serializer.StreamOptions
+	}{
+		{"plain", serializer.StreamOptions{}},
+		{"checksum", serializer.StreamOptions{Checksum: true}},
+		{"gzip", serializer.StreamOptions{Compression: serializer.CompressionGzip, Checksum: true}},
+		{"zstd", serializer.StreamOptions{Compression: serializer.CompressionZstd}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buffer bytes.Buffer
+			writer, err := serializer.NewMessageWriter(&buffer, tc.options)
+			require.NoError(t, err)
+
+			for _, laptop := range laptops {
+				err := writer.Write(laptop)
+				require.NoError(t, err)
+			}
+			require.NoError(t, writer.Close())
+
+			reader, err := serializer.NewMessageReader(&buffer)
+			require.NoError(t, err)
+			defer reader.Close()
+			require.Equal(t, tc.options, reader.Options())
+
+			for _, laptop := range laptops {
+				other := &pb.Laptop{}
+				err := reader.Read(other)
+				require.NoError(t, err)
+				require.True(t, proto.Equal(laptop, other))
+			}
+
+			err = reader.Read(&pb.Laptop{})
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestMessageStreamCorruptedRecord(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	writer, err := serializer.NewMessageWriter(&buffer, serializer.StreamOptions{Checksum: true})
+	require.NoError(t, err)
+
+	first, second := sample.NewLaptop(), sample.NewLaptop()
+	require.NoError(t, writer.Write(first))
+	require.NoError(t, writer.Write(second))
+	require.NoError(t, writer.Close())
+
+	// flip a byte of the first message, right after the header and its one byte length
+	data := buffer.Bytes()
+	data[len("PCBS")+3+1+10] ^= 0xff
+
+	reader, err := serializer.NewMessageReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	err = reader.Read(&pb.Laptop{})
+	require.ErrorIs(t, err, serializer.ErrChecksumMismatch)
+
+	// the following records are still readable
+	other := &pb.Laptop{}
+	err = reader.Read(other)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(second, other))
+
+	_, err = serializer.NewMessageReader(bytes.NewReader([]byte("not a stream")))
+	require.ErrorIs(t, err, serializer.ErrInvalidStream)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StoreStats describes how many records a store holds and their size in bytes
+type StoreStats struct {
+	Count uint64
+	Bytes uint64
+}
+
+// StatsReporter is implemented by stores that can report statistics about their content
+type StatsReporter interface {
+	Stats() (StoreStats, error)
+}
+
+type CompactResult struct {
+	Removed        uint64
+	ReclaimedBytes uint64
+}
+
+// Compactor is implemented by stores that can release storage which is no longer used
+type Compactor interface {
+	Compact() (CompactResult, error)
+}
+
+// AdminServer lets operators inspect and maintain a running server
+type AdminServer struct {
+	pb.UnimplementedAdminServiceServer
+	LaptopStore LaptopStore
+	ImageStore  ImageStore
+}
+
+func NewAdminServer(laptopStore LaptopStore, imageStore ImageStore) *AdminServer {
+	return &AdminServer{LaptopStore: laptopStore, ImageStore: imageStore}
+}
+
+func (server *AdminServer) GetStoreStats(ctx context.Context, req *pb.GetStoreStatsRequest) (*pb.GetStoreStatsResponse, error) {
+	laptopStats, err := storeStats(server.LaptopStore)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot get laptop store stats : %v", err))
+	}
+
+	imageStats, err := storeStats(server.ImageStore)
+	if err != nil {
+		return nil, logError(status.Errorf(codes.Internal, "cannot get image store stats : %v", err))
+	}
+
+	res := &pb.GetStoreStatsResponse{
+		Laptops: laptopStats,
+		Images:  imageStats,
+	}
+
+	if reporter, ok := server.LaptopStore.(CacheStatsReporter); ok {
+		cacheStats := reporter.CacheStats()
+		res.LaptopCache = &pb.CacheStats{
+			Hits:      cacheStats.Hits,
+			Misses:    cacheStats.Misses,
+			Evictions: cacheStats.Evictions,
+			Entries:   cacheStats.Entries,
+			Bytes:     cacheStats.Bytes,
+		}
+	}
+
+	return res, nil
+}
+
+func (server *AdminServer) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	level := req.GetLevel()
+	if level == pb.LogLevel_UNKNOWN || pb.LogLevel_name[int32(level)] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid log level : %v", level)
+	}
+
+	previousLevel := SetLogLevel(level)
+	logf(pb.LogLevel_INFO, "log level changed from %v to %v", previousLevel, level)
+
+	res := &pb.SetLogLevelResponse{
+		PreviousLevel: previousLevel,
+		Level:         level,
+	}
+
+	return res, nil
+}
+
+func (server *AdminServer) CompactStore(ctx context.Context, req *pb.CompactStoreRequest) (*pb.CompactStoreResponse, error) {
+	res := &pb.CompactStoreResponse{}
+
+	for _, store := range []interface{}{server.LaptopStore, server.ImageStore} {
+		compactor, ok := store.(Compactor)
+		if !ok {
+			continue
+		}
+
+		result, err := compactor.Compact()
+		if err != nil {
+			return nil, logError(status.Errorf(codes.Internal, "cannot compact store : %v", err))
+		}
+
+		res.Removed += result.Removed
+		res.ReclaimedBytes += result.ReclaimedBytes
+	}
+
+	logf(pb.LogLevel_INFO, "compacted stores, removed %d records and reclaimed %d bytes", res.Removed, res.ReclaimedBytes)
+	return res, nil
+}
+
+// storeStats returns nil stats for the stores that can't report them
+func storeStats(store interface{}) (*pb.StoreStats, error) {
+	reporter, ok := store.(StatsReporter)
+	if !ok {
+		return nil, nil
+	}
+
+	stats, err := reporter.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.StoreStats{Count: stats.Count, Bytes: stats.Bytes}, nil
+}
@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAdminGetStoreStats(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptopBytes := 0
+	for i := 0; i < 3; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+		laptopBytes += proto.Size(laptop)
+	}
+
+	_, err := imageStore.Save("laptop-id", ".png", *bytes.NewBuffer(make([]byte, 100)))
+	require.NoError(t, err)
+	_, err = imageStore.Save("laptop-id", ".png", *bytes.NewBuffer(make([]byte, 50)))
+	require.NoError(t, err)
+
+	server := service.NewAdminServer(laptopStore, imageStore)
+	res, err := server.GetStoreStats(context.Background(), &pb.GetStoreStatsRequest{})
+	require.NoError(t, err)
+
+	require.EqualValues(t, 3, res.GetLaptops().GetCount())
+	require.EqualValues(t, laptopBytes, res.GetLaptops().GetBytes())
+	require.EqualValues(t, 2, res.GetImages().GetCount())
+	require.EqualValues(t, 150, res.GetImages().GetBytes())
+
+	// a server without image store doesn't report image stats
+	server = service.NewAdminServer(laptopStore, nil)
+	res, err = server.GetStoreStats(context.Background(), &pb.GetStoreStatsRequest{})
+	require.NoError(t, err)
+	require.Nil(t, res.GetImages())
+}
+
+func TestAdminSetLogLevel(t *testing.T) {
+	server := service.NewAdminServer(service.NewInMemoryLaptopStore(), nil)
+	initialLevel := service.CurrentLogLevel()
+	defer service.SetLogLevel(initialLevel)
+
+	res, err := server.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: pb.LogLevel_DEBUG})
+	require.NoError(t, err)
+	require.Equal(t, initialLevel, res.GetPreviousLevel())
+	require.Equal(t, pb.LogLevel_DEBUG, service.CurrentLogLevel())
+
+	for _, level := range []pb.LogLevel{pb.LogLevel_UNKNOWN, pb.LogLevel(42)} {
+		_, err = server.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: level})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	require.Equal(t, pb.LogLevel_DEBUG, service.CurrentLogLevel())
+}
+
+func TestAdminCompactStore(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	imageId, err := imageStore.Save("laptop-id", ".png", *bytes.NewBuffer(make([]byte, 10)))
+	require.NoError(t, err)
+
+	orphanPath := filepath.Join(imageFolder, "orphan.png")
+	require.NoError(t, os.WriteFile(orphanPath, make([]byte, 20), 0644))
+	gitignorePath := filepath.Join(imageFolder, ".gitignore")
+	require.NoError(t, os.WriteFile(gitignorePath, []byte("*"), 0644))
+
+	server := service.NewAdminServer(service.NewInMemoryLaptopStore(), imageStore)
+	res, err := server.CompactStore(context.Background(), &pb.CompactStoreRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, res.GetRemoved())
+	require.EqualValues(t, 20, res.GetReclaimedBytes())
+
+	require.NoFileExists(t, orphanPath)
+	require.FileExists(t, gitignorePath)
+	require.FileExists(t, filepath.Join(imageFolder, imageId+".png"))
+}
+
+func TestAdminCompactStoreDuringSaves(t *testing.T) {
+	t.Parallel()
+
+	imageStore := service.NewDiskImageStore(t.TempDir())
+	server := service.NewAdminServer(service.NewInMemoryLaptopStore(), imageStore)
+
+	// the images being saved must not be taken for orphans
+	saved := make(chan error)
+	imageIds := make(chan string, 50)
+	go func() {
+		for i := 0; i < cap(imageIds); i++ {
+			imageId, err := imageStore.Save("laptop-id", ".png", *bytes.NewBuffer(make([]byte, 1000)))
+			if err != nil {
+				saved <- err
+				return
+			}
+			imageIds <- imageId
+		}
+		close(imageIds)
+		saved <- nil
+	}()
+
+	for compacting := true; compacting; {
+		select {
+		case err := <-saved:
+			require.NoError(t, err)
+			compacting = false
+		default:
+			res, err := server.CompactStore(context.Background(), &pb.CompactStoreRequest{})
+			require.NoError(t, err)
+			require.Zero(t, res.GetRemoved())
+		}
+	}
+
+	for imageId := range imageIds {
+		image, data, err := imageStore.Open(imageId)
+		require.NoError(t, err)
+		require.NoError(t, data.Close())
+		require.FileExists(t, image.Path)
+	}
+}
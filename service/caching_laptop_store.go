@@ -0,0 +1,389 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// CacheOptions bounds what a CachingLaptopStore keeps, a zero bound is no bound
+type CacheOptions struct {
+	MaxEntries int
+	MaxBytes   uint64        // of the encoded laptops
+	TTL        time.Duration // how long an entry is served before the store is read again
+}
+
+// CacheStats counts the lookups served by a cache, and what it holds
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64 // the entries removed to stay within the bounds and the expired ones
+	Entries   uint64
+	Bytes     uint64
+}
+
+// CacheStatsReporter is implemented by stores that cache laptops
+type CacheStatsReporter interface {
+	CacheStats() CacheStats
+}
+
+// CachingLaptopStore keeps the laptops found by ID, and the laptops found by the searches, in a least recently
+// used cache in front of the wrapped store. The writes go to the wrapped store and invalidate the laptop
+// and every cached search, so the wrapped store must only be written through the cache.
+// The concurrent misses of a laptop are coalesced into a single read of the wrapped store.
+// The searches depending on the exchange rates may be served with the previous rates until the TTL
+type CachingLaptopStore struct {
+	LaptopStore
+	Options CacheOptions
+
+	mutex      sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List // the most recently used entries first
+	searches   map[string]bool
+	loads      map[string]*cacheLoad
+	generation uint64 // incremented by every write, what was read before a write is not cached
+	stats      CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	laptops []*pb.Laptop // the laptop found by ID, none if it doesn't exist, or the laptops found by a search
+	size    uint64
+	expires time.Time // zero if the entry never expires
+}
+
+// cacheLoad is a read of the wrapped store shared by the concurrent misses of a laptop
+type cacheLoad struct {
+	done   chan struct{}
+	laptop *pb.Laptop
+	err    error
+}
+
+func NewCachingLaptopStore(store LaptopStore, options CacheOptions) *CachingLaptopStore {
+	return &CachingLaptopStore{
+		LaptopStore: store,
+		Options:     options,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		searches:    make(map[string]bool),
+		loads:       make(map[string]*cacheLoad),
+	}
+}
+
+func laptopCacheKey(laptopId string) string {
+	return "laptop/" + laptopId
+}
+
+func searchCacheKey(filter *pb.Filter, options SearchOptions) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("search/%d/%d/%s", options.Order, options.Limit, data), nil
+}
+
+func (store *CachingLaptopStore) Save(laptop *pb.Laptop) error {
+	err := store.LaptopStore.Save(laptop)
+	store.invalidate(laptop.GetId())
+	return err
+}
+
+func (store *CachingLaptopStore) Update(laptop *pb.Laptop) error {
+	err := store.LaptopStore.Update(laptop)
+	store.invalidate(laptop.GetId())
+	return err
+}
+
+func (store *CachingLaptopStore) Delete(laptopId string) error {
+	err := store.LaptopStore.Delete(laptopId)
+	store.invalidate(laptopId)
+	return err
+}
+
+func (store *CachingLaptopStore) FindById(laptopId string) (*pb.Laptop, error) {
+	key := laptopCacheKey(laptopId)
+
+	store.mutex.Lock()
+	if laptops, ok := store.get(key); ok {
+		store.mutex.Unlock()
+		if len(laptops) == 0 {
+			return nil, nil
+		}
+		return deepCopy(laptops[0])
+	}
+	store.stats.Misses++
+
+	load, loading := store.loads[key]
+	if loading {
+		store.mutex.Unlock()
+		<-load.done
+	} else {
+		load = &cacheLoad{done: make(chan struct{})}
+		store.loads[key] = load
+		generation := store.generation
+		store.mutex.Unlock()
+
+		load.laptop, load.err = store.LaptopStore.FindById(laptopId)
+
+		store.mutex.Lock()
+		if store.loads[key] == load {
+			delete(store.loads, key)
+		}
+		if load.err == nil && generation == store.generation {
+			laptops := []*pb.Laptop{}
+			if load.laptop != nil {
+				laptops = append(laptops, load.laptop)
+			}
+			store.put(key, laptops)
+		}
+		store.mutex.Unlock()
+		close(load.done)
+	}
+
+	if load.err != nil || load.laptop == nil {
+		return nil, load.err
+	}
+	return deepCopy(load.laptop)
+}
+
+// Search serves the laptops of a previous search with the same filter, or searches the wrapped store.
+// Only the searches going through every laptop are cached, not the ones stopped by found or by the context
+func (store *CachingLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+	return store.search(ctx, filter, SearchOptions{}, store.LaptopStore.Search, found)
+}
+
+// SearchSorted caches the sorted searches like the other ones, the order and the limit are part of the key
+func (store *CachingLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	searcher, ok := store.LaptopStore.(SortedLaptopSearcher)
+	if !ok {
+		return ErrSortedSearchNotSupported
+	}
+
+	return store.search(ctx, filter, options, func(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+		return searcher.SearchSorted(ctx, filter, options, found)
+	}, found)
+}
+
+func (store *CachingLaptopStore) search(ctx context.Context, filter *pb.Filter, options SearchOptions,
+	search func(context.Context, *pb.Filter, func(*pb.Laptop) error) error, found func(*pb.Laptop) error) error {
+	key, err := searchCacheKey(filter, options)
+	if err != nil {
+		return search(ctx, filter, found)
+	}
+
+	store.mutex.Lock()
+	laptops, ok := store.get(key)
+	if !ok {
+		store.stats.Misses++
+	}
+	generation := store.generation
+	store.mutex.Unlock()
+
+	if ok {
+		for _, laptop := range laptops {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			other, err := deepCopy(laptop)
+			if err != nil {
+				return err
+			}
+			if err := found(other); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// the results too large to be cached are not kept
+	cacheable := true
+	size := uint64(0)
+	err = search(ctx, filter, func(laptop *pb.Laptop) error {
+		if cacheable {
+			size += uint64(proto.Size(laptop))
+			cacheable = store.Options.MaxBytes == 0 || size <= store.Options.MaxBytes
+
+			other, err := deepCopy(laptop)
+			if err != nil {
+				return err
+			}
+			laptops = append(laptops, other)
+		}
+		return found(laptop)
+	})
+	if err != nil || !cacheable {
+		return err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if generation == store.generation {
+		if laptops == nil {
+			laptops = []*pb.Laptop{}
+		}
+		if store.put(key, laptops) {
+			store.searches[key] = true
+		}
+	}
+	return nil
+}
+
+// Begin starts a transaction on the wrapped store, its laptops are invalidated once committed
+func (store *CachingLaptopStore) Begin() (LaptopTx, error) {
+	transactional, ok := store.LaptopStore.(TransactionalLaptopStore)
+	if !ok {
+		return nil, ErrTxNotSupported
+	}
+
+	tx, err := transactional.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachingLaptopTx{LaptopTx: tx, store: store}, nil
+}
+
+type cachingLaptopTx struct {
+	LaptopTx
+	store *CachingLaptopStore
+	ids   []string
+}
+
+func (tx *cachingLaptopTx) Save(laptop *pb.Laptop) error {
+	err := tx.LaptopTx.Save(laptop)
+	if err != nil {
+		return err
+	}
+
+	tx.ids = append(tx.ids, laptop.GetId())
+	return nil
+}
+
+func (tx *cachingLaptopTx) Commit() error {
+	err := tx.LaptopTx.Commit()
+	tx.store.invalidate(tx.ids...)
+	return err
+}
+
+// List goes through the laptops of the wrapped store, they are not cached
+func (store *CachingLaptopStore) List(found func(*pb.Laptop) error) error {
+	lister, ok := store.LaptopStore.(LaptopLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	return lister.List(found)
+}
+
+// Ready returns the readiness of the wrapped store
+func (store *CachingLaptopStore) Ready() error {
+	if checker, ok := store.LaptopStore.(ReadinessChecker); ok {
+		return checker.Ready()
+	}
+	return nil
+}
+
+// Stats returns the statistics of the wrapped store
+func (store *CachingLaptopStore) Stats() (StoreStats, error) {
+	reporter, ok := store.LaptopStore.(StatsReporter)
+	if !ok {
+		return StoreStats{}, nil
+	}
+	return reporter.Stats()
+}
+
+func (store *CachingLaptopStore) CacheStats() CacheStats {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.stats
+}
+
+// invalidate removes the laptops and every search, and the reads in progress are no longer cached
+func (store *CachingLaptopStore) invalidate(laptopIds ...string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.generation++
+	for _, laptopId := range laptopIds {
+		key := laptopCacheKey(laptopId)
+		store.remove(key)
+		delete(store.loads, key) // the next misses read the store again instead of waiting for a stale read
+	}
+	for key := range store.searches {
+		store.remove(key)
+	}
+}
+
+// get returns the laptops of the entry and makes it the most recently used, the mutex must be held
+func (store *CachingLaptopStore) get(key string) ([]*pb.Laptop, bool) {
+	element, ok := store.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		store.remove(key)
+		store.stats.Evictions++
+		return nil, false
+	}
+
+	store.lru.MoveToFront(element)
+	store.stats.Hits++
+	return entry.laptops, true
+}
+
+// put adds the entry and evicts the least recently used ones beyond the bounds, the mutex must be held.
+// It returns false if the entry alone is larger than the bounds
+func (store *CachingLaptopStore) put(key string, laptops []*pb.Laptop) bool {
+	entry := &cacheEntry{key: key, laptops: laptops}
+	for _, laptop := range laptops {
+		entry.size += uint64(proto.Size(laptop))
+	}
+	if store.Options.MaxBytes > 0 && entry.size > store.Options.MaxBytes {
+		return false
+	}
+	if store.Options.TTL > 0 {
+		entry.expires = time.Now().Add(store.Options.TTL)
+	}
+
+	store.remove(key)
+	store.entries[key] = store.lru.PushFront(entry)
+	store.stats.Entries++
+	store.stats.Bytes += entry.size
+
+	for store.overBounds() {
+		oldest := store.lru.Back().Value.(*cacheEntry)
+		store.remove(oldest.key)
+		store.stats.Evictions++
+	}
+	return true
+}
+
+func (store *CachingLaptopStore) overBounds() bool {
+	if store.Options.MaxEntries > 0 && store.stats.Entries > uint64(store.Options.MaxEntries) {
+		return true
+	}
+	return store.Options.MaxBytes > 0 && store.stats.Bytes > store.Options.MaxBytes
+}
+
+func (store *CachingLaptopStore) remove(key string) {
+	element, ok := store.entries[key]
+	if !ok {
+		return
+	}
+
+	entry := element.Value.(*cacheEntry)
+	store.lru.Remove(element)
+	delete(store.entries, key)
+	delete(store.searches, key)
+	store.stats.Entries--
+	store.stats.Bytes -= entry.size
+}
@@ -0,0 +1,273 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCachingLaptopStoreFindById(t *testing.T) {
+	t.Parallel()
+
+	counting := newCountingLaptopStore()
+	store := service.NewCachingLaptopStore(counting, service.CacheOptions{})
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	for i := 0; i < 3; i++ {
+		found, err := store.FindById(laptop.GetId())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(laptop, found))
+
+		// the cached laptop cannot be changed by the caller
+		found.Name = "Changed"
+	}
+	require.EqualValues(t, 1, atomic.LoadInt32(&counting.finds))
+
+	// the laptops which don't exist are cached too
+	unknownId := sample.NewLaptop().GetId()
+	for i := 0; i < 2; i++ {
+		found, err := store.FindById(unknownId)
+		require.NoError(t, err)
+		require.Nil(t, found)
+	}
+	require.EqualValues(t, 2, atomic.LoadInt32(&counting.finds))
+
+	// the writes invalidate the laptop
+	laptop.Name = "Updated"
+	require.NoError(t, store.Update(laptop))
+	found, err := store.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, "Updated", found.GetName())
+
+	require.NoError(t, store.Delete(laptop.GetId()))
+	found, err = store.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+	require.EqualValues(t, 4, atomic.LoadInt32(&counting.finds))
+
+	stats := store.CacheStats()
+	require.EqualValues(t, 3, stats.Hits)
+	require.EqualValues(t, 4, stats.Misses)
+	require.EqualValues(t, 2, stats.Entries)
+
+	// the admin service reports them
+	res, err := service.NewAdminServer(store, nil).GetStoreStats(context.Background(), &pb.GetStoreStatsRequest{})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, res.GetLaptopCache().GetHits())
+	require.EqualValues(t, 4, res.GetLaptopCache().GetMisses())
+}
+
+func TestCachingLaptopStoreBounds(t *testing.T) {
+	t.Parallel()
+
+	laptops := []*pb.Laptop{}
+	local := service.NewInMemoryLaptopStore()
+	for i := 0; i < 3; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, local.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+
+	requireCached := func(store *service.CachingLaptopStore, laptop *pb.Laptop, cached bool) {
+		hits := store.CacheStats().Hits
+		_, err := store.FindById(laptop.GetId())
+		require.NoError(t, err)
+		require.Equal(t, cached, store.CacheStats().Hits > hits)
+	}
+
+	// the least recently used laptop is evicted
+	store := service.NewCachingLaptopStore(local, service.CacheOptions{MaxEntries: 2})
+	requireCached(store, laptops[0], false)
+	requireCached(store, laptops[1], false)
+	requireCached(store, laptops[0], true)
+	requireCached(store, laptops[2], false)
+	requireCached(store, laptops[0], true)
+	requireCached(store, laptops[1], false)
+	require.EqualValues(t, 2, store.CacheStats().Entries)
+	require.EqualValues(t, 2, store.CacheStats().Evictions)
+
+	maxBytes := proto.Size(laptops[0]) + proto.Size(laptops[1])
+	store = service.NewCachingLaptopStore(local, service.CacheOptions{MaxBytes: uint64(maxBytes)})
+	requireCached(store, laptops[0], false)
+	requireCached(store, laptops[1], false)
+	require.EqualValues(t, maxBytes, store.CacheStats().Bytes)
+	requireCached(store, laptops[2], false)
+	require.LessOrEqual(t, store.CacheStats().Bytes, uint64(maxBytes))
+	requireCached(store, laptops[0], false)
+
+	store = service.NewCachingLaptopStore(local, service.CacheOptions{TTL: 20 * time.Millisecond})
+	requireCached(store, laptops[0], false)
+	requireCached(store, laptops[0], true)
+	time.Sleep(40 * time.Millisecond)
+	requireCached(store, laptops[0], false)
+	require.EqualValues(t, 1, store.CacheStats().Evictions)
+}
+
+func TestCachingLaptopStoreCoalescesMisses(t *testing.T) {
+	t.Parallel()
+
+	counting := newCountingLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, counting.Save(laptop))
+	counting.release = make(chan struct{})
+
+	store := service.NewCachingLaptopStore(counting, service.CacheOptions{})
+
+	type result struct {
+		laptop *pb.Laptop
+		err    error
+	}
+
+	// the readers send back what they found, it is checked once the miss is released
+	const readers = 10
+	results := make(chan result, readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			found, err := store.FindById(laptop.GetId())
+			results <- result{found, err}
+		}()
+	}
+
+	require.Eventually(t, func() bool { return store.CacheStats().Misses == readers }, 5*time.Second, time.Millisecond)
+	close(counting.release)
+
+	for i := 0; i < readers; i++ {
+		result := <-results
+		require.NoError(t, result.err)
+		require.True(t, proto.Equal(laptop, result.laptop))
+	}
+	require.EqualValues(t, 1, atomic.LoadInt32(&counting.finds))
+}
+
+func TestCachingLaptopStoreWriteDuringMiss(t *testing.T) {
+	t.Parallel()
+
+	counting := newCountingLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, counting.Save(laptop))
+	counting.release = make(chan struct{})
+
+	store := service.NewCachingLaptopStore(counting, service.CacheOptions{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		store.FindById(laptop.GetId())
+	}()
+
+	// the laptop read before the update is not cached
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&counting.finds) == 1 }, 5*time.Second, time.Millisecond)
+	updated := proto.Clone(laptop).(*pb.Laptop)
+	updated.Name = "Updated"
+	require.NoError(t, store.Update(updated))
+	close(counting.release)
+	<-done
+
+	found, err := store.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, "Updated", found.GetName())
+}
+
+func TestCachingLaptopStoreSearch(t *testing.T) {
+	t.Parallel()
+
+	counting := newCountingLaptopStore()
+	store := service.NewCachingLaptopStore(counting, service.CacheOptions{})
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Save(sample.NewLaptop()))
+	}
+
+	search := func(filter *pb.Filter) []string {
+		ids := []string{}
+		err := store.Search(context.Background(), filter, func(laptop *pb.Laptop) error {
+			ids = append(ids, laptop.GetId())
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	filter := &pb.Filter{MaxPriceUsd: 5000}
+	first := search(filter)
+	require.Len(t, first, 5)
+	require.Equal(t, first, search(&pb.Filter{MaxPriceUsd: 5000}))
+	require.EqualValues(t, 1, atomic.LoadInt32(&counting.searches))
+
+	require.Empty(t, search(&pb.Filter{MaxPriceUsd: 1}))
+	require.Empty(t, search(&pb.Filter{MaxPriceUsd: 1}))
+	require.EqualValues(t, 2, atomic.LoadInt32(&counting.searches))
+
+	// a search stopped before the end is not cached
+	errStop := errors.New("stop")
+	err := store.Search(context.Background(), &pb.Filter{MaxPriceUsd: 4000}, func(laptop *pb.Laptop) error { return errStop })
+	require.ErrorIs(t, err, errStop)
+	search(&pb.Filter{MaxPriceUsd: 4000})
+	require.EqualValues(t, 4, atomic.LoadInt32(&counting.searches))
+
+	// every write invalidates the searches
+	require.NoError(t, store.Save(sample.NewLaptop()))
+	require.Len(t, search(filter), 6)
+	require.EqualValues(t, 5, atomic.LoadInt32(&counting.searches))
+
+	// the sorted searches are cached apart from the unsorted ones with the same filter
+	searchSorted := func(options service.SearchOptions) []string {
+		ids := []string{}
+		err := store.SearchSorted(context.Background(), filter, options, func(laptop *pb.Laptop) error {
+			ids = append(ids, laptop.GetId())
+			return nil
+		})
+		require.NoError(t, err)
+		return ids
+	}
+
+	options := service.SearchOptions{Order: pb.SearchLaptopRequest_ASCENDING, Limit: 3}
+	sorted := searchSorted(options)
+	require.Len(t, sorted, 3)
+	require.Equal(t, sorted, searchSorted(options))
+	require.EqualValues(t, 6, atomic.LoadInt32(&counting.searches))
+
+	options.Limit = 4
+	require.Len(t, searchSorted(options), 4)
+	require.EqualValues(t, 7, atomic.LoadInt32(&counting.searches))
+}
+
+// countingLaptopStore counts the reads of an in-memory store, the reads by ID wait for release once the laptop is read
+type countingLaptopStore struct {
+	*service.InMemoryLaptopStore
+	finds    int32
+	searches int32
+	release  chan struct{}
+}
+
+func newCountingLaptopStore() *countingLaptopStore {
+	return &countingLaptopStore{InMemoryLaptopStore: service.NewInMemoryLaptopStore()}
+}
+
+func (store *countingLaptopStore) FindById(laptopId string) (*pb.Laptop, error) {
+	laptop, err := store.InMemoryLaptopStore.FindById(laptopId)
+	atomic.AddInt32(&store.finds, 1)
+	if store.release != nil {
+		<-store.release
+	}
+	return laptop, err
+}
+
+func (store *countingLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+	atomic.AddInt32(&store.searches, 1)
+	return store.InMemoryLaptopStore.Search(ctx, filter, found)
+}
+
+func (store *countingLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options service.SearchOptions, found func(*pb.Laptop) error) error {
+	atomic.AddInt32(&store.searches, 1)
+	return store.InMemoryLaptopStore.SearchSorted(ctx, filter, options, found)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/validator"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClusterServer is the internal service of a member of a raft cluster. It applies the writes forwarded
+// by the other members when it is the leader, and changes the members of the cluster
+type ClusterServer struct {
+	pb.UnimplementedClusterServiceServer
+	LaptopStore *RaftLaptopStore
+}
+
+func NewClusterServer(laptopStore *RaftLaptopStore) *ClusterServer {
+	return &ClusterServer{LaptopStore: laptopStore}
+}
+
+// ApplyCommand applies a forwarded write, it is never forwarded again: a member which is no longer
+// the leader rejects it with the address of the new one
+func (server *ClusterServer) ApplyCommand(ctx context.Context, req *pb.ApplyCommandRequest) (*pb.ApplyCommandResponse, error) {
+	if violations := validateCommand("command", req.GetCommand()); len(violations) > 0 {
+		return nil, logError(badRequestStatus(ReasonInvalidCommand, "Command is invalid", violations).Err())
+	}
+
+	err := server.LaptopStore.applyCommand(req.GetCommand())
+	if err != nil {
+		return nil, clusterError("cannot apply laptop command", err)
+	}
+
+	return &pb.ApplyCommandResponse{}, nil
+}
+
+func (server *ClusterServer) AddMember(ctx context.Context, req *pb.AddMemberRequest) (*pb.AddMemberResponse, error) {
+	logf(pb.LogLevel_INFO, "receive a request to add member %s with raft address %s", req.GetId(), req.GetRaftAddress())
+
+	violations := []*errdetails.BadRequest_FieldViolation{}
+	if req.GetId() == "" {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "id", Description: "is required"})
+	}
+	if req.GetRaftAddress() == "" {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: "raft_address", Description: "is required"})
+	}
+	if len(violations) > 0 {
+		return nil, badRequestStatus(ReasonInvalidMember, "invalid member", violations).Err()
+	}
+
+	err := server.LaptopStore.AddMember(req.GetId(), req.GetRaftAddress())
+	if err != nil {
+		return nil, logError(clusterError("cannot add member", err))
+	}
+
+	return &pb.AddMemberResponse{}, nil
+}
+
+func (server *ClusterServer) RemoveMember(ctx context.Context, req *pb.RemoveMemberRequest) (*pb.RemoveMemberResponse, error) {
+	logf(pb.LogLevel_INFO, "receive a request to remove member %s", req.GetId())
+
+	if req.GetId() == "" {
+		return nil, badRequestStatus(ReasonInvalidMember, "invalid member", []*errdetails.BadRequest_FieldViolation{
+			{Field: "id", Description: "is required"},
+		}).Err()
+	}
+
+	err := server.LaptopStore.RemoveMember(req.GetId())
+	if err != nil {
+		return nil, logError(clusterError("cannot remove member", err))
+	}
+
+	return &pb.RemoveMemberResponse{}, nil
+}
+
+func (server *ClusterServer) ListMembers(ctx context.Context, req *pb.ListMembersRequest) (*pb.ListMembersResponse, error) {
+	members, err := server.LaptopStore.Members()
+	if err != nil {
+		return nil, logError(clusterError("cannot list members", err))
+	}
+
+	return &pb.ListMembersResponse{Members: members}, nil
+}
+
+// validateCommand checks the laptops of the command like the laptop server checks the requests,
+// the command is appended to the log of every member so it is not trusted even if it comes from a member
+func validateCommand(path string, command *pb.LaptopCommand) []*errdetails.BadRequest_FieldViolation {
+	violations := []*errdetails.BadRequest_FieldViolation{}
+
+	validateLaptop := func(path string, laptop *pb.Laptop) {
+		violations = append(violations, validator.ValidateLaptop(path, laptop)...)
+		if laptop == nil {
+			return
+		}
+		if _, err := uuid.Parse(laptop.GetId()); err != nil {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: path + ".id", Description: "must be a valid UUID"})
+		}
+	}
+
+	switch command := command.GetCommand().(type) {
+	case *pb.LaptopCommand_Save:
+		validateLaptop(path+".save", command.Save)
+	case *pb.LaptopCommand_Update:
+		validateLaptop(path+".update", command.Update)
+	case *pb.LaptopCommand_DeleteId:
+		if _, err := uuid.Parse(command.DeleteId); err != nil {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: path + ".delete_id", Description: "must be a valid UUID"})
+		}
+	case *pb.LaptopCommand_SaveAll:
+		for i, laptop := range command.SaveAll.GetLaptops() {
+			validateLaptop(fmt.Sprintf("%s.save_all.laptops[%d]", path, i), laptop)
+		}
+	default:
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: path, Description: "is required"})
+	}
+
+	return violations
+}
+
+// clusterError keeps the errors of the store distinguishable by the member which forwarded the request
+func clusterError(message string, err error) error {
+	if st := notLeaderStatus(err); st != nil {
+		return st.Err()
+	}
+
+	switch {
+	case errors.Is(err, ErrAlreadyExists):
+		return status.Errorf(codes.AlreadyExists, "%s : %v", message, err)
+	case errors.Is(err, ErrNotFound):
+		return status.Errorf(codes.NotFound, "%s : %v", message, err)
+	default:
+		return storeError(message, err)
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// ErrorDomain is the domain of the ErrorInfo attached to the errors of the laptop service
+const ErrorDomain = "pcbook.laptop"
+
+// reasons of the ErrorInfo, clients can rely on them instead of parsing the error messages
+const (
+	ReasonInvalidLaptopId     = "INVALID_LAPTOP_ID"
+	ReasonInvalidLaptop       = "INVALID_LAPTOP"
+	ReasonInvalidFilter       = "INVALID_FILTER"
+	ReasonLaptopAlreadyExists = "LAPTOP_ALREADY_EXISTS"
+	ReasonLaptopNotFound      = "LAPTOP_NOT_FOUND"
+	ReasonImageTooLarge       = "IMAGE_TOO_LARGE"
+	ReasonImageNotFound       = "IMAGE_NOT_FOUND"
+	ReasonIdGenerationFailed  = "ID_GENERATION_FAILED"
+	ReasonStoreFailure        = "STORE_FAILURE"
+	ReasonStreamFailure       = "STREAM_FAILURE"
+	ReasonRequestCancelled    = "REQUEST_CANCELLED"
+	ReasonDeadlineExceeded    = "DEADLINE_EXCEEDED"
+	ReasonNotLeader           = "NOT_LEADER"
+	ReasonInvalidMember       = "INVALID_MEMBER"
+	ReasonInvalidCommand      = "INVALID_COMMAND"
+)
+
+const laptopResourceType = "pb.Laptop"
+
+// newStatus returns a status whose first detail is an ErrorInfo with the reason, followed by the other details
+func newStatus(code codes.Code, reason string, metadata map[string]string, message string, details ...protoiface.MessageV1) *status.Status {
+	st := status.New(code, message)
+
+	errorInfo := &errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: metadata}
+	detailed, err := st.WithDetails(append([]protoiface.MessageV1{errorInfo}, details...)...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// badRequestStatus returns an InvalidArgument status listing the violations in its message and in a BadRequest detail
+func badRequestStatus(reason string, message string, violations []*errdetails.BadRequest_FieldViolation) *status.Status {
+	fields := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, fmt.Sprintf("%s %s", violation.GetField(), violation.GetDescription()))
+	}
+
+	return newStatus(
+		codes.InvalidArgument,
+		reason,
+		nil,
+		fmt.Sprintf("%s : %s", message, strings.Join(fields, ", ")),
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+}
+
+func laptopResource(laptopId string, description string) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{
+		ResourceType: laptopResourceType,
+		ResourceName: laptopId,
+		Description:  description,
+	}
+}
+
+func streamError(message string, err error) error {
+	return newStatus(codes.Unknown, ReasonStreamFailure, nil, fmt.Sprintf("%s : %v", message, err)).Err()
+}
+
+func storeError(message string, err error, details ...protoiface.MessageV1) error {
+	return newStatus(codes.Internal, ReasonStoreFailure, nil, fmt.Sprintf("%s : %v", message, err), details...).Err()
+}
+
+// notLeaderStatus returns the status of a write rejected by a follower, with the address of the leader, or nil for the other errors
+func notLeaderStatus(err error) *status.Status {
+	var notLeader *NotLeaderError
+	if !errors.As(err, &notLeader) {
+		return nil
+	}
+
+	return newStatus(
+		codes.FailedPrecondition,
+		ReasonNotLeader,
+		map[string]string{"leader": notLeader.Leader},
+		fmt.Sprintf("cannot write laptops on this server : %v", err),
+	)
+}
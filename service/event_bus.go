@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+const subscriptionQueueSize = 1024
+
+var (
+	ErrInvalidResumeToken = errors.New("invalid resume token")
+	ErrResumeTokenExpired = errors.New("resume token is too old, the events are no longer available")
+	ErrSubscriberTooSlow  = errors.New("subscriber is too slow to receive the events")
+)
+
+// LaptopEvent is a mutation of the laptop store
+type LaptopEvent struct {
+	Sequence uint64
+	Type     pb.LaptopEvent_Type
+	Laptop   *pb.Laptop // the laptop after the mutation, or the deleted laptop
+	Previous *pb.Laptop // the laptop before an update
+	Time     time.Time
+	token    string
+}
+
+// ResumeToken identifies the event, a subscriber resuming with it receives the events published after this one
+func (event *LaptopEvent) ResumeToken() string {
+	return event.token
+}
+
+// EventBus delivers the laptop events to the subscribers.
+// The latest events are kept so that a subscriber can resume where it left off.
+type EventBus struct {
+	mutex       sync.Mutex
+	epoch       string
+	sequence    uint64
+	history     []*LaptopEvent // oldest first
+	historySize int
+	subscribers map[*Subscription]bool
+}
+
+func NewEventBus(historySize int) *EventBus {
+	return &EventBus{
+		// tokens of a previous run of the server must not be resumed
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+func (bus *EventBus) Publish(eventType pb.LaptopEvent_Type, laptop *pb.Laptop, previous *pb.Laptop) *LaptopEvent {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.sequence++
+	event := &LaptopEvent{
+		Sequence: bus.sequence,
+		Type:     eventType,
+		Laptop:   laptop,
+		Previous: previous,
+		Time:     time.Now(),
+		token:    bus.token(bus.sequence),
+	}
+
+	bus.history = append(bus.history, event)
+	if len(bus.history) > bus.historySize {
+		bus.history = bus.history[len(bus.history)-bus.historySize:]
+	}
+
+	for subscription := range bus.subscribers {
+		subscription.push(event)
+	}
+
+	return event
+}
+
+// ResumeToken returns the token of the last published event, subscribing with it receives the events published next
+func (bus *EventBus) ResumeToken() string {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	return bus.token(bus.sequence)
+}
+
+func (bus *EventBus) token(sequence uint64) string {
+	return fmt.Sprintf("%s-%d", bus.epoch, sequence)
+}
+
+// Subscribe starts receiving the events published after the resume token, or from now on if the token is empty
+func (bus *EventBus) Subscribe(resumeToken string) (*Subscription, error) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	subscription := &Subscription{
+		bus:    bus,
+		notify: make(chan struct{}, 1),
+	}
+
+	if resumeToken != "" {
+		sequence, err := bus.parseResumeToken(resumeToken)
+		if err != nil {
+			return nil, err
+		}
+
+		// the history must contain every event after the token
+		if sequence < bus.sequence && (len(bus.history) == 0 || bus.history[0].Sequence > sequence+1) {
+			return nil, ErrResumeTokenExpired
+		}
+
+		for _, event := range bus.history {
+			if event.Sequence > sequence {
+				subscription.push(event)
+			}
+		}
+	}
+
+	bus.subscribers[subscription] = true
+	return subscription, nil
+}
+
+func (bus *EventBus) parseResumeToken(resumeToken string) (uint64, error) {
+	epoch, sequenceText, ok := strings.Cut(resumeToken, "-")
+	if !ok {
+		return 0, ErrInvalidResumeToken
+	}
+
+	if epoch != bus.epoch {
+		return 0, ErrResumeTokenExpired
+	}
+
+	sequence, err := strconv.ParseUint(sequenceText, 10, 64)
+	if err != nil || sequence > bus.sequence {
+		return 0, ErrInvalidResumeToken
+	}
+
+	return sequence, nil
+}
+
+func (bus *EventBus) unsubscribe(subscription *Subscription) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	delete(bus.subscribers, subscription)
+}
+
+// Subscription queues the events until they are received with Next
+type Subscription struct {
+	bus      *EventBus
+	mutex    sync.Mutex
+	queue    []*LaptopEvent
+	overflow bool
+	notify   chan struct{}
+}
+
+func (subscription *Subscription) push(event *LaptopEvent) {
+	subscription.mutex.Lock()
+	if len(subscription.queue) >= subscriptionQueueSize {
+		subscription.overflow = true
+	} else {
+		subscription.queue = append(subscription.queue, event)
+	}
+	subscription.mutex.Unlock()
+
+	select {
+	case subscription.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for the next event. ErrSubscriberTooSlow is returned once too many events are waiting,
+// the subscriber should then resume with the token of the last event it received.
+func (subscription *Subscription) Next(ctx context.Context) (*LaptopEvent, error) {
+	for {
+		subscription.mutex.Lock()
+		if subscription.overflow {
+			subscription.mutex.Unlock()
+			return nil, ErrSubscriberTooSlow
+		}
+		if len(subscription.queue) > 0 {
+			event := subscription.queue[0]
+			subscription.queue = subscription.queue[1:]
+			subscription.mutex.Unlock()
+			return event, nil
+		}
+		subscription.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-subscription.notify:
+		}
+	}
+}
+
+func (subscription *Subscription) Close() {
+	subscription.bus.unsubscribe(subscription)
+}
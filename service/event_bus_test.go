@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBusResume(t *testing.T) {
+	t.Parallel()
+
+	bus := service.NewEventBus(3)
+
+	subscription, err := bus.Subscribe("")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	laptops := []*pb.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+	for _, laptop := range laptops {
+		bus.Publish(pb.LaptopEvent_CREATED, laptop, nil)
+	}
+
+	events := make([]*service.LaptopEvent, 0, len(laptops))
+	for i := range laptops {
+		event := requireNextEvent(t, subscription)
+		require.Equal(t, laptops[i].GetId(), event.Laptop.GetId())
+		events = append(events, event)
+	}
+
+	// resuming after the first event replays the two others
+	resumed, err := bus.Subscribe(events[0].ResumeToken())
+	require.NoError(t, err)
+	defer resumed.Close()
+	require.Equal(t, laptops[1].GetId(), requireNextEvent(t, resumed).Laptop.GetId())
+	require.Equal(t, laptops[2].GetId(), requireNextEvent(t, resumed).Laptop.GetId())
+
+	// resuming with the latest token doesn't replay anything
+	latest, err := bus.Subscribe(events[2].ResumeToken())
+	require.NoError(t, err)
+	defer latest.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = latest.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// only the latest 3 events are kept
+	bus.Publish(pb.LaptopEvent_DELETED, laptops[0], nil)
+	stillResumable, err := bus.Subscribe(events[0].ResumeToken())
+	require.NoError(t, err)
+	stillResumable.Close()
+
+	bus.Publish(pb.LaptopEvent_DELETED, laptops[1], nil)
+	_, err = bus.Subscribe(events[0].ResumeToken())
+	require.ErrorIs(t, err, service.ErrResumeTokenExpired)
+
+	_, err = bus.Subscribe("invalid")
+	require.ErrorIs(t, err, service.ErrInvalidResumeToken)
+
+	// tokens of another bus, e.g. before the server restarted, can't be resumed
+	_, err = service.NewEventBus(3).Subscribe(events[0].ResumeToken())
+	require.ErrorIs(t, err, service.ErrResumeTokenExpired)
+}
+
+func TestEventBusSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	bus := service.NewEventBus(10)
+	subscription, err := bus.Subscribe("")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	laptop := sample.NewLaptop()
+	for i := 0; i < 2000; i++ {
+		bus.Publish(pb.LaptopEvent_UPDATED, laptop, laptop)
+	}
+
+	_, err = subscription.Next(context.Background())
+	require.ErrorIs(t, err, service.ErrSubscriberTooSlow)
+}
+
+func TestEventLaptopStorePublishesMutations(t *testing.T) {
+	t.Parallel()
+
+	bus := service.NewEventBus(10)
+	store := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), bus)
+
+	subscription, err := store.Subscribe("")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+
+	laptop.PriceUsd = 999
+	require.NoError(t, store.Update(laptop))
+	require.NoError(t, store.Delete(laptop.GetId()))
+
+	// failed mutations are not published
+	require.ErrorIs(t, store.Delete(laptop.GetId()), service.ErrNotFound)
+	require.ErrorIs(t, store.Update(laptop), service.ErrNotFound)
+
+	event := requireNextEvent(t, subscription)
+	require.Equal(t, pb.LaptopEvent_CREATED, event.Type)
+
+	event = requireNextEvent(t, subscription)
+	require.Equal(t, pb.LaptopEvent_UPDATED, event.Type)
+	require.Equal(t, 999.0, event.Laptop.GetPriceUsd())
+	require.NotEqual(t, 999.0, event.Previous.GetPriceUsd())
+
+	event = requireNextEvent(t, subscription)
+	require.Equal(t, pb.LaptopEvent_DELETED, event.Type)
+	require.Equal(t, laptop.GetId(), event.Laptop.GetId())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = subscription.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func requireNextEvent(t *testing.T, subscription *service.Subscription) *service.LaptopEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	event, err := subscription.Next(ctx)
+	require.NoError(t, err)
+	return event
+}
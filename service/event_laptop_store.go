@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// LaptopWatcher is implemented by stores that publish their mutations
+type LaptopWatcher interface {
+	Subscribe(resumeToken string) (*Subscription, error)
+}
+
+// EventLaptopStore publishes every mutation of the wrapped store to the event bus
+type EventLaptopStore struct {
+	LaptopStore
+	mutex    sync.Mutex // keeps the events in the same order as the mutations
+	EventBus *EventBus
+}
+
+func NewEventLaptopStore(store LaptopStore, eventBus *EventBus) *EventLaptopStore {
+	return &EventLaptopStore{LaptopStore: store, EventBus: eventBus}
+}
+
+func (store *EventLaptopStore) Save(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.LaptopStore.Save(laptop)
+	if err != nil {
+		return err
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	store.EventBus.Publish(pb.LaptopEvent_CREATED, other, nil)
+	return nil
+}
+
+func (store *EventLaptopStore) Update(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	previous, err := store.LaptopStore.FindById(laptop.Id)
+	if err != nil {
+		return err
+	}
+
+	err = store.LaptopStore.Update(laptop)
+	if err != nil {
+		return err
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	store.EventBus.Publish(pb.LaptopEvent_UPDATED, other, previous)
+	return nil
+}
+
+func (store *EventLaptopStore) Delete(laptopId string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	previous, err := store.LaptopStore.FindById(laptopId)
+	if err != nil {
+		return err
+	}
+
+	err = store.LaptopStore.Delete(laptopId)
+	if err != nil {
+		return err
+	}
+
+	store.EventBus.Publish(pb.LaptopEvent_DELETED, previous, nil)
+	return nil
+}
+
+// Begin starts a transaction on the wrapped store, the laptops are published once the transaction is committed
+func (store *EventLaptopStore) Begin() (LaptopTx, error) {
+	transactional, ok := store.LaptopStore.(TransactionalLaptopStore)
+	if !ok {
+		return nil, ErrTxNotSupported
+	}
+
+	tx, err := transactional.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventLaptopTx{LaptopTx: tx, store: store}, nil
+}
+
+type eventLaptopTx struct {
+	LaptopTx
+	store   *EventLaptopStore
+	laptops []*pb.Laptop
+}
+
+func (tx *eventLaptopTx) Save(laptop *pb.Laptop) error {
+	err := tx.LaptopTx.Save(laptop)
+	if err != nil {
+		return err
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	tx.laptops = append(tx.laptops, other)
+	return nil
+}
+
+func (tx *eventLaptopTx) Commit() error {
+	tx.store.mutex.Lock()
+	defer tx.store.mutex.Unlock()
+
+	err := tx.LaptopTx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, laptop := range tx.laptops {
+		tx.store.EventBus.Publish(pb.LaptopEvent_CREATED, laptop, nil)
+	}
+	return nil
+}
+
+// SearchSorted searches the wrapped store
+func (store *EventLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	searcher, ok := store.LaptopStore.(SortedLaptopSearcher)
+	if !ok {
+		return ErrSortedSearchNotSupported
+	}
+	return searcher.SearchSorted(ctx, filter, options, found)
+}
+
+// List goes through the laptops of the wrapped store
+func (store *EventLaptopStore) List(found func(*pb.Laptop) error) error {
+	lister, ok := store.LaptopStore.(LaptopLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	return lister.List(found)
+}
+
+// SubscribeSnapshot calls found with every laptop of the store, then subscribes to the events published after them.
+// No mutation happens in between, the returned token is the one of the last event included in the laptops
+func (store *EventLaptopStore) SubscribeSnapshot(found func(*pb.Laptop) error) (string, *Subscription, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.List(found)
+	if err != nil {
+		return "", nil, err
+	}
+
+	resumeToken := store.EventBus.ResumeToken()
+	subscription, err := store.EventBus.Subscribe(resumeToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return resumeToken, subscription, nil
+}
+
+func (store *EventLaptopStore) Subscribe(resumeToken string) (*Subscription, error) {
+	return store.EventBus.Subscribe(resumeToken)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultReplicationRetryInterval = time.Second
+
+var errNotSynced = errors.New("follower has not received the laptops of the leader yet")
+
+// FollowerLaptopStore is the store of a follower: the reads are served by the local store,
+// which receives the mutations of the leader, and the writes fail with a NotLeaderError.
+// The local store must implement LaptopLister so that the snapshots of the leader can replace its laptops
+type FollowerLaptopStore struct {
+	LaptopStore
+	Leader        string // the internal address of the leader
+	RetryInterval time.Duration
+
+	client      pb.ReplicationServiceClient
+	mutex       sync.Mutex
+	resumeToken string // the token of the last event applied, empty until a snapshot is applied
+}
+
+func NewFollowerLaptopStore(local LaptopStore, leader string, conn grpc.ClientConnInterface) *FollowerLaptopStore {
+	return &FollowerLaptopStore{
+		LaptopStore:   local,
+		Leader:        leader,
+		RetryInterval: defaultReplicationRetryInterval,
+		client:        pb.NewReplicationServiceClient(conn),
+	}
+}
+
+func (store *FollowerLaptopStore) Save(laptop *pb.Laptop) error {
+	return &NotLeaderError{Leader: store.Leader}
+}
+
+func (store *FollowerLaptopStore) Update(laptop *pb.Laptop) error {
+	return &NotLeaderError{Leader: store.Leader}
+}
+
+func (store *FollowerLaptopStore) Delete(laptopId string) error {
+	return &NotLeaderError{Leader: store.Leader}
+}
+
+// SearchSorted searches the local store
+func (store *FollowerLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	searcher, ok := store.LaptopStore.(SortedLaptopSearcher)
+	if !ok {
+		return ErrSortedSearchNotSupported
+	}
+	return searcher.SearchSorted(ctx, filter, options, found)
+}
+
+// List goes through the laptops of the local store
+func (store *FollowerLaptopStore) List(found func(*pb.Laptop) error) error {
+	lister, ok := store.LaptopStore.(LaptopLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	return lister.List(found)
+}
+
+// Subscribe watches the mutations applied to the local store
+func (store *FollowerLaptopStore) Subscribe(resumeToken string) (*Subscription, error) {
+	watcher, ok := store.LaptopStore.(LaptopWatcher)
+	if !ok {
+		return nil, errors.New("local store doesn't publish its changes")
+	}
+	return watcher.Subscribe(resumeToken)
+}
+
+// Ready returns an error until the laptops of the leader are received, the follower keeps serving
+// the laptops it has when the leader becomes unreachable
+func (store *FollowerLaptopStore) Ready() error {
+	if store.ResumeToken() == "" {
+		return errNotSynced
+	}
+	return nil
+}
+
+// ResumeToken returns the token of the last event of the leader applied to the local store
+func (store *FollowerLaptopStore) ResumeToken() string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.resumeToken
+}
+
+func (store *FollowerLaptopStore) setResumeToken(resumeToken string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.resumeToken = resumeToken
+}
+
+// Run replicates the laptops of the leader until the context is done, it reconnects after every failure
+func (store *FollowerLaptopStore) Run(ctx context.Context) {
+	for {
+		err := store.replicate(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logf(pb.LogLevel_WARN, "replication from leader %s stopped, retrying in %v : %v", store.Leader, store.RetryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(store.RetryInterval):
+		}
+	}
+}
+
+// replicate applies the snapshot and the events streamed by the leader until the stream fails
+func (store *FollowerLaptopStore) replicate(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := store.client.Replicate(ctx, &pb.ReplicateRequest{ResumeToken: store.ResumeToken()})
+	if err != nil {
+		return err
+	}
+
+	snapshot := []*pb.Laptop{}
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			return err // even io.EOF, the leader never ends the stream on its own
+		}
+
+		switch data := res.GetData().(type) {
+		case *pb.ReplicateResponse_Snapshot:
+			snapshot = append(snapshot, data.Snapshot.GetLaptops()...)
+			if !data.Snapshot.GetLast() {
+				continue
+			}
+
+			err = store.applySnapshot(snapshot)
+			if err != nil {
+				return fmt.Errorf("cannot apply snapshot : %w", err)
+			}
+			snapshot = []*pb.Laptop{}
+			store.setResumeToken(data.Snapshot.GetResumeToken())
+			logf(pb.LogLevel_INFO, "applied snapshot of leader %s", store.Leader)
+
+		case *pb.ReplicateResponse_Event:
+			err = store.applyEvent(data.Event)
+			if err != nil {
+				return fmt.Errorf("cannot apply %v event of laptop %s : %w", data.Event.GetType(), data.Event.GetLaptop().GetId(), err)
+			}
+			store.setResumeToken(data.Event.GetResumeToken())
+		}
+	}
+}
+
+// applySnapshot makes the laptops of the local store the same as the ones of the snapshot
+func (store *FollowerLaptopStore) applySnapshot(laptops []*pb.Laptop) error {
+	return replaceLaptops(store.LaptopStore, laptops)
+}
+
+// applyEvent applies the mutation of the leader, the mutations already applied are ignored
+func (store *FollowerLaptopStore) applyEvent(event *pb.LaptopEvent) error {
+	switch event.GetType() {
+	case pb.LaptopEvent_CREATED, pb.LaptopEvent_UPDATED:
+		return putLaptop(store.LaptopStore, event.GetLaptop())
+	case pb.LaptopEvent_DELETED:
+		err := store.LaptopStore.Delete(event.GetLaptop().GetId())
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown event type %v", event.GetType())
+	}
+}
+
+// replaceLaptops makes the laptops of the store the same as the given ones, the store must implement LaptopLister
+func replaceLaptops(store LaptopStore, laptops []*pb.Laptop) error {
+	lister, ok := store.(LaptopLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+
+	kept := make(map[string]bool, len(laptops))
+	for _, laptop := range laptops {
+		kept[laptop.GetId()] = true
+	}
+
+	removed := []string{}
+	err := lister.List(func(laptop *pb.Laptop) error {
+		if !kept[laptop.GetId()] {
+			removed = append(removed, laptop.GetId())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, laptopId := range removed {
+		err := store.Delete(laptopId)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	for _, laptop := range laptops {
+		err := putLaptop(store, laptop)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putLaptop saves the laptop, or updates it if the store already has it
+func putLaptop(store LaptopStore, laptop *pb.Laptop) error {
+	existing, err := store.FindById(laptop.GetId())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case existing == nil:
+		return store.Save(laptop)
+	case proto.Equal(existing, laptop):
+		return nil // unchanged since the last snapshot
+	default:
+		return store.Update(laptop)
+	}
+}
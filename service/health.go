@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ReadinessChecker is implemented by stores that can tell whether they are able to serve requests
+type ReadinessChecker interface {
+	Ready() error
+}
+
+// Flusher is implemented by stores that keep data which must be persisted before the server exits
+type Flusher interface {
+	Flush() error
+}
+
+// HealthReporter reports the readiness of the stores to the standard gRPC health service
+type HealthReporter struct {
+	HealthServer *health.Server
+	Stores       []ReadinessChecker
+	Interval     time.Duration
+}
+
+func NewHealthReporter(healthServer *health.Server, interval time.Duration, stores ...ReadinessChecker) *HealthReporter {
+	return &HealthReporter{
+		HealthServer: healthServer,
+		Stores:       stores,
+		Interval:     interval,
+	}
+}
+
+// Check checks every store once and updates the serving status of the server and the laptop service
+func (reporter *HealthReporter) Check() healthpb.HealthCheckResponse_ServingStatus {
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+
+	for _, store := range reporter.Stores {
+		if err := store.Ready(); err != nil {
+			logf(pb.LogLevel_WARN, "store is not ready : %v", err)
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+			break
+		}
+	}
+
+	reporter.HealthServer.SetServingStatus("", servingStatus)
+	reporter.HealthServer.SetServingStatus(pb.LaptopService_ServiceDesc.ServiceName, servingStatus)
+
+	return servingStatus
+}
+
+// Run checks the stores every interval until the context is done
+func (reporter *HealthReporter) Run(ctx context.Context) {
+	reporter.Check()
+
+	ticker := time.NewTicker(reporter.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reporter.Check()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthReporterReflectsStoreReadiness(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := filepath.Join(t.TempDir(), "img")
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	healthServer := health.NewServer()
+	reporter := service.NewHealthReporter(healthServer, time.Second, laptopStore, imageStore)
+
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	healthClient := healthpb.NewHealthClient(conn)
+
+	// the image folder doesn't exist yet
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, reporter.Check())
+	requireServingStatus(t, healthClient, pb.LaptopService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	require.NoError(t, os.Mkdir(imageFolder, 0755))
+
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, reporter.Check())
+	requireServingStatus(t, healthClient, "", healthpb.HealthCheckResponse_SERVING)
+	requireServingStatus(t, healthClient, pb.LaptopService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	// once shut down the server never reports serving again
+	healthServer.Shutdown()
+	reporter.Check()
+	requireServingStatus(t, healthClient, "", healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func TestStopGracefullyWaitsForInFlightUpload(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	laptopServer := service.NewLaptopServer(laptopStore, imageStore, nil)
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+
+	laptopClient := newTestLaptopClient(t, listener.Addr().String())
+
+	// an upload that is never finished by the client
+	stream, err := laptopClient.UploadImage(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UploadImageRequest{
+		Data: &pb.UploadImageRequest_Info{Info: &pb.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".png"}},
+	}))
+	require.NoError(t, stream.Send(&pb.UploadImageRequest{
+		Data: &pb.UploadImageRequest_ChunkData{ChunkData: []byte("chunk")},
+	}))
+	time.Sleep(100 * time.Millisecond) // let the server start receiving the chunks
+
+	start := time.Now()
+	require.False(t, service.StopGracefully(grpcServer, 200*time.Millisecond))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestStopGracefullyWithoutInFlightRequests(t *testing.T) {
+	t.Parallel()
+
+	grpcServer := grpc.NewServer()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+
+	require.True(t, service.StopGracefully(grpcServer, 5*time.Second))
+}
+
+func requireServingStatus(t *testing.T, healthClient healthpb.HealthClient, serviceName string, expected healthpb.HealthCheckResponse_ServingStatus) {
+	res, err := healthClient.Check(context.Background(), &healthpb.HealthCheckRequest{Service: serviceName})
+	require.NoError(t, err)
+	require.Equal(t, expected, res.GetStatus())
+}
@@ -3,9 +3,13 @@ package service
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/daffarg/grpc-pcbook/pb"
 	"github.com/google/uuid"
 )
 
@@ -13,59 +17,354 @@ type ImageStore interface {
 	Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error) // returns image id and error
 }
 
+// ImageTx writes the saved images on disk right away but adds them to the store only once committed,
+// the deleted images are kept aside until the commit. Rollback undoes the writes and does nothing after Commit
+type ImageTx interface {
+	Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error)
+	DeleteByLaptop(laptopID string) (int, error) // returns how many images of the laptop are deleted
+	Commit() error
+	Rollback()
+}
+
+// TransactionalImageStore is implemented by the image stores whose writes can be undone
+type TransactionalImageStore interface {
+	Begin() (ImageTx, error)
+}
+
+// ImageReader is implemented by the image stores which can give back the saved images
+type ImageReader interface {
+	// Open returns the info and the data of the image, or ErrNotFound, the caller must close the data
+	Open(imageID string) (*ImageInfo, io.ReadCloser, error)
+}
+
 type DiskImageStore struct {
 	mutex sync.RWMutex
 	ImageFolder string
 	Images map[string]*ImageInfo
+	pending map[string]bool // the paths of the images being written or saved by the transactions in progress
 }
 
 type ImageInfo struct {
 	LaptopID string
 	Type string
 	Path string
+	Size int
 }
 
 func NewDiskImageStore(imageFolder string) *DiskImageStore {
 	return &DiskImageStore {
 		ImageFolder: imageFolder,
 		Images: make(map[string]*ImageInfo),
+		pending: make(map[string]bool),
 	}
 }
 
 func (store *DiskImageStore) Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error) {
+	imageId, image, err := store.writeImage(laptopID, imageType, imageData)
+	if err != nil {
+		return "", err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.Images[imageId] = image
+	delete(store.pending, filepath.Clean(image.Path))
+	return imageId, nil
+}
+
+// writeImage writes the image file without adding it to the store, a partially written file is removed.
+// The path is pending from before the file is created so that Compact doesn't remove it,
+// the caller clears it once the image is added to the store
+func (store *DiskImageStore) writeImage(laptopID string, imageType string, imageData bytes.Buffer) (string, *ImageInfo, error) {
 	// create new image ID
 	imageId, err := uuid.NewRandom()
 	if err != nil {
-		return "", fmt.Errorf("cannot create new image ID: %v", err)
+		return "", nil, fmt.Errorf("cannot create new image ID: %v", err)
 	}
 
 	imagePath := fmt.Sprintf("%s/%s%s", store.ImageFolder, imageId.String(), imageType)
+	imageSize := imageData.Len()
+
+	store.setPending(imagePath, true)
 
 	file, err := os.Create(imagePath) // create new file
 	if err != nil {
-		return "", fmt.Errorf("cannot create the image file: %v", err)
+		store.setPending(imagePath, false)
+		return "", nil, fmt.Errorf("cannot create the image file: %v", err)
 	}
 
 	_, err = imageData.WriteTo(file) // write image data to file that created before
 	if err != nil {
-		return "", fmt.Errorf("cannot write to image file: %v", err)
+		file.Close()
+		os.Remove(imagePath)
+		store.setPending(imagePath, false)
+		return "", nil, fmt.Errorf("cannot write to image file: %v", err)
 	}
 
 	err = file.Close()
 	if err != nil {
-		return "", fmt.Errorf("cannot close the file: %v", err)
+		os.Remove(imagePath)
+		store.setPending(imagePath, false)
+		return "", nil, fmt.Errorf("cannot close the file: %v", err)
 	}
 
-	store.mutex.Lock()
-	defer store.mutex.Unlock()
-
-	store.Images[imageId.String()] = &ImageInfo{
+	image := &ImageInfo{
 		LaptopID: laptopID,
 		Type: imageType,
 		Path: imagePath,
+		Size: imageSize,
+	}
+
+	return imageId.String(), image, nil
+}
+
+func (store *DiskImageStore) Open(imageID string) (*ImageInfo, io.ReadCloser, error) {
+	store.mutex.RLock()
+	image := store.Images[imageID]
+	store.mutex.RUnlock()
+
+	if image == nil {
+		return nil, nil, ErrNotFound
 	}
 
-	return imageId.String(), nil
-} 
+	file, err := os.Open(image.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open the image file: %v", err)
+	}
 
+	info := *image
+	return &info, file, nil
+}
+
+func (store *DiskImageStore) Begin() (ImageTx, error) {
+	return &diskImageTx{
+		store: store,
+		saved: make(map[string]*ImageInfo),
+		deleted: make(map[string]*ImageInfo),
+	}, nil
+}
+
+// diskImageTx writes the saved images in the image folder and renames the deleted ones into hidden files,
+// so that the rollback can remove the first ones and rename the others back
+type diskImageTx struct {
+	store *DiskImageStore
+	saved map[string]*ImageInfo
+	deleted map[string]*ImageInfo
+	done bool
+}
+
+// deletedImagePath is hidden so that Compact keeps the deleted images until the transaction ends
+func deletedImagePath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".deleted")
+}
+
+func (tx *diskImageTx) Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error) {
+	if tx.done {
+		return "", ErrTxDone
+	}
+
+	// the image stays pending until the transaction ends
+	imageId, image, err := tx.store.writeImage(laptopID, imageType, imageData)
+	if err != nil {
+		return "", err
+	}
+
+	tx.saved[imageId] = image
+	return imageId, nil
+}
+
+func (tx *diskImageTx) DeleteByLaptop(laptopID string) (int, error) {
+	if tx.done {
+		return 0, ErrTxDone
+	}
+
+	tx.store.mutex.RLock()
+	images := make(map[string]*ImageInfo)
+	for imageId, image := range tx.store.Images {
+		if image.LaptopID == laptopID && tx.deleted[imageId] == nil {
+			images[imageId] = image
+		}
+	}
+	tx.store.mutex.RUnlock()
+
+	for imageId, image := range images {
+		err := os.Rename(image.Path, deletedImagePath(image.Path))
+		if err != nil {
+			return 0, fmt.Errorf("cannot delete the image file: %v", err)
+		}
+		tx.deleted[imageId] = image
+	}
+
+	// the images saved by the transaction are deleted too
+	for imageId, image := range tx.saved {
+		if image.LaptopID != laptopID {
+			continue
+		}
+		err := os.Remove(image.Path)
+		if err != nil {
+			return 0, fmt.Errorf("cannot delete the image file: %v", err)
+		}
+		delete(tx.saved, imageId)
+		tx.store.setPending(image.Path, false)
+		images[imageId] = image
+	}
+
+	return len(images), nil
+}
+
+func (store *DiskImageStore) setPending(path string, pending bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if pending {
+		store.pending[filepath.Clean(path)] = true
+	} else {
+		delete(store.pending, filepath.Clean(path))
+	}
+}
 
+// Commit adds the saved images to the store and removes the deleted ones, the files of the deleted
+// images which cannot be removed are left for Compact
+func (tx *diskImageTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	tx.store.mutex.Lock()
+	for imageId, image := range tx.saved {
+		tx.store.Images[imageId] = image
+		delete(tx.store.pending, filepath.Clean(image.Path))
+	}
+	for imageId := range tx.deleted {
+		delete(tx.store.Images, imageId)
+	}
+	tx.store.mutex.Unlock()
+
+	var removeErr error
+	for _, image := range tx.deleted {
+		err := os.Remove(deletedImagePath(image.Path))
+		if err != nil && removeErr == nil {
+			removeErr = fmt.Errorf("cannot remove the deleted image file: %v", err)
+		}
+	}
+
+	return removeErr
+}
+
+func (tx *diskImageTx) Rollback() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+
+	for _, image := range tx.saved {
+		tx.store.setPending(image.Path, false)
+		if err := os.Remove(image.Path); err != nil {
+			logf(pb.LogLevel_WARN, "cannot remove the image file %s of a rolled back transaction : %v", image.Path, err)
+		}
+	}
+	for _, image := range tx.deleted {
+		if err := os.Rename(deletedImagePath(image.Path), image.Path); err != nil {
+			logf(pb.LogLevel_ERROR, "cannot restore the image file %s of a rolled back transaction : %v", image.Path, err)
+		}
+	}
+}
+
+// Ready checks that the image folder exists
+func (store *DiskImageStore) Ready() error {
+	info, err := os.Stat(store.ImageFolder)
+	if err != nil {
+		return fmt.Errorf("cannot access the image folder: %v", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("image folder %s is not a directory", store.ImageFolder)
+	}
+
+	return nil
+}
+
+// Flush syncs the saved image files and the image folder so that they are durable on disk
+func (store *DiskImageStore) Flush() error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, image := range store.Images {
+		err := syncFile(image.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return syncFile(store.ImageFolder)
+}
+
+func syncFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("cannot sync %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (store *DiskImageStore) Stats() (StoreStats, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	stats := StoreStats{Count: uint64(len(store.Images))}
+	for _, image := range store.Images {
+		stats.Bytes += uint64(image.Size)
+	}
+
+	return stats, nil
+}
+
+// Compact removes the files in the image folder which are not known by the store,
+// e.g. partially written images of failed uploads or images saved by a previous run.
+// Hidden files such as .gitignore are kept.
+func (store *DiskImageStore) Compact() (CompactResult, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	known := make(map[string]bool)
+	for _, image := range store.Images {
+		known[filepath.Clean(image.Path)] = true
+	}
+
+	entries, err := os.ReadDir(store.ImageFolder)
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("cannot read the image folder: %v", err)
+	}
+
+	result := CompactResult{}
+	for _, entry := range entries {
+		path := filepath.Join(store.ImageFolder, entry.Name())
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || known[path] || store.pending[path] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return result, fmt.Errorf("cannot stat %s: %v", path, err)
+		}
+
+		err = os.Remove(path)
+		if err != nil {
+			return result, fmt.Errorf("cannot remove %s: %v", path, err)
+		}
+
+		result.Removed++
+		result.ReclaimedBytes += uint64(info.Size())
+	}
+
+	return result, nil
+}
@@ -2,6 +2,7 @@ package service_test
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -12,19 +13,23 @@ import (
 	"testing"
 	"time"
 
+	"github.com/daffarg/grpc-pcbook/money"
 	"github.com/daffarg/grpc-pcbook/pb"
 	"github.com/daffarg/grpc-pcbook/sample"
 	"github.com/daffarg/grpc-pcbook/serializer"
 	"github.com/daffarg/grpc-pcbook/service"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestClientCreateLaptop(t *testing.T) {
 	t.Parallel()
 
 	laptopStore := service.NewInMemoryLaptopStore()
-	listenAddr := startTestLaptopServer(t, laptopStore, nil)
+	listenAddr := startTestLaptopServer(t, laptopStore, nil, nil)
 	laptopClient := newTestLaptopClient(t, listenAddr)
 
 	laptop := sample.NewLaptop()
@@ -63,7 +68,7 @@ func TestClientSearchLaptop(t *testing.T) {
 	expectedId := make(map[string]bool)
 	laptop_sent := []*pb.Laptop{}
 
-	for i := 0; i < 6; i ++ {
+	for i := 0; i < 8; i ++ {
 		laptop := sample.NewLaptop()
 		switch i {
 			case 0:
@@ -88,6 +93,18 @@ func TestClientSearchLaptop(t *testing.T) {
 				laptop.Cpu.MaxGhz = 5.0;
 				laptop.Ram = &pb.Memory{Unit: pb.Memory_GIGABYTE, Value: 64}
 				expectedId[laptop.Id] = true
+			case 6: // far less than 8 GB, kilobytes used to be counted as megabytes
+				laptop.PriceUsd = 1500
+				laptop.Cpu.NumberCores = 4
+				laptop.Cpu.MinGhz = 2.5
+				laptop.Ram = &pb.Memory{Unit: pb.Memory_KILOBYTE, Value: 9000}
+			case 7:
+				laptop.PriceUsd = 1500
+				laptop.Cpu.NumberCores = 4
+				laptop.Cpu.MinGhz = 2.5
+				laptop.Cpu.MaxGhz = 4.5
+				laptop.Ram = &pb.Memory{Unit: pb.Memory_MEGABYTE, Value: 8192}
+				expectedId[laptop.Id] = true
 		}
 		err := store.Save(laptop)
 		require.NoError(t, err)
@@ -100,7 +117,7 @@ func TestClientSearchLaptop(t *testing.T) {
 		Filter: filter,
 	}
 
-	serverAddress := startTestLaptopServer(t, store, nil)
+	serverAddress := startTestLaptopServer(t, store, nil, nil)
 	laptopClient := newTestLaptopClient(t, serverAddress)
 
 	stream, err := laptopClient.SearchLaptop(context.Background(), searchLaptopReq)
@@ -130,6 +147,104 @@ func TestClientSearchLaptop(t *testing.T) {
 
 }	
 
+func TestClientGetLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	laptopClient := newTestLaptopClient(t, startTestLaptopServer(t, laptopStore, nil, nil))
+
+	res, err := laptopClient.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: laptop.GetId()})
+	require.NoError(t, err)
+	requireSameLaptop(t, laptop, res.GetLaptop())
+
+	_, err = laptopClient.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: sample.NewLaptop().GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = laptopClient.GetLaptop(context.Background(), &pb.GetLaptopRequest{Id: "invalid-uuid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestClientSearchLaptopByPrice(t *testing.T) {
+	t.Parallel()
+
+	rates := &money.Rates{Base: money.USD, Rates: map[string]float64{"EUR": 0.5}}
+
+	store := service.NewInMemoryLaptopStore()
+	store.Rates = rates
+	laptopServer := service.NewLaptopServer(store, nil, nil)
+	laptopServer.Rates = rates
+
+	laptopClient := newTestLaptopClient(t, serveTestLaptopServer(t, laptopServer))
+
+	// 1800 USD, 1000 EUR = 2000 USD, 1500 USD from a legacy client and 1200 EUR = 2400 USD
+	prices := []*pb.Money{
+		money.FromFloat(money.USD, 1800),
+		{CurrencyCode: "EUR", Units: 1000},
+		nil,
+		{CurrencyCode: "EUR", Units: 1200},
+	}
+
+	ids := make([]string, len(prices))
+	for i, price := range prices {
+		laptop := sample.NewLaptop()
+		laptop.Price = price
+		laptop.PriceUsd = 0
+		if price == nil {
+			laptop.PriceUsd = 1500
+		}
+
+		res, err := laptopClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+		require.NoError(t, err)
+		ids[i] = res.GetId()
+	}
+
+	// price_usd is filled from the price in another currency, and the price from price_usd
+	saved, err := store.FindById(ids[1])
+	require.NoError(t, err)
+	require.InDelta(t, 2000, saved.GetPriceUsd(), 1e-6)
+
+	saved, err = store.FindById(ids[2])
+	require.NoError(t, err)
+	require.Equal(t, "1500.00 USD", money.Format(saved.GetPrice()))
+
+	search := func(req *pb.SearchLaptopRequest) []string {
+		stream, err := laptopClient.SearchLaptop(context.Background(), req)
+		require.NoError(t, err)
+
+		found := []string{}
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return found
+			}
+			require.NoError(t, err)
+			found = append(found, res.GetLaptop().GetId())
+		}
+	}
+
+	// at most 1000 EUR is 2000 USD
+	found := search(&pb.SearchLaptopRequest{
+		Filter:     &pb.Filter{MaxPrice: &pb.Money{CurrencyCode: "EUR", Units: 1000}},
+		PriceOrder: pb.SearchLaptopRequest_ASCENDING,
+	})
+	require.Equal(t, []string{ids[2], ids[0], ids[1]}, found)
+
+	found = search(&pb.SearchLaptopRequest{
+		Filter:     &pb.Filter{MaxPriceUsd: 3000},
+		PriceOrder: pb.SearchLaptopRequest_DESCENDING,
+	})
+	require.Equal(t, []string{ids[3], ids[1], ids[0], ids[2]}, found)
+
+	// there is no exchange rate for JPY
+	laptop := sample.NewLaptop()
+	laptop.Price = &pb.Money{CurrencyCode: "JPY", Units: 250000}
+	_, err = laptopClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestClientUploadImage(t * testing.T) {
 	t.Parallel()
 
@@ -142,7 +257,7 @@ func TestClientUploadImage(t * testing.T) {
 	err := laptopStore.Save(laptop)
 	require.NoError(t, err)
 
-	serverAddress := startTestLaptopServer(t, laptopStore, imageStore)
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
 	serviceClient := newTestLaptopClient(t, serverAddress)
 
 	imagePath := fmt.Sprintf("%s/laptop.png", imageFolder)
@@ -207,9 +322,376 @@ func TestClientUploadImage(t * testing.T) {
 	log.Printf("successfully uploaded image with ID = %s and size = %d", res.GetId(), res.GetSize())
 } 
 
-func startTestLaptopServer(t *testing.T, laptopStore service.LaptopStore, imageStore service.ImageStore) string {
-	laptopServer := service.NewLaptopServer(laptopStore, imageStore)
+func TestClientDownloadImage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	// larger than a chunk so that the image is sent in several responses
+	image := bytes.Repeat([]byte("laptop"), 20000)
+	imageId, err := imageStore.Save(laptop.GetId(), ".png", *bytes.NewBuffer(image))
+	require.NoError(t, err)
+
+	laptopClient := newTestLaptopClient(t, startTestLaptopServer(t, laptopStore, imageStore, nil))
+
+	stream, err := laptopClient.DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: imageId})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), res.GetInfo().GetLaptopId())
+	require.Equal(t, ".png", res.GetInfo().GetImageType())
+
+	var downloaded bytes.Buffer
+	chunks := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		downloaded.Write(res.GetChunkData())
+		chunks++
+	}
+	require.Equal(t, image, downloaded.Bytes())
+	require.Greater(t, chunks, 1)
+
+	stream, err = laptopClient.DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: "unknown"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClientUploadImageErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	err := laptopStore.Save(laptop)
+	require.NoError(t, err)
+
+	serverAddress := startTestLaptopServer(t, laptopStore, service.NewDiskImageStore("../tmp"), nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	upload := func(laptopId string, size int) error {
+		stream, err := laptopClient.UploadImage(context.Background())
+		require.NoError(t, err)
+
+		err = stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_Info{Info: &pb.ImageInfo{LaptopId: laptopId, ImageType: ".png"}},
+		})
+		require.NoError(t, err)
+
+		chunk := make([]byte, 64*1024)
+		for sent := 0; sent < size; sent += len(chunk) {
+			// the server stops receiving as soon as the image is too large
+			if err := stream.Send(&pb.UploadImageRequest{Data: &pb.UploadImageRequest_ChunkData{ChunkData: chunk}}); err != nil {
+				break
+			}
+		}
+
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	err = upload(laptop.GetId(), 2<<20)
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+	require.Equal(t, service.ReasonImageTooLarge, st.Details()[0].(*errdetails.ErrorInfo).GetReason())
+
+	quotaFailure, ok := st.Details()[1].(*errdetails.QuotaFailure)
+	require.True(t, ok)
+	require.Equal(t, "laptop:"+laptop.GetId(), quotaFailure.GetViolations()[0].GetSubject())
+
+	unknownId := sample.NewLaptop().GetId()
+	err = upload(unknownId, 0)
+	st = status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Equal(t, service.ReasonLaptopNotFound, st.Details()[0].(*errdetails.ErrorInfo).GetReason())
+
+	resourceFound := false
+	for _, detail := range st.Details() {
+		if resourceInfo, ok := detail.(*errdetails.ResourceInfo); ok {
+			require.Equal(t, unknownId, resourceInfo.GetResourceName())
+			resourceFound = true
+		}
+	}
+	require.True(t, resourceFound)
+}
+
+func TestClientRateLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	ratingStore := service.NewInMemoryRatingStore()
+
+	laptop := sample.NewLaptop()
+	err := laptopStore.Save(laptop)
+	require.NoError(t, err)
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, ratingStore)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	stream, err := laptopClient.RateLaptop(context.Background())
+	require.NoError(t, err)
+
+	scores := []float64{8, 7.5, 10}
+	averages := []float64{8, 7.75, 8.5}
+
+	for i, score := range scores {
+		err := stream.Send(&pb.RateLaptopRequest{
+			LaptopId: laptop.GetId(),
+			Score: score,
+		})
+		require.NoError(t, err)
+
+		// the server replies to every rating with the running average
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, laptop.GetId(), res.GetLaptopId())
+		require.EqualValues(t, i+1, res.GetRatedCount())
+		require.Equal(t, averages[i], res.GetAverageScore())
+	}
+
+	err = stream.CloseSend()
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestClientRateLaptopNotFound(t *testing.T) {
+	t.Parallel()
+
+	serverAddress := startTestLaptopServer(t, service.NewInMemoryLaptopStore(), nil, service.NewInMemoryRatingStore())
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	stream, err := laptopClient.RateLaptop(context.Background())
+	require.NoError(t, err)
+
+	err = stream.Send(&pb.RateLaptopRequest{LaptopId: "unknown", Score: 5})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestClientWatchLaptops(t *testing.T) {
+	t.Parallel()
+
+	bus := service.NewEventBus(100)
+	laptopStore := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), bus)
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	// get a token of an event published before watching, so that no event is missed by the watcher
+	subscription, err := bus.Subscribe("")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	first := sample.NewLaptop()
+	first.PriceUsd = 1000
+	require.NoError(t, laptopStore.Save(first))
+	firstEvent, err := subscription.Next(context.Background())
+	require.NoError(t, err)
+
+	cheap := sample.NewLaptop()
+	cheap.PriceUsd = 1500
+	expensive := sample.NewLaptop()
+	expensive.PriceUsd = 2500
+	require.NoError(t, laptopStore.Save(cheap))
+	require.NoError(t, laptopStore.Save(expensive))
+
+	// an update that makes the laptop too expensive is still sent, the watcher has to know it doesn't match anymore
+	cheap.PriceUsd = 3000
+	require.NoError(t, laptopStore.Update(cheap))
+	require.NoError(t, laptopStore.Delete(first.GetId()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := laptopClient.WatchLaptops(ctx, &pb.WatchLaptopsRequest{
+		Filter: &pb.Filter{MaxPriceUsd: 2000},
+		ResumeToken: firstEvent.ResumeToken(),
+	})
+	require.NoError(t, err)
+
+	expected := []struct {
+		eventType pb.LaptopEvent_Type
+		laptopId string
+	}{
+		{pb.LaptopEvent_CREATED, cheap.GetId()},
+		{pb.LaptopEvent_UPDATED, cheap.GetId()},
+		{pb.LaptopEvent_DELETED, first.GetId()},
+	}
+
+	var lastToken string
+	for _, e := range expected {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, e.eventType, res.GetEvent().GetType())
+		require.Equal(t, e.laptopId, res.GetEvent().GetLaptop().GetId())
+		require.NotEmpty(t, res.GetEvent().GetResumeToken())
+		lastToken = res.GetEvent().GetResumeToken()
+	}
+	cancel()
+
+	// reconnect with the last token, only the new events are received
+	third := sample.NewLaptop()
+	third.PriceUsd = 1200
+	require.NoError(t, laptopStore.Save(third))
+
+	stream, err = laptopClient.WatchLaptops(context.Background(), &pb.WatchLaptopsRequest{
+		Filter: &pb.Filter{MaxPriceUsd: 2000},
+		ResumeToken: lastToken,
+	})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, pb.LaptopEvent_CREATED, res.GetEvent().GetType())
+	require.Equal(t, third.GetId(), res.GetEvent().GetLaptop().GetId())
+
+	// a malformed token is rejected
+	stream, err = laptopClient.WatchLaptops(context.Background(), &pb.WatchLaptopsRequest{ResumeToken: "malformed"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestClientBulkCreateLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	existing := sample.NewLaptop()
+	err := laptopStore.Save(existing)
+	require.NoError(t, err)
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	stream, err := laptopClient.BulkCreateLaptops(context.Background())
+	require.NoError(t, err)
+
+	err = stream.Send(&pb.BulkCreateLaptopsRequest{
+		Data: &pb.BulkCreateLaptopsRequest_Options{Options: &pb.BulkCreateOptions{BatchSize: 2}},
+	})
+	require.NoError(t, err)
+
+	invalid := sample.NewLaptop()
+	invalid.Id = "invalid-uuid"
+
+	noId := sample.NewLaptop()
+	noId.Id = ""
+
+	laptops := []*pb.Laptop{sample.NewLaptop(), existing, invalid, noId, sample.NewLaptop()}
+	expected := []codes.Code{codes.OK, codes.AlreadyExists, codes.InvalidArgument, codes.OK, codes.OK}
+
+	for _, laptop := range laptops {
+		err := stream.Send(&pb.BulkCreateLaptopsRequest{
+			Data: &pb.BulkCreateLaptopsRequest_Laptop{Laptop: laptop},
+		})
+		require.NoError(t, err)
+	}
+
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), len(laptops))
+	require.EqualValues(t, 3, res.GetCreatedCount())
+	require.EqualValues(t, 2, res.GetFailedCount())
+
+	for i, result := range res.GetResults() {
+		require.EqualValues(t, i, result.GetIndex())
+		require.Equal(t, expected[i], codes.Code(result.GetStatus().GetCode()))
+
+		if expected[i] == codes.OK {
+			require.NotEmpty(t, result.GetId())
+			other, err := laptopStore.FindById(result.GetId())
+			require.NoError(t, err)
+			require.NotNil(t, other)
+		}
+	}
+}
+
+func TestClientBulkCreateLaptopsAtomic(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	existing := sample.NewLaptop()
+	err := laptopStore.Save(existing)
+	require.NoError(t, err)
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	// one laptop already exists, so none of them is created
+	first, second := sample.NewLaptop(), sample.NewLaptop()
+	res, err := bulkCreateAtomic(t, laptopClient, first, existing, second)
+	require.NoError(t, err)
+	require.Zero(t, res.GetCreatedCount())
+	require.EqualValues(t, 3, res.GetFailedCount())
+	require.Equal(t, codes.Aborted, codes.Code(res.GetResults()[0].GetStatus().GetCode()))
+	require.Equal(t, codes.AlreadyExists, codes.Code(res.GetResults()[1].GetStatus().GetCode()))
+	require.Equal(t, codes.Aborted, codes.Code(res.GetResults()[2].GetStatus().GetCode()))
+	require.Equal(t, 1, laptopStore.Len())
+
+	res, err = bulkCreateAtomic(t, laptopClient, first, second)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, res.GetCreatedCount())
+	require.Zero(t, res.GetFailedCount())
+	require.Equal(t, 3, laptopStore.Len())
+}
+
+func TestClientBulkCreateLaptopsAtomicWithoutTx(t *testing.T) {
+	t.Parallel()
+
+	// the laptops saved without a transaction would be seen before the creation is undone
+	laptopStore := service.NewInMemoryLaptopStore()
+	serverAddress := startTestLaptopServer(t, &nonTransactionalLaptopStore{LaptopStore: laptopStore}, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	_, err := bulkCreateAtomic(t, laptopClient, sample.NewLaptop(), sample.NewLaptop())
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Zero(t, laptopStore.Len())
+}
+
+// bulkCreateAtomic creates the laptops in a single atomic bulk creation saving them one by one
+func bulkCreateAtomic(t *testing.T, laptopClient pb.LaptopServiceClient, laptops ...*pb.Laptop) (*pb.BulkCreateLaptopsResponse, error) {
+	stream, err := laptopClient.BulkCreateLaptops(context.Background())
+	require.NoError(t, err)
+
+	// io.EOF means that the server has ended the stream, its error is returned by CloseAndRecv
+	err = stream.Send(&pb.BulkCreateLaptopsRequest{
+		Data: &pb.BulkCreateLaptopsRequest_Options{Options: &pb.BulkCreateOptions{Atomic: true, BatchSize: 1}},
+	})
+	if err != io.EOF {
+		require.NoError(t, err)
+	}
+
+	for _, laptop := range laptops {
+		err := stream.Send(&pb.BulkCreateLaptopsRequest{
+			Data: &pb.BulkCreateLaptopsRequest_Laptop{Laptop: laptop},
+		})
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	return stream.CloseAndRecv()
+}
+
+func startTestLaptopServer(t *testing.T, laptopStore service.LaptopStore, imageStore service.ImageStore, ratingStore service.RatingStore) string {
+	return serveTestLaptopServer(t, service.NewLaptopServer(laptopStore, imageStore, ratingStore))
+}
 
+func serveTestLaptopServer(t *testing.T, laptopServer *service.LaptopServer) string {
 	grpcServer := grpc.NewServer()
 	pb.RegisterLaptopServiceServer(grpcServer, laptopServer) // register laptopServer to grpcServer
 
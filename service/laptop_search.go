@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// errSearchStopped is returned by found to stop a search once its limit is reached
+var errSearchStopped = errors.New("search stopped")
+
+// searchLaptops finds the laptops of the store in the order and up to the limit of the options.
+// The store sorts the laptops itself when it can, otherwise they are collected and sorted here
+func searchLaptops(ctx context.Context, store LaptopStore, filter *pb.Filter, options SearchOptions, rates money.RateProvider, found func(*pb.Laptop) error) error {
+	if searcher, ok := store.(SortedLaptopSearcher); ok {
+		err := searcher.SearchSorted(ctx, filter, options, found)
+		if !errors.Is(err, ErrSortedSearchNotSupported) {
+			return err
+		}
+	}
+
+	return sortedSearch(ctx, store.Search, filter, options, rates, found)
+}
+
+// sortedSearch finds the laptops with search, the laptops have to be collected before they can be sorted
+func sortedSearch(ctx context.Context, search func(context.Context, *pb.Filter, func(*pb.Laptop) error) error, filter *pb.Filter,
+	options SearchOptions, rates money.RateProvider, found func(*pb.Laptop) error) error {
+	if options.Order == pb.SearchLaptopRequest_UNSORTED {
+		return limitSearch(options.Limit, found, func(found func(*pb.Laptop) error) error {
+			return search(ctx, filter, found)
+		})
+	}
+
+	laptops := []*pb.Laptop{}
+	err := search(ctx, filter, func(laptop *pb.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, currency := filterMaxPrice(filter)
+	sortByPrice(laptops, options.Order, currency, rates)
+
+	if options.Limit > 0 && len(laptops) > options.Limit {
+		laptops = laptops[:options.Limit]
+	}
+	for _, laptop := range laptops {
+		if err := found(laptop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitSearch stops the search once found is called with limit laptops, 0 never stops it
+func limitSearch(limit int, found func(*pb.Laptop) error, search func(found func(*pb.Laptop) error) error) error {
+	if limit == 0 {
+		return search(found)
+	}
+
+	count := 0
+	err := search(func(laptop *pb.Laptop) error {
+		if err := found(laptop); err != nil {
+			return err
+		}
+		count++
+		if count == limit {
+			return errSearchStopped
+		}
+		return nil
+	})
+	if errors.Is(err, errSearchStopped) {
+		return nil
+	}
+	return err
+}
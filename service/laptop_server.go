@@ -4,45 +4,58 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"strconv"
 	"time"
 
+	"github.com/daffarg/grpc-pcbook/money"
 	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/validator"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const maxImageSize = 1 << 20 // one megabyte
+const imageChunkSize = 64 << 10
+
+const (
+	minScore = 1.0
+	maxScore = 10.0
+)
 
 type LaptopServer struct {
 	pb.UnimplementedLaptopServiceServer
 	LaptopStore LaptopStore
 	ImageStore ImageStore
+	RatingStore RatingStore
+	Rates       money.RateProvider // converts the prices between currencies, nil if every price is in USD
 }
 
-func NewLaptopServer(laptopStore LaptopStore, imageStore ImageStore) *LaptopServer {
-	return &LaptopServer{LaptopStore: laptopStore, ImageStore: imageStore}
+func NewLaptopServer(laptopStore LaptopStore, imageStore ImageStore, ratingStore RatingStore) *LaptopServer {
+	return &LaptopServer{LaptopStore: laptopStore, ImageStore: imageStore, RatingStore: ratingStore}
 }
 
 func (server *LaptopServer) CreateLaptop(ctx context.Context, req *pb.CreateLaptopRequest) (*pb.CreateLaptopResponse, error) {
 	laptop := req.GetLaptop()
-	log.Printf("Receiving create laptop request with id : %s", laptop.Id)
+	logf(pb.LogLevel_INFO, "Receiving create laptop request with id : %s", laptop.GetId())
 
-	if (len(laptop.Id) > 0) { // laptop id provided by the client
-		_, err := uuid.Parse(laptop.Id) // check if laptop id valid
+	// the laptop may be missing, it has to be validated before any of its fields is used
+	if violations := validator.ValidateLaptop("laptop", laptop); violations != nil {
+		return nil, logError(badRequestStatus(ReasonInvalidLaptop, "Laptop is invalid", violations).Err())
+	}
 
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "Laptop ID is not a valid UUID : %v", err)
-		}
-	} else {
-		id, err := uuid.NewRandom()
-		
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "Failed to create new UUID for the laptop : %v", err)
-		} 	
-		laptop.Id = id.String() // set the laptop ID with new generated id
+	if err := assignLaptopId(laptop); err != nil {
+		return nil, err
+	}
+
+	if err := server.populatePrice(laptop); err != nil {
+		return nil, logError(err)
 	}
 
 	// pretending do heavy computation
@@ -54,17 +67,15 @@ func (server *LaptopServer) CreateLaptop(ctx context.Context, req *pb.CreateLapt
 	}
 
 	// save the laptop to in-memory store
+	_, span := tracer.Start(ctx, "LaptopStore.Save", trace.WithAttributes(attribute.String("laptop.id", laptop.Id)))
 	err := server.LaptopStore.Save(laptop)
+	endSpan(span, err)
 
 	if err != nil {
-		code := codes.Internal
-		if errors.Is(err, ErrAlreadyExists) {
-			code = codes.AlreadyExists
-		}
-		return nil, status.Errorf(code, "Failed to save new laptop : %v", err)
+		return nil, saveError(laptop.Id, err).Err()
 	}
 
-	log.Printf("Successfully saved new laptop with id : %s", laptop.Id)
+	logf(pb.LogLevel_INFO, "Successfully saved new laptop with id : %s", laptop.Id)
 	response := &pb.CreateLaptopResponse{
 		Id: laptop.Id,
 	}
@@ -75,51 +86,111 @@ func (server *LaptopServer) CreateLaptop(ctx context.Context, req *pb.CreateLapt
 func (server *LaptopServer) SearchLaptop(req *pb.SearchLaptopRequest, stream pb.LaptopService_SearchLaptopServer) error {
 	filter := req.GetFilter()
 
-	log.Printf("receive search laptop request with filter : %v", filter)
+	logf(pb.LogLevel_INFO, "receive search laptop request with filter : %v", filter)
 
-	err := server.LaptopStore.Search(
-		stream.Context(),
-		filter, 
-		func (laptop *pb.Laptop) error { // call back function: send laptop stream to client
-			log.Print(time.Now())
-			
-			res := &pb.SearchLaptopResponse{Laptop: laptop}
+	if violations := validator.ValidateFilter("filter", filter); violations != nil {
+		return logError(badRequestStatus(ReasonInvalidFilter, "Filter is invalid", violations).Err())
+	}
+
+	ctx, span := tracer.Start(stream.Context(), "LaptopStore.Search")
+
+	var sendErr error
+	send := func (laptop *pb.Laptop) error { // call back function: send laptop stream to client
+		logf(pb.LogLevel_DEBUG, "%v", time.Now())
 		
-			err := stream.Send(res)
+		res := &pb.SearchLaptopResponse{Laptop: laptop}
+	
+		_, sendSpan := tracer.Start(ctx, "SearchLaptop.Send", trace.WithAttributes(attribute.String("laptop.id", laptop.GetId())))
+		err := stream.Send(res)
+		endSpan(sendSpan, err)
 
-			if err != nil {
-				return err
-			}
+		if err != nil {
+			sendErr = err
+			return err
+		}
 
-			log.Printf("sent laptop with id : %s", laptop.GetId())
-			return nil
-		},
-	)
+		logf(pb.LogLevel_DEBUG, "sent laptop with id : %s", laptop.GetId())
+		return nil
+	}
+
+	// the store sorts and limits the laptops when it can, they are sent as soon as they are found
+	options := SearchOptions{Order: req.GetPriceOrder(), Limit: int(req.GetLimit())}
+	err := searchLaptops(ctx, server.LaptopStore, filter, options, server.Rates, send)
+	endSpan(span, err)
 
 	if err != nil {
-		return status.Errorf(codes.Internal, "unexpected error %v", err)
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+		if sendErr != nil {
+			return logError(streamError("cannot send laptop", sendErr))
+		}
+		return logError(storeError("cannot search laptops", err))
 	}
 
 	return nil
 }	
 
+func (server *LaptopServer) GetLaptop(ctx context.Context, req *pb.GetLaptopRequest) (*pb.GetLaptopResponse, error) {
+	laptopId := req.GetId()
+	logf(pb.LogLevel_INFO, "Receiving get laptop request with id : %s", laptopId)
+
+	_, err := uuid.Parse(laptopId)
+	if err != nil {
+		return nil, invalidLaptopIdError("id", "must be a valid UUID", err).Err()
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	_, span := tracer.Start(ctx, "LaptopStore.FindById", trace.WithAttributes(attribute.String("laptop.id", laptopId)))
+	laptop, err := server.LaptopStore.FindById(laptopId)
+	endSpan(span, err)
+	if err != nil {
+		return nil, logError(storeError(fmt.Sprintf("cannot find laptop with ID = %s", laptopId), err, laptopResource(laptopId, "")))
+	}
+	if laptop == nil {
+		return nil, newStatus(
+			codes.NotFound,
+			ReasonLaptopNotFound,
+			map[string]string{"laptop_id": laptopId},
+			fmt.Sprintf("laptop with ID = %s doesn't exists", laptopId),
+			laptopResource(laptopId, "laptop doesn't exist"),
+		).Err()
+	}
+
+	return &pb.GetLaptopResponse{Laptop: laptop}, nil
+}
+
 func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServer) error {
 	req, err := stream.Recv() // receive image info from client
 
 	if err != nil {
-		return logError(status.Errorf(codes.Unknown, "cannot receive image info"))
+		return logError(streamError("cannot receive image info", err))
 	}
 
 	laptopId := req.GetInfo().GetLaptopId()
 	imageType := req.GetInfo().GetImageType()
 
 	// check if laptop exists
+	_, span := tracer.Start(stream.Context(), "LaptopStore.FindById", trace.WithAttributes(attribute.String("laptop.id", laptopId)))
 	laptop, err := server.LaptopStore.FindById(laptopId)
+	endSpan(span, err)
 	if err != nil {
-		return logError(status.Errorf(codes.Internal, "cannot find laptop with ID = %s : %v", laptopId, err))
+		return logError(storeError(fmt.Sprintf("cannot find laptop with ID = %s", laptopId), err, laptopResource(laptopId, "")))
 	}
 	if laptop == nil { // laptop doesn't exists
-		return logError(status.Errorf(codes.InvalidArgument, "laptop with ID = %s doesn't exists", laptopId))
+		return logError(newStatus(
+			codes.InvalidArgument,
+			ReasonLaptopNotFound,
+			map[string]string{"laptop_id": laptopId},
+			fmt.Sprintf("laptop with ID = %s doesn't exists", laptopId),
+			&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "info.laptop_id", Description: "must be the ID of an existing laptop"},
+			}},
+			laptopResource(laptopId, "laptop doesn't exist"),
+		).Err())
 	}
 
 	// initialize empty buffer
@@ -128,19 +199,19 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 
 	for {
 		if err := contextError(stream.Context()); err != nil {
-			return nil
+			return err
 		}
 
-		log.Printf("waiting to receive more image data")
+		logf(pb.LogLevel_DEBUG, "waiting to receive more image data")
 
 		req, err := stream.Recv() 
 
 		if err == io.EOF {
-			log.Printf("no more image data")
+			logf(pb.LogLevel_DEBUG, "no more image data")
 			break
 		}
 		if err != nil {
-			return logError(status.Errorf(codes.Unknown, "cannot receive chunk data: %v", err))
+			return logError(streamError("cannot receive chunk data", err))
 		}
 
 		// time.Sleep(1 * time.Second)
@@ -148,24 +219,41 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 		chunk := req.GetChunkData() // get image chunk data
 		size := len(chunk)
 
-		log.Printf("received a chunk with size %d", size)
+		logf(pb.LogLevel_DEBUG, "received a chunk with size %d", size)
 
 		imageSize += size // increase total image size
 		if imageSize > maxImageSize {
-			return logError(status.Errorf(codes.InvalidArgument, "image size larger than maximum size : %d > %d", imageSize, maxImageSize))
+			return logError(newStatus(
+				codes.InvalidArgument,
+				ReasonImageTooLarge,
+				map[string]string{"size": strconv.Itoa(imageSize), "max_size": strconv.Itoa(maxImageSize)},
+				fmt.Sprintf("image size larger than maximum size : %d > %d", imageSize, maxImageSize),
+				&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+					{
+						Subject: fmt.Sprintf("laptop:%s", laptopId),
+						Description: fmt.Sprintf("image is at least %d bytes, over the limit of %d bytes by %d bytes", imageSize, maxImageSize, imageSize-maxImageSize),
+					},
+				}},
+			).Err())
 		}
 
 		_, err = imageData.Write(chunk) // write chunk data received from client to image data buffer
 		if err != nil {
-			return logError(status.Errorf(codes.Unknown, "cannot write chunk data into the buffer : %v", err))
+			return logError(newStatus(codes.Internal, ReasonStreamFailure, nil, fmt.Sprintf("cannot write chunk data into the buffer : %v", err)).Err())
 		}
 	}
 
 	// after successfully received all chunk data, store the image
+	_, span = tracer.Start(
+		stream.Context(),
+		"ImageStore.Save",
+		trace.WithAttributes(attribute.String("laptop.id", laptopId), attribute.Int("image.size", imageSize)),
+	)
 	imageId, err := server.ImageStore.Save(laptopId, imageType, imageData)
+	endSpan(span, err)
 	
 	if err != nil {
-		return logError(status.Errorf(codes.Unknown, "cannot write chunk data into the buffer : %v", err))
+		return logError(storeError("cannot save image to the store", err, laptopResource(laptopId, "")))
 	}
 
 	res := &pb.UploadImageResponse{
@@ -175,16 +263,548 @@ func (server *LaptopServer) UploadImage(stream pb.LaptopService_UploadImageServe
 
 	err = stream.SendAndClose(res)
 	if err != nil {
-		return logError(status.Errorf(codes.Unknown, "cannot send response : %v", err))
+		return logError(streamError("cannot send response", err))
+	}
+
+	logf(pb.LogLevel_INFO, "saved an image with id = %s and size = %d", imageId, imageSize)
+	return nil
+}
+
+func (server *LaptopServer) DownloadImage(req *pb.DownloadImageRequest, stream pb.LaptopService_DownloadImageServer) error {
+	imageId := req.GetImageId()
+	logf(pb.LogLevel_INFO, "receive download image request with id : %s", imageId)
+
+	reader, ok := server.ImageStore.(ImageReader)
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "the image store cannot read the saved images")
+	}
+
+	_, span := tracer.Start(stream.Context(), "ImageStore.Open", trace.WithAttributes(attribute.String("image.id", imageId)))
+	image, data, err := reader.Open(imageId)
+	endSpan(span, err)
+	if errors.Is(err, ErrNotFound) {
+		return newStatus(
+			codes.NotFound,
+			ReasonImageNotFound,
+			map[string]string{"image_id": imageId},
+			fmt.Sprintf("image with ID = %s doesn't exists", imageId),
+		).Err()
+	}
+	if err != nil {
+		return logError(storeError("cannot open image from the store", err))
+	}
+	defer data.Close()
+
+	err = stream.Send(&pb.DownloadImageResponse{
+		Data: &pb.DownloadImageResponse_Info{
+			Info: &pb.ImageInfo{LaptopId: image.LaptopID, ImageType: image.Type},
+		},
+	})
+	if err != nil {
+		return logError(streamError("cannot send image info", err))
+	}
+
+	buffer := make([]byte, imageChunkSize)
+	imageSize := 0
+
+	for {
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+
+		n, err := data.Read(buffer)
+		if n > 0 {
+			sendErr := stream.Send(&pb.DownloadImageResponse{
+				Data: &pb.DownloadImageResponse_ChunkData{ChunkData: buffer[:n]},
+			})
+			if sendErr != nil {
+				return logError(streamError("cannot send chunk data", sendErr))
+			}
+			imageSize += n
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logError(storeError("cannot read image from the store", err))
+		}
 	}
 
-	log.Printf("saved an image with id = %s and size = %d", imageId, imageSize)
+	logf(pb.LogLevel_INFO, "sent an image with id = %s and size = %d", imageId, imageSize)
 	return nil
 }
 
+func (server *LaptopServer) RateLaptop(stream pb.LaptopService_RateLaptopServer) error {
+	for {
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+
+		req, err := stream.Recv()
+		if err == io.EOF {
+			logf(pb.LogLevel_DEBUG, "no more rating data")
+			break
+		}
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot receive rating request : %v", err))
+		}
+
+		laptopId := req.GetLaptopId()
+		score := req.GetScore()
+
+		logf(pb.LogLevel_INFO, "received a rate laptop request: id = %s, score = %.2f", laptopId, score)
+
+		if score < minScore || score > maxScore {
+			return logError(status.Errorf(codes.InvalidArgument, "score must be between %.0f and %.0f : %v", minScore, maxScore, score))
+		}
+
+		// check if laptop exists
+		_, span := tracer.Start(stream.Context(), "LaptopStore.FindById", trace.WithAttributes(attribute.String("laptop.id", laptopId)))
+		laptop, err := server.LaptopStore.FindById(laptopId)
+		endSpan(span, err)
+		if err != nil {
+			return logError(status.Errorf(codes.Internal, "cannot find laptop with ID = %s : %v", laptopId, err))
+		}
+		if laptop == nil {
+			return logError(status.Errorf(codes.NotFound, "laptop with ID = %s doesn't exists", laptopId))
+		}
+
+		_, span = tracer.Start(stream.Context(), "RatingStore.Add", trace.WithAttributes(attribute.String("laptop.id", laptopId)))
+		rating, err := server.RatingStore.Add(laptopId, score)
+		endSpan(span, err)
+		if err != nil {
+			return logError(status.Errorf(codes.Internal, "cannot add rating to the store : %v", err))
+		}
+
+		res := &pb.RateLaptopResponse{
+			LaptopId: laptopId,
+			RatedCount: rating.Count,
+			AverageScore: rating.Sum / float64(rating.Count),
+		}
+
+		err = stream.Send(res)
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot send rating response : %v", err))
+		}
+	}
+
+	return nil
+}
+
+func (server *LaptopServer) UpdateLaptop(ctx context.Context, req *pb.UpdateLaptopRequest) (*pb.UpdateLaptopResponse, error) {
+	laptop := req.GetLaptop()
+	logf(pb.LogLevel_INFO, "Receiving update laptop request with id : %s", laptop.GetId())
+
+	_, err := uuid.Parse(laptop.GetId())
+	if err != nil {
+		return nil, invalidLaptopIdError("laptop.id", "must be a valid UUID", err).Err()
+	}
+
+	if violations := validator.ValidateLaptop("laptop", laptop); violations != nil {
+		return nil, logError(badRequestStatus(ReasonInvalidLaptop, "Laptop is invalid", violations).Err())
+	}
+
+	if err := server.populatePrice(laptop); err != nil {
+		return nil, logError(err)
+	}
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	_, span := tracer.Start(ctx, "LaptopStore.Update", trace.WithAttributes(attribute.String("laptop.id", laptop.Id)))
+	err = server.LaptopStore.Update(laptop)
+	endSpan(span, err)
+
+	if err != nil {
+		if st := notLeaderStatus(err); st != nil {
+			return nil, st.Err()
+		}
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "Failed to update laptop : %v", err)
+	}
+
+	logf(pb.LogLevel_INFO, "Successfully updated laptop with id : %s", laptop.Id)
+	return &pb.UpdateLaptopResponse{Id: laptop.Id}, nil
+}
+
+func (server *LaptopServer) DeleteLaptop(ctx context.Context, req *pb.DeleteLaptopRequest) (*pb.DeleteLaptopResponse, error) {
+	laptopId := req.GetId()
+	logf(pb.LogLevel_INFO, "Receiving delete laptop request with id : %s", laptopId)
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	// the images of the laptop are deleted with it
+	uow, err := NewUnitOfWork(server.LaptopStore, server.ImageStore)
+	if err != nil {
+		return nil, logError(storeError("cannot delete laptop", err))
+	}
+
+	_, span := tracer.Start(ctx, "UnitOfWork.DeleteLaptop", trace.WithAttributes(attribute.String("laptop.id", laptopId)))
+	images, err := uow.DeleteLaptop(laptopId)
+	if err == nil {
+		err = uow.Commit()
+	} else {
+		uow.Rollback()
+	}
+	endSpan(span, err)
+
+	if err != nil {
+		if st := notLeaderStatus(err); st != nil {
+			return nil, st.Err()
+		}
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "Failed to delete laptop : %v", err)
+	}
+
+	logf(pb.LogLevel_INFO, "Successfully deleted laptop with id : %s and its %d images", laptopId, images)
+	return &pb.DeleteLaptopResponse{}, nil
+}
+
+func (server *LaptopServer) WatchLaptops(req *pb.WatchLaptopsRequest, stream pb.LaptopService_WatchLaptopsServer) error {
+	filter := req.GetFilter()
+	logf(pb.LogLevel_INFO, "receive watch laptops request with filter : %v, resume token : %q", filter, req.GetResumeToken())
+
+	if violations := validator.ValidateFilter("filter", filter); violations != nil {
+		return logError(badRequestStatus(ReasonInvalidFilter, "Filter is invalid", violations).Err())
+	}
+
+	watcher, ok := server.LaptopStore.(LaptopWatcher)
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "the laptop store doesn't publish its changes")
+	}
+
+	subscription, err := watcher.Subscribe(req.GetResumeToken())
+	if err != nil {
+		code := codes.Internal
+		switch {
+		case errors.Is(err, ErrInvalidResumeToken):
+			code = codes.InvalidArgument
+		case errors.Is(err, ErrResumeTokenExpired):
+			code = codes.OutOfRange
+		}
+		return logError(status.Errorf(code, "cannot watch laptops : %v", err))
+	}
+	defer subscription.Close()
+
+	for {
+		event, err := subscription.Next(stream.Context())
+		if err != nil {
+			if errors.Is(err, ErrSubscriberTooSlow) {
+				return logError(status.Errorf(codes.ResourceExhausted, "cannot watch laptops : %v", err))
+			}
+			return contextError(stream.Context())
+		}
+
+		if filter != nil && !isQualified(filter, event.Laptop, server.Rates) && (event.Previous == nil || !isQualified(filter, event.Previous, server.Rates)) {
+			continue
+		}
+
+		res := &pb.WatchLaptopsResponse{Event: eventProto(event)}
+
+		err = stream.Send(res)
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot send laptop event : %v", err))
+		}
+
+		logf(pb.LogLevel_DEBUG, "sent %v event of laptop with id : %s", event.Type, event.Laptop.GetId())
+	}
+}
+
+func eventProto(event *LaptopEvent) *pb.LaptopEvent {
+	return &pb.LaptopEvent{
+		Type:        event.Type,
+		Laptop:      event.Laptop,
+		ResumeToken: event.ResumeToken(),
+		Time:        timestamppb.New(event.Time),
+	}
+}
+
+func (server *LaptopServer) BulkCreateLaptops(stream pb.LaptopService_BulkCreateLaptopsServer) error {
+	ctx := stream.Context()
+	options := &pb.BulkCreateOptions{}
+	receivedOptions := false
+	results := []*pb.BulkCreateResult{}
+	batch := []*bulkItem{}
+	var uow *UnitOfWork // the single unit of work of an atomic bulk creation
+
+	defer func() {
+		if uow != nil {
+			uow.Rollback()
+		}
+	}()
+
+	for {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+
+		req, err := stream.Recv()
+		if err == io.EOF {
+			logf(pb.LogLevel_DEBUG, "no more laptops to create")
+			break
+		}
+		if err != nil {
+			return logError(status.Errorf(codes.Unknown, "cannot receive bulk create request : %v", err))
+		}
+
+		switch data := req.GetData().(type) {
+		case *pb.BulkCreateLaptopsRequest_Options:
+			if receivedOptions || len(results) > 0 {
+				return logError(status.Errorf(codes.InvalidArgument, "bulk create options must be sent once, before the laptops"))
+			}
+			receivedOptions = true
+			options = data.Options
+			logf(pb.LogLevel_INFO, "receive bulk create laptops request with options : %v", options)
+
+			// the laptops saved without a transaction would be visible before the creation fails and is undone
+			if options.GetAtomic() {
+				uow, err = NewUnitOfWork(server.LaptopStore, server.ImageStore)
+				if err != nil {
+					return logError(storeError("cannot begin atomic bulk creation", err))
+				}
+
+				err = uow.RequireLaptopTx()
+				if errors.Is(err, ErrTxNotSupported) {
+					return logError(status.Errorf(codes.FailedPrecondition, "atomic bulk creation is not supported by the laptop store"))
+				}
+				if err != nil {
+					return logError(storeError("cannot begin atomic bulk creation", err))
+				}
+			}
+		case *pb.BulkCreateLaptopsRequest_Laptop:
+			laptop := data.Laptop
+			result := &pb.BulkCreateResult{Index: uint32(len(results))}
+			results = append(results, result)
+
+			if violations := validator.ValidateLaptop("laptop", laptop); violations != nil {
+				result.Id = laptop.GetId()
+				result.Status = badRequestStatus(ReasonInvalidLaptop, "Laptop is invalid", violations).Proto()
+				continue
+			}
+
+			if err := assignLaptopId(laptop); err != nil {
+				result.Status = status.Convert(err).Proto()
+				continue
+			}
+			result.Id = laptop.Id
+
+			if err := server.populatePrice(laptop); err != nil {
+				result.Status = status.Convert(err).Proto()
+				continue
+			}
+
+			batch = append(batch, &bulkItem{laptop: laptop, result: result})
+			if len(batch) >= bulkBatchSize(options) {
+				server.saveLaptopBatch(ctx, uow, batch)
+				batch = []*bulkItem{}
+			}
+		default:
+			return logError(status.Errorf(codes.InvalidArgument, "bulk create request has neither options nor laptop"))
+		}
+	}
+
+	server.saveLaptopBatch(ctx, uow, batch)
+
+	if uow != nil {
+		commitBulkUnitOfWork(ctx, uow, results)
+		uow = nil
+	}
+
+	res := &pb.BulkCreateLaptopsResponse{Results: results}
+	for _, result := range results {
+		if codes.Code(result.GetStatus().GetCode()) == codes.OK {
+			res.CreatedCount++
+		} else {
+			res.FailedCount++
+		}
+	}
+
+	err := stream.SendAndClose(res)
+	if err != nil {
+		return logError(status.Errorf(codes.Unknown, "cannot send bulk create response : %v", err))
+	}
+
+	logf(pb.LogLevel_INFO, "bulk created %d laptops, %d failed", res.CreatedCount, res.FailedCount)
+	return nil
+}
+
+const defaultBulkBatchSize = 100
+
+// bulkItem is a laptop of a bulk creation waiting to be saved
+type bulkItem struct {
+	laptop *pb.Laptop
+	result *pb.BulkCreateResult
+}
+
+func bulkBatchSize(options *pb.BulkCreateOptions) int {
+	if options.GetBatchSize() == 0 {
+		return defaultBulkBatchSize
+	}
+	return int(options.GetBatchSize())
+}
+
+// beginLaptopTx begins a transaction of the laptop store, ErrTxNotSupported if it has none
+func (server *LaptopServer) beginLaptopTx() (LaptopTx, error) {
+	transactional, ok := server.LaptopStore.(TransactionalLaptopStore)
+	if !ok {
+		return nil, ErrTxNotSupported
+	}
+	return transactional.Begin()
+}
+
+// saveLaptopBatch saves the batch into the unit of work of an atomic creation if there is one, otherwise the batch
+// is saved in its own transaction when the store supports it, falling back to saving the laptops one by one
+func (server *LaptopServer) saveLaptopBatch(ctx context.Context, uow *UnitOfWork, batch []*bulkItem) {
+	if len(batch) == 0 {
+		return
+	}
+
+	_, span := tracer.Start(ctx, "LaptopStore.SaveBatch", trace.WithAttributes(attribute.Int("batch.size", len(batch))))
+	defer span.End()
+
+	if uow != nil {
+		for _, item := range batch {
+			if err := uow.SaveLaptop(item.laptop); err != nil {
+				item.result.Status = saveError(item.laptop.Id, err).Proto()
+			}
+		}
+		return
+	}
+
+	if batchTx, err := server.beginLaptopTx(); err == nil {
+		pending := []*bulkItem{}
+		for _, item := range batch {
+			if err := batchTx.Save(item.laptop); err != nil {
+				item.result.Status = saveError(item.laptop.Id, err).Proto()
+				continue
+			}
+			pending = append(pending, item)
+		}
+
+		err = batchTx.Commit()
+		if err == nil {
+			for _, item := range pending {
+				item.result.Status = status.New(codes.OK, "").Proto()
+			}
+			return
+		}
+
+		// a laptop of the batch was saved concurrently, save the rest one by one
+		logf(pb.LogLevel_WARN, "cannot commit laptop batch, saving the laptops one by one : %v", err)
+		batch = pending
+	}
+
+	for _, item := range batch {
+		err := server.LaptopStore.Save(item.laptop)
+		if err != nil {
+			item.result.Status = saveError(item.laptop.Id, err).Proto()
+		} else {
+			item.result.Status = status.New(codes.OK, "").Proto()
+		}
+	}
+}
+
+// commitBulkUnitOfWork commits the atomic creation only if every laptop was accepted, the results of the
+// accepted laptops become Aborted otherwise
+func commitBulkUnitOfWork(ctx context.Context, uow *UnitOfWork, results []*pb.BulkCreateResult) {
+	failed := false
+	for _, result := range results {
+		if result.GetStatus() != nil {
+			failed = true
+			break
+		}
+	}
+
+	var err error
+	if failed {
+		uow.Rollback()
+	} else {
+		_, span := tracer.Start(ctx, "UnitOfWork.Commit")
+		err = uow.Commit()
+		endSpan(span, err)
+	}
+
+	for _, result := range results {
+		if result.GetStatus() != nil {
+			continue
+		}
+
+		switch {
+		case failed:
+			result.Status = status.New(codes.Aborted, "laptop is not created because another laptop of the atomic bulk creation failed").Proto()
+		case err != nil:
+			result.Status = saveError(result.GetId(), err).Proto()
+		default:
+			result.Status = status.New(codes.OK, "").Proto()
+		}
+	}
+}
+
+// assignLaptopId checks the laptop id provided by the client, or generates a new one if it is empty
+func assignLaptopId(laptop *pb.Laptop) error {
+	if (len(laptop.Id) > 0) { // laptop id provided by the client
+		_, err := uuid.Parse(laptop.Id) // check if laptop id valid
+
+		if err != nil {
+			return invalidLaptopIdError("laptop.id", "must be a valid UUID or empty", err).Err()
+		}
+	} else {
+		id, err := uuid.NewRandom()
+		
+		if err != nil {
+			return newStatus(codes.Internal, ReasonIdGenerationFailed, nil, fmt.Sprintf("Failed to create new UUID for the laptop : %v", err)).Err()
+		} 	
+		laptop.Id = id.String() // set the laptop ID with new generated id
+	}
+
+	return nil
+}
+
+func invalidLaptopIdError(field string, description string, err error) *status.Status {
+	return newStatus(
+		codes.InvalidArgument,
+		ReasonInvalidLaptopId,
+		nil,
+		fmt.Sprintf("Laptop ID is not a valid UUID : %v", err),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: description},
+		}},
+	)
+}
+
+func saveError(laptopId string, err error) *status.Status {
+	if st := notLeaderStatus(err); st != nil {
+		return st
+	}
+
+	message := fmt.Sprintf("Failed to save new laptop : %v", err)
+
+	if errors.Is(err, ErrAlreadyExists) {
+		return newStatus(
+			codes.AlreadyExists,
+			ReasonLaptopAlreadyExists,
+			map[string]string{"laptop_id": laptopId},
+			message,
+			laptopResource(laptopId, "laptop already exists"),
+		)
+	}
+
+	return newStatus(codes.Internal, ReasonStoreFailure, nil, message, laptopResource(laptopId, ""))
+}
+
 func logError(err error) error {
 	if err != nil {
-		log.Print(err)
+		logf(pb.LogLevel_ERROR, "%v", err)
 	}
 	return err
 }
@@ -193,11 +813,11 @@ func contextError(ctx context.Context) error {
 	// check if the context is cancelled
 	switch ctx.Err() {
 		case context.Canceled:
-			log.Print("Request is canceled")
-			return logError(status.Error(codes.Canceled, "request is cancelled"))
+			logf(pb.LogLevel_WARN, "Request is canceled")
+			return logError(newStatus(codes.Canceled, ReasonRequestCancelled, nil, "request is cancelled").Err())
 		case context.DeadlineExceeded:
-			log.Print("deadline is exceeded")
-			return logError(status.Error(codes.DeadlineExceeded, "deadline is exceeded"))
+			logf(pb.LogLevel_WARN, "deadline is exceeded")
+			return logError(newStatus(codes.DeadlineExceeded, ReasonDeadlineExceeded, nil, "deadline is exceeded").Err())
 		default:
 			return nil
 		}
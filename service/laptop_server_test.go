@@ -8,6 +8,7 @@ import (
 	"github.com/daffarg/grpc-pcbook/sample"
 	"github.com/daffarg/grpc-pcbook/service"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -20,6 +21,9 @@ func TestServerCreateLaptop(t *testing.T) {
 	laptopInvalidID := sample.NewLaptop()
 	laptopInvalidID.Id = "invalid-uuid"
 
+	laptopInvalid := sample.NewLaptop()
+	laptopInvalid.PriceUsd = -1
+
 	laptopDuplicateID := sample.NewLaptop()
 	storeDuplicateID := service.NewInMemoryLaptopStore()
 	storeDuplicateID.Save(laptopDuplicateID)
@@ -52,6 +56,13 @@ func TestServerCreateLaptop(t *testing.T) {
 			imageStore: nil,
 			code: codes.InvalidArgument,
 		},
+		{
+			name: "failure_invalid_laptop",
+			laptop: laptopInvalid,
+			laptopStore: service.NewInMemoryLaptopStore(),
+			imageStore: nil,
+			code: codes.InvalidArgument,
+		},
 		{
 			name: "failure_duplicate_id",
 			laptop: laptopDuplicateID,
@@ -68,7 +79,7 @@ func TestServerCreateLaptop(t *testing.T) {
 				Laptop: test.laptop,
 			}
 
-			server := service.NewLaptopServer(test.laptopStore, test.imageStore)
+			server := service.NewLaptopServer(test.laptopStore, test.imageStore, nil)
 			res, err := server.CreateLaptop(context.Background(), req)
 
 			if test.code == codes.OK {
@@ -88,4 +99,100 @@ func TestServerCreateLaptop(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestServerCreateLaptopBadRequest(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	laptop.PriceUsd = -1
+	laptop.Cpu.NumberCores = 0
+	laptop.Ram.Unit = pb.Memory_UNKNOWN
+
+	server := service.NewLaptopServer(service.NewInMemoryLaptopStore(), nil, nil)
+	_, err := server.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+
+	errorInfo, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, service.ReasonInvalidLaptop, errorInfo.GetReason())
+	require.Equal(t, service.ErrorDomain, errorInfo.GetDomain())
+
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+
+	fields := []string{}
+	for _, violation := range badRequest.GetFieldViolations() {
+		fields = append(fields, violation.GetField())
+	}
+	require.ElementsMatch(t, []string{"laptop.price_usd", "laptop.cpu.number_cores", "laptop.ram.unit"}, fields)
+}
+
+func TestServerCreateLaptopMissing(t *testing.T) {
+	t.Parallel()
+
+	server := service.NewLaptopServer(service.NewInMemoryLaptopStore(), nil, nil)
+	_, err := server.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{})
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	require.Equal(t, "laptop", badRequest.GetFieldViolations()[0].GetField())
+	require.Equal(t, "is required", badRequest.GetFieldViolations()[0].GetDescription())
+}
+
+func TestServerUpdateAndDeleteLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptop := sample.NewLaptop()
+	store := service.NewInMemoryLaptopStore()
+	require.NoError(t, store.Save(laptop))
+
+	server := service.NewLaptopServer(store, nil, nil)
+
+	laptop.PriceUsd = 1234
+	updateRes, err := server.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+	require.Equal(t, laptop.Id, updateRes.Id)
+
+	updated, err := store.FindById(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1234.0, updated.PriceUsd)
+
+	_, err = server.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	invalidLaptop := sample.NewLaptop()
+	invalidLaptop.Id = "invalid-uuid"
+	_, err = server.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: invalidLaptop})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// unlike a created laptop, an updated one needs an ID
+	invalidLaptop.Id = ""
+	_, err = server.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: invalidLaptop})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Equal(t, "laptop.id", badRequest.GetFieldViolations()[0].GetField())
+	require.Equal(t, "must be a valid UUID", badRequest.GetFieldViolations()[0].GetDescription())
+
+	_, err = server.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: laptop.Id})
+	require.NoError(t, err)
+
+	deleted, err := store.FindById(laptop.Id)
+	require.NoError(t, err)
+	require.Nil(t, deleted)
+
+	_, err = server.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: laptop.Id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -4,37 +4,100 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 
+	"github.com/daffarg/grpc-pcbook/memunit"
+	"github.com/daffarg/grpc-pcbook/money"
 	"github.com/daffarg/grpc-pcbook/pb"
 	"github.com/jinzhu/copier"
+	"google.golang.org/protobuf/proto"
 )
 
 var ErrAlreadyExists = errors.New("record already exists")
+var ErrNotFound = errors.New("record not found")
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+var ErrTxNotSupported = errors.New("store doesn't support transactions")
+var ErrListNotSupported = errors.New("store cannot list its laptops")
+var ErrSortedSearchNotSupported = errors.New("store cannot sort the laptops it finds")
+
+// NotLeaderError is returned by the writes to a store which only replicates the laptops of another server
+type NotLeaderError struct {
+	Leader string // the internal address of the server accepting the writes, empty if it is unknown
+}
+
+func (err *NotLeaderError) Error() string {
+	if err.Leader == "" {
+		return "store is not the leader and the leader is unknown"
+	}
+	return fmt.Sprintf("store is not the leader, the writes go to %s", err.Leader)
+}
 
 type LaptopStore interface {
 	Save(laptop *pb.Laptop) error
+	Update(laptop *pb.Laptop) error
+	Delete(laptopId string) error
 	FindById(laptopId string) (*pb.Laptop, error)
 	Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error)  error // param2: callback function
 }
 
+// LaptopLister is implemented by stores that can go through every laptop, whatever its price
+type LaptopLister interface {
+	List(found func(*pb.Laptop) error) error
+}
+
+// LaptopTx collects laptops that become visible in the store only once committed,
+// Rollback discards them and does nothing after Commit
+type LaptopTx interface {
+	Save(laptop *pb.Laptop) error
+	Commit() error
+	Rollback()
+}
+
+// TransactionalLaptopStore is implemented by stores that can save several laptops atomically
+type TransactionalLaptopStore interface {
+	Begin() (LaptopTx, error)
+}
+
+// SearchOptions sorts and limits the laptops found by a search
+type SearchOptions struct {
+	Order pb.SearchLaptopRequest_PriceOrder // the prices are compared in the currency of the filter
+	Limit int                               // the maximum number of laptops found, 0 finds every laptop
+}
+
+// SortedLaptopSearcher is implemented by stores that can find the laptops in the order of their price,
+// and stop searching once the limit is reached. ErrSortedSearchNotSupported is returned before any laptop is found
+// by the stores wrapping a store that cannot do it
+type SortedLaptopSearcher interface {
+	SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error
+}
+
+// InMemoryLaptopStore keeps the laptops in immutable versions: a write builds a new version under the mutex
+// and replaces the current one, the reads go through the version current when they start without any lock.
+// A search sees the laptops as they were when it started, and never delays the writes however slow its callback is
 type InMemoryLaptopStore struct {
-	Mutex sync.RWMutex
-	Data  map[string]*pb.Laptop
+	mutex   sync.Mutex   // serializes the writes
+	version atomic.Value // *laptopVersion
+	Rates money.RateProvider // converts the prices into the currency of the filter, nil if every price is in USD
 }
 
 func NewInMemoryLaptopStore() *InMemoryLaptopStore {
-	return &InMemoryLaptopStore{
-		Data: make(map[string]*pb.Laptop),
-	}
+	store := &InMemoryLaptopStore{}
+	store.version.Store(&laptopVersion{})
+	return store
+}
+
+func (store *InMemoryLaptopStore) current() *laptopVersion {
+	return store.version.Load().(*laptopVersion)
 }
 
 func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
-	store.Mutex.Lock()
-	defer store.Mutex.Unlock()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
-	if store.Data[laptop.Id] != nil {
+	version := store.current()
+	if version.get(laptop.Id) != nil {
 		return ErrAlreadyExists
 	}
 
@@ -44,15 +107,50 @@ func (store *InMemoryLaptopStore) Save(laptop *pb.Laptop) error {
 		return err
 	}
 
-	store.Data[laptop.Id] = other
+	edit := version.edit()
+	edit.put(other)
+	store.version.Store(edit.version)
 	return nil
 }
 
-func (store *InMemoryLaptopStore) FindById(laptopId string) (*pb.Laptop, error) {
-	store.Mutex.RLock()
-	defer store.Mutex.RUnlock()
+func (store *InMemoryLaptopStore) Update(laptop *pb.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	version := store.current()
+	if version.get(laptop.Id) == nil {
+		return ErrNotFound
+	}
+
+	other, err := deepCopy(laptop)
+
+	if err != nil {
+		return err
+	}
+
+	edit := version.edit()
+	edit.put(other)
+	store.version.Store(edit.version)
+	return nil
+}
+
+func (store *InMemoryLaptopStore) Delete(laptopId string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	version := store.current()
+	if version.get(laptopId) == nil {
+		return ErrNotFound
+	}
 
-	laptop := store.Data[laptopId]
+	edit := version.edit()
+	edit.remove(laptopId)
+	store.version.Store(edit.version)
+	return nil
+}
+
+func (store *InMemoryLaptopStore) FindById(laptopId string) (*pb.Laptop, error) {
+	laptop := store.current().get(laptopId)
 	if laptop == nil {
 		return nil, nil
 	}
@@ -61,36 +159,210 @@ func (store *InMemoryLaptopStore) FindById(laptopId string) (*pb.Laptop, error)
 }
 
 func (store *InMemoryLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
-	store.Mutex.RLock()
-	defer store.Mutex.RLocker().Unlock()
-
-	for _, laptop := range store.Data {
-		log.Print("checking laptop id: ", laptop.Id)
+	return store.current().each(func(laptop *pb.Laptop) error {
+		logf(pb.LogLevel_DEBUG, "checking laptop id: %s", laptop.Id)
 
 		if ctx.Err() == context.Canceled || ctx.Err() == context.DeadlineExceeded {
-			log.Print("context is cancelled")
+			logf(pb.LogLevel_WARN, "context is cancelled")
 			return errors.New("context is cancelled")
 		}
 
-		if isQualified(filter, laptop) {
-			fmt.Println("QUALIFIED")
-			other, err := deepCopy(laptop)
-			if err != nil {
-				return err
-			}
-			
-			err = found(other)
+		if !isQualified(filter, laptop, store.Rates) {
+			return nil
+		}
+
+		logf(pb.LogLevel_DEBUG, "laptop %s is qualified", laptop.Id)
+		_, span := tracer.Start(ctx, "LaptopStore.deepCopy")
+		other, err := deepCopy(laptop)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		return found(other)
+	})
+}
+
+func (store *InMemoryLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	return sortedSearch(ctx, store.Search, filter, options, store.Rates, found)
+}
+
+func (store *InMemoryLaptopStore) List(found func(*pb.Laptop) error) error {
+	return store.current().each(func(laptop *pb.Laptop) error {
+		other, err := deepCopy(laptop)
+		if err != nil {
+			return err
+		}
+
+		return found(other)
+	})
+}
+
+// Len returns how many laptops the store has
+func (store *InMemoryLaptopStore) Len() int {
+	return store.current().count
+}
+
+func (store *InMemoryLaptopStore) Begin() (LaptopTx, error) {
+	return &inMemoryLaptopTx{store: store, ids: make(map[string]bool)}, nil
+}
+
+// inMemoryLaptopTx buffers the saved laptops and inserts all of them in a single version on commit
+type inMemoryLaptopTx struct {
+	store   *InMemoryLaptopStore
+	laptops []*pb.Laptop
+	ids     map[string]bool
+	done    bool
+}
+
+func (tx *inMemoryLaptopTx) Save(laptop *pb.Laptop) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	exists := tx.store.current().get(laptop.Id) != nil
+
+	if exists || tx.ids[laptop.Id] {
+		return ErrAlreadyExists
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	tx.ids[laptop.Id] = true
+	tx.laptops = append(tx.laptops, other)
+	return nil
+}
+
+func (tx *inMemoryLaptopTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	tx.store.mutex.Lock()
+	defer tx.store.mutex.Unlock()
+
+	// another writer may have saved one of the laptops since it was added to the transaction
+	version := tx.store.current()
+	for _, laptop := range tx.laptops {
+		if version.get(laptop.Id) != nil {
+			return ErrAlreadyExists
+		}
+	}
+
+	edit := version.edit()
+	for _, laptop := range tx.laptops {
+		edit.put(laptop)
+	}
+	tx.store.version.Store(edit.version)
+
+	return nil
+}
+
+func (tx *inMemoryLaptopTx) Rollback() {
+	tx.done = true
+	tx.laptops = nil
+}
+
+// Ready always returns nil, the in-memory store is ready as soon as it is created
+func (store *InMemoryLaptopStore) Ready() error {
+	return nil
+}
+
+func (store *InMemoryLaptopStore) Stats() (StoreStats, error) {
+	version := store.current()
+
+	stats := StoreStats{Count: uint64(version.count)}
+	version.each(func(laptop *pb.Laptop) error {
+		stats.Bytes += uint64(proto.Size(laptop))
+		return nil
+	})
+
+	return stats, nil
+}
+
+const laptopBuckets = 256
+
+// laptopVersion is an immutable set of laptops. The laptops are spread over buckets by the hash of their ID,
+// so that a new version copies the array of buckets and the buckets it changes, and shares the other ones
+type laptopVersion struct {
+	buckets [laptopBuckets]map[string]*pb.Laptop
+	count   int
+}
+
+func laptopBucket(laptopId string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(laptopId))
+	return int(hash.Sum32() % laptopBuckets)
+}
+
+func (version *laptopVersion) get(laptopId string) *pb.Laptop {
+	return version.buckets[laptopBucket(laptopId)][laptopId]
+}
+
+// each calls found with the laptops of the version until it returns an error, the laptops must not be changed
+func (version *laptopVersion) each(found func(*pb.Laptop) error) error {
+	for _, bucket := range version.buckets {
+		for _, laptop := range bucket {
+			err := found(laptop)
 			if err != nil {
 				return err
 			}
 		}
 	}
-
 	return nil
 }
 
-func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
-	if laptop.GetPriceUsd() > filter.GetMaxPriceUsd() {
+// edit starts a new version from this one
+func (version *laptopVersion) edit() *laptopEdit {
+	return &laptopEdit{
+		version: &laptopVersion{buckets: version.buckets, count: version.count},
+		copied:  make(map[int]bool),
+	}
+}
+
+// laptopEdit builds a new version, the buckets are copied the first time they are changed
+type laptopEdit struct {
+	version *laptopVersion
+	copied  map[int]bool
+}
+
+func (edit *laptopEdit) bucket(laptopId string) map[string]*pb.Laptop {
+	i := laptopBucket(laptopId)
+	if !edit.copied[i] {
+		bucket := make(map[string]*pb.Laptop, len(edit.version.buckets[i])+1)
+		for id, laptop := range edit.version.buckets[i] {
+			bucket[id] = laptop
+		}
+		edit.version.buckets[i] = bucket
+		edit.copied[i] = true
+	}
+	return edit.version.buckets[i]
+}
+
+func (edit *laptopEdit) put(laptop *pb.Laptop) {
+	bucket := edit.bucket(laptop.Id)
+	if bucket[laptop.Id] == nil {
+		edit.version.count++
+	}
+	bucket[laptop.Id] = laptop
+}
+
+func (edit *laptopEdit) remove(laptopId string) {
+	bucket := edit.bucket(laptopId)
+	if bucket[laptopId] != nil {
+		edit.version.count--
+	}
+	delete(bucket, laptopId)
+}
+
+func isQualified(filter *pb.Filter, laptop *pb.Laptop, rates money.RateProvider) bool {
+	// a price which cannot be converted into the currency of the filter doesn't qualify
+	maxPrice, currency := filterMaxPrice(filter)
+	if price, err := laptopPrice(laptop, currency, rates); err != nil || price > maxPrice {
 		return false
 	}
 
@@ -102,34 +374,14 @@ func isQualified(filter *pb.Filter, laptop *pb.Laptop) bool {
 		return false
 	}	
 
-	if toBit(laptop.GetRam()) < toBit(filter.GetMinRam()) {
+	// a ram which cannot be compared doesn't qualify
+	if cmp, err := memunit.Compare(laptop.GetRam(), filter.GetMinRam()); err != nil || cmp < 0 {
 		return false
 	}
 
 	return true
 }
 
-func toBit(memory *pb.Memory) uint64 {
-	value := memory.GetValue()
-
-	switch memory.GetUnit() {
-		case pb.Memory_BIT:
-			return value
-		case pb.Memory_BYTE:
-			return value << 3
-		case pb.Memory_KILOBYTE:
-			return value << 13
-		case pb.Memory_MEGABYTE:
-			return value << 13
-		case pb.Memory_GIGABYTE:
-			return value << 23
-		case pb.Memory_TERABYTE:
-			return value << 33
-		default:
-			return 0
-	}
-}
-
 func deepCopy(laptop *pb.Laptop) (*pb.Laptop, error) {
 	other := &pb.Laptop{}
 	err := copier.Copy(other, laptop)
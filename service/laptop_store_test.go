@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInMemoryLaptopStoreSearchSnapshot(t *testing.T) {
+	t.Parallel()
+
+	store := service.NewInMemoryLaptopStore()
+
+	laptops := []*pb.Laptop{}
+	for i := 0; i < 10; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+
+	filter := &pb.Filter{MaxPriceUsd: math.MaxFloat64}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	found := map[string]bool{}
+
+	go func() {
+		done <- store.Search(context.Background(), filter, func(laptop *pb.Laptop) error {
+			if len(found) == 0 {
+				close(started)
+				<-release
+			}
+			found[laptop.Id] = true
+			return nil
+		})
+	}()
+
+	<-started
+
+	// the writes must not wait for the blocked search
+	written := make(chan error, 3)
+	saved := sample.NewLaptop()
+	go func() {
+		written <- store.Save(saved)
+		written <- store.Delete(laptops[0].Id)
+
+		updated := proto.Clone(laptops[1]).(*pb.Laptop)
+		updated.Brand = "updated"
+		written <- store.Update(updated)
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-written:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			close(release)
+			t.Fatal("writes are blocked by the search")
+		}
+	}
+
+	other, err := store.FindById(laptops[1].Id)
+	require.NoError(t, err)
+	require.Equal(t, "updated", other.Brand)
+	require.Equal(t, 10, store.Len())
+
+	close(release)
+	require.NoError(t, <-done)
+
+	// the search sees the laptops as they were when it started
+	require.Len(t, found, 10)
+	require.False(t, found[saved.Id])
+	for _, laptop := range laptops {
+		require.True(t, found[laptop.Id])
+	}
+}
+
+func TestInMemoryLaptopStoreTx(t *testing.T) {
+	t.Parallel()
+
+	store := service.NewInMemoryLaptopStore()
+	existing := sample.NewLaptop()
+	require.NoError(t, store.Save(existing))
+
+	tx, err := store.Begin()
+	require.NoError(t, err)
+	require.ErrorIs(t, tx.Save(existing), service.ErrAlreadyExists)
+
+	laptops := []*pb.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+	for _, laptop := range laptops {
+		require.NoError(t, tx.Save(laptop))
+	}
+	require.Equal(t, 1, store.Len())
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t, 4, store.Len())
+
+	for _, laptop := range laptops {
+		other, err := store.FindById(laptop.Id)
+		require.NoError(t, err)
+		require.Equal(t, laptop.Id, other.Id)
+	}
+}
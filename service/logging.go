@@ -0,0 +1,27 @@
+package service
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+var logLevel = int32(pb.LogLevel_INFO)
+
+// SetLogLevel changes the level of the service logs at runtime and returns the previous level
+func SetLogLevel(level pb.LogLevel) pb.LogLevel {
+	return pb.LogLevel(atomic.SwapInt32(&logLevel, int32(level)))
+}
+
+func CurrentLogLevel() pb.LogLevel {
+	return pb.LogLevel(atomic.LoadInt32(&logLevel))
+}
+
+// logf prints the log only if its level is at least the current log level
+func logf(level pb.LogLevel, format string, args ...interface{}) {
+	if level < CurrentLogLevel() {
+		return
+	}
+	log.Printf(level.String()+" "+format, args...)
+}
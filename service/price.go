@@ -0,0 +1,91 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// laptopPrice returns the laptop price in the currency, the laptops saved before the currencies only have price_usd
+func laptopPrice(laptop *pb.Laptop, currency string, rates money.RateProvider) (float64, error) {
+	price := laptop.GetPrice()
+
+	// price_usd is the exact price of the laptops priced in USD
+	if price == nil || price.GetCurrencyCode() == money.USD {
+		if currency == money.USD {
+			return laptop.GetPriceUsd(), nil
+		}
+		price = money.FromFloat(money.USD, laptop.GetPriceUsd())
+	}
+
+	return money.Amount(price, currency, rates)
+}
+
+// filterMaxPrice returns the max price of the filter, falling back to max_price_usd
+func filterMaxPrice(filter *pb.Filter) (float64, string) {
+	if filter.GetMaxPrice() == nil {
+		return filter.GetMaxPriceUsd(), money.USD
+	}
+	return money.ToFloat(filter.GetMaxPrice()), filter.GetMaxPrice().GetCurrencyCode()
+}
+
+// populatePrice fills the price from price_usd for the old clients, and price_usd from the price for the others.
+// For a USD price, price_usd wins when both are set so the old clients can still change the price of a laptop
+func (server *LaptopServer) populatePrice(laptop *pb.Laptop) error {
+	price := laptop.GetPrice()
+	if price == nil || (price.GetCurrencyCode() == money.USD && laptop.GetPriceUsd() != 0) {
+		laptop.Price = money.FromFloat(money.USD, laptop.GetPriceUsd())
+		return nil
+	}
+
+	priceUsd, err := money.Amount(laptop.GetPrice(), money.USD, server.Rates)
+	if err != nil {
+		return badRequestStatus(ReasonInvalidLaptop, "Laptop price cannot be converted to USD", []*errdetails.BadRequest_FieldViolation{
+			{Field: "laptop.price.currency_code", Description: "must be a currency with an exchange rate to USD"},
+		}).Err()
+	}
+
+	laptop.PriceUsd = priceUsd
+	return nil
+}
+
+// pricedLaptop is a laptop with its price in the currency of a search
+type pricedLaptop struct {
+	laptop *pb.Laptop
+	price  float64
+	ok     bool // false if the price cannot be converted into the currency
+}
+
+func newPricedLaptop(laptop *pb.Laptop, currency string, rates money.RateProvider) pricedLaptop {
+	price, err := laptopPrice(laptop, currency, rates)
+	return pricedLaptop{laptop: laptop, price: price, ok: err == nil}
+}
+
+// before reports whether the laptop comes before the other one in the order, the laptops without price come last
+func (laptop pricedLaptop) before(other pricedLaptop, order pb.SearchLaptopRequest_PriceOrder) bool {
+	if laptop.ok != other.ok {
+		return laptop.ok
+	}
+	if order == pb.SearchLaptopRequest_DESCENDING {
+		return laptop.price > other.price
+	}
+	return laptop.price < other.price
+}
+
+// sortByPrice sorts the laptops by their price in the currency, the laptops whose price cannot be converted come last
+func sortByPrice(laptops []*pb.Laptop, order pb.SearchLaptopRequest_PriceOrder, currency string, rates money.RateProvider) {
+	priced := make([]pricedLaptop, len(laptops))
+	for i, laptop := range laptops {
+		priced[i] = newPricedLaptop(laptop, currency, rates)
+	}
+
+	sort.SliceStable(priced, func(i, j int) bool {
+		return priced[i].before(priced[j], order)
+	})
+
+	for i := range priced {
+		laptops[i] = priced[i].laptop
+	}
+}
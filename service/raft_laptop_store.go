@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/hashicorp/raft"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultRaftApplyTimeout = 5 * time.Second
+
+var errNoLeader = errors.New("cluster has no leader")
+
+// RaftConfig holds what a member of a raft cluster needs besides its local store
+type RaftConfig struct {
+	ID            string // the address of the cluster service of the member, also its raft server id
+	Transport     raft.Transport
+	LogStore      raft.LogStore
+	StableStore   raft.StableStore
+	SnapshotStore raft.SnapshotStore
+	Raft          *raft.Config      // the timeouts and the snapshot thresholds, raft.DefaultConfig() if nil
+	DialOptions   []grpc.DialOption // of the connections forwarding the writes to the leader, insecure if empty
+}
+
+// RaftLaptopStore is the store of a member of a raft cluster: the reads are served by the local store,
+// the mutations are appended to the raft log by the leader and applied to the local store of every member
+// once committed. The writes received by the other members are forwarded to the leader.
+// The local store must implement LaptopLister so that it can be snapshotted
+type RaftLaptopStore struct {
+	LaptopStore
+	ID           string
+	Raft         *raft.Raft
+	ApplyTimeout time.Duration
+
+	transport   raft.Transport
+	dialOptions []grpc.DialOption
+	mutex       sync.Mutex
+	conns       map[string]*grpc.ClientConn // to the members the writes were forwarded to, by id
+}
+
+func NewRaftLaptopStore(local LaptopStore, config RaftConfig) (*RaftLaptopStore, error) {
+	raftConfig := raft.DefaultConfig()
+	if config.Raft != nil {
+		*raftConfig = *config.Raft
+	}
+	raftConfig.LocalID = raft.ServerID(config.ID)
+
+	node, err := raft.NewRaft(raftConfig, &laptopStateMachine{store: local}, config.LogStore, config.StableStore, config.SnapshotStore, config.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start raft : %w", err)
+	}
+
+	dialOptions := config.DialOptions
+	if len(dialOptions) == 0 {
+		dialOptions = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	return &RaftLaptopStore{
+		LaptopStore:  local,
+		ID:           config.ID,
+		Raft:         node,
+		ApplyTimeout: defaultRaftApplyTimeout,
+		transport:    config.Transport,
+		dialOptions:  dialOptions,
+		conns:        make(map[string]*grpc.ClientConn),
+	}, nil
+}
+
+// Bootstrap starts a new cluster whose only member is this one, the other members join it with AddMember.
+// It fails with raft.ErrCantBootstrap if the member already has a raft state
+func (store *RaftLaptopStore) Bootstrap() error {
+	configuration := raft.Configuration{
+		Servers: []raft.Server{{ID: raft.ServerID(store.ID), Address: store.transport.LocalAddr()}},
+	}
+	return store.Raft.BootstrapCluster(configuration).Error()
+}
+
+func (store *RaftLaptopStore) Save(laptop *pb.Laptop) error {
+	return store.apply(&pb.LaptopCommand{Command: &pb.LaptopCommand_Save{Save: laptop}})
+}
+
+func (store *RaftLaptopStore) Update(laptop *pb.Laptop) error {
+	return store.apply(&pb.LaptopCommand{Command: &pb.LaptopCommand_Update{Update: laptop}})
+}
+
+func (store *RaftLaptopStore) Delete(laptopId string) error {
+	return store.apply(&pb.LaptopCommand{Command: &pb.LaptopCommand_DeleteId{DeleteId: laptopId}})
+}
+
+// Begin starts a transaction whose laptops are saved by a single command on commit
+func (store *RaftLaptopStore) Begin() (LaptopTx, error) {
+	return &raftLaptopTx{store: store, ids: make(map[string]bool)}, nil
+}
+
+// SearchSorted searches the local store
+func (store *RaftLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	searcher, ok := store.LaptopStore.(SortedLaptopSearcher)
+	if !ok {
+		return ErrSortedSearchNotSupported
+	}
+	return searcher.SearchSorted(ctx, filter, options, found)
+}
+
+// List goes through the laptops of the local store
+func (store *RaftLaptopStore) List(found func(*pb.Laptop) error) error {
+	lister, ok := store.LaptopStore.(LaptopLister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	return lister.List(found)
+}
+
+// Subscribe watches the commands applied to the local store
+func (store *RaftLaptopStore) Subscribe(resumeToken string) (*Subscription, error) {
+	watcher, ok := store.LaptopStore.(LaptopWatcher)
+	if !ok {
+		return nil, errors.New("local store doesn't publish its changes")
+	}
+	return watcher.Subscribe(resumeToken)
+}
+
+// Ready returns an error while the member doesn't know the leader, during an election
+// or when it is cut off from the majority of the cluster
+func (store *RaftLaptopStore) Ready() error {
+	_, leaderId := store.Raft.LeaderWithID()
+	if leaderId == "" {
+		return errNoLeader
+	}
+	return nil
+}
+
+// AddMember adds a voter to the cluster, or updates its raft address if it is already a member
+func (store *RaftLaptopStore) AddMember(id string, raftAddress string) error {
+	return store.onLeader(
+		func() error { return store.addMember(id, raftAddress) },
+		func(ctx context.Context, client pb.ClusterServiceClient) error {
+			_, err := client.AddMember(ctx, &pb.AddMemberRequest{Id: id, RaftAddress: raftAddress})
+			return err
+		},
+	)
+}
+
+// RemoveMember removes the member from the cluster, the leader steps down if it removes itself
+func (store *RaftLaptopStore) RemoveMember(id string) error {
+	return store.onLeader(
+		func() error { return store.removeMember(id) },
+		func(ctx context.Context, client pb.ClusterServiceClient) error {
+			_, err := client.RemoveMember(ctx, &pb.RemoveMemberRequest{Id: id})
+			return err
+		},
+	)
+}
+
+// Members returns the members of the cluster known by this member
+func (store *RaftLaptopStore) Members() ([]*pb.ClusterMember, error) {
+	future := store.Raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, fmt.Errorf("cannot get cluster configuration : %w", err)
+	}
+
+	_, leaderId := store.Raft.LeaderWithID()
+	members := []*pb.ClusterMember{}
+	for _, server := range future.Configuration().Servers {
+		members = append(members, &pb.ClusterMember{
+			Id:          string(server.ID),
+			RaftAddress: string(server.Address),
+			Voter:       server.Suffrage == raft.Voter,
+			Leader:      server.ID == leaderId,
+		})
+	}
+
+	return members, nil
+}
+
+// Shutdown stops the member and closes its connections to the other members
+func (store *RaftLaptopStore) Shutdown() error {
+	err := store.Raft.Shutdown().Error()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for id, conn := range store.conns {
+		conn.Close()
+		delete(store.conns, id)
+	}
+	return err
+}
+
+// apply appends the command to the log if the member is the leader, otherwise forwards it to the leader
+func (store *RaftLaptopStore) apply(command *pb.LaptopCommand) error {
+	return store.onLeader(
+		func() error { return store.applyCommand(command) },
+		func(ctx context.Context, client pb.ClusterServiceClient) error {
+			_, err := client.ApplyCommand(ctx, &pb.ApplyCommandRequest{Command: command})
+			return err
+		},
+	)
+}
+
+// applyCommand appends the command to the log and waits until it is applied to the local store,
+// it returns a NotLeaderError if the member is not the leader
+func (store *RaftLaptopStore) applyCommand(command *pb.LaptopCommand) error {
+	data, err := proto.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("cannot encode command : %w", err)
+	}
+
+	future := store.Raft.Apply(data, store.ApplyTimeout)
+	if err := future.Error(); err != nil {
+		return store.raftError(err)
+	}
+
+	if err, ok := future.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (store *RaftLaptopStore) addMember(id string, raftAddress string) error {
+	future := store.Raft.AddVoter(raft.ServerID(id), raft.ServerAddress(raftAddress), 0, store.ApplyTimeout)
+	return store.raftError(future.Error())
+}
+
+func (store *RaftLaptopStore) removeMember(id string) error {
+	future := store.Raft.RemoveServer(raft.ServerID(id), 0, store.ApplyTimeout)
+	return store.raftError(future.Error())
+}
+
+func (store *RaftLaptopStore) raftError(err error) error {
+	if errors.Is(err, raft.ErrNotLeader) {
+		_, leaderId := store.Raft.LeaderWithID()
+		return &NotLeaderError{Leader: string(leaderId)}
+	}
+	if err != nil {
+		return fmt.Errorf("raft : %w", err)
+	}
+	return nil
+}
+
+// onLeader runs local, and if the member is not the leader, calls forward with a client of the leader.
+// The forwarded requests run on the leader only, the member receiving one after losing the leadership
+// rejects it with the new leader, so the requests never go round in circles
+func (store *RaftLaptopStore) onLeader(local func() error, forward func(context.Context, pb.ClusterServiceClient) error) error {
+	err := local()
+
+	var notLeader *NotLeaderError
+	if !errors.As(err, &notLeader) || notLeader.Leader == "" || notLeader.Leader == store.ID {
+		return err
+	}
+
+	client, err := store.clusterClient(notLeader.Leader)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.ApplyTimeout)
+	defer cancel()
+
+	return forwardedError(notLeader.Leader, forward(ctx, client))
+}
+
+func (store *RaftLaptopStore) clusterClient(id string) (pb.ClusterServiceClient, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	conn, ok := store.conns[id]
+	if !ok {
+		var err error
+		conn, err = grpc.Dial(id, store.dialOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot dial leader %s : %w", id, err)
+		}
+		store.conns[id] = conn
+	}
+
+	return pb.NewClusterServiceClient(conn), nil
+}
+
+// forwardedError turns the status returned by the leader back into the error of its store
+func forwardedError(leader string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.AlreadyExists:
+		return ErrAlreadyExists
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.FailedPrecondition:
+		for _, detail := range st.Details() {
+			if errorInfo, ok := detail.(*errdetails.ErrorInfo); ok && errorInfo.GetReason() == ReasonNotLeader {
+				return &NotLeaderError{Leader: errorInfo.GetMetadata()["leader"]}
+			}
+		}
+	}
+
+	return fmt.Errorf("cannot forward request to leader %s : %w", leader, err)
+}
+
+// raftLaptopTx collects the laptops and saves them with a single command
+type raftLaptopTx struct {
+	store   *RaftLaptopStore
+	laptops []*pb.Laptop
+	ids     map[string]bool
+	done    bool
+}
+
+func (tx *raftLaptopTx) Save(laptop *pb.Laptop) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	existing, err := tx.store.FindById(laptop.Id)
+	if err != nil {
+		return err
+	}
+	if existing != nil || tx.ids[laptop.Id] {
+		return ErrAlreadyExists
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return err
+	}
+
+	tx.ids[laptop.Id] = true
+	tx.laptops = append(tx.laptops, other)
+	return nil
+}
+
+func (tx *raftLaptopTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	return tx.store.apply(&pb.LaptopCommand{
+		Command: &pb.LaptopCommand_SaveAll{SaveAll: &pb.SaveLaptops{Laptops: tx.laptops}},
+	})
+}
+
+func (tx *raftLaptopTx) Rollback() {
+	tx.done = true
+	tx.laptops = nil
+}
@@ -0,0 +1,347 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRaftCluster(t *testing.T) {
+	t.Parallel()
+
+	members := startRaftCluster(t, 3)
+	leader := waitForLeader(t, members)
+	followers := otherMembers(members, leader)
+
+	// the writes received by the followers are forwarded to the leader
+	laptop := sample.NewLaptop()
+	_, err := newTestLaptopClient(t, followers[0].address).CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+
+	laptop.Name = "Updated"
+	followerClient := newTestLaptopClient(t, followers[1].address)
+	_, err = followerClient.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+
+	// and fail like the writes of the leader
+	_, err = followerClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = followerClient.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	deleted := sample.NewLaptop()
+	require.NoError(t, leader.store.Save(deleted))
+	require.NoError(t, followers[0].store.Delete(deleted.GetId()))
+
+	// a transaction is a single command
+	tx, err := followers[1].store.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Save(sample.NewLaptop()))
+	require.NoError(t, tx.Save(sample.NewLaptop()))
+	require.NoError(t, tx.Commit())
+
+	for _, member := range members {
+		requireReplicated(t, leader.local, member.local)
+	}
+	require.Len(t, listLaptops(t, leader.local), 3)
+
+	clusterClient := newTestClusterClient(t, followers[0].address)
+	res, err := clusterClient.ListMembers(context.Background(), &pb.ListMembersRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.GetMembers(), 3)
+	for _, member := range res.GetMembers() {
+		require.True(t, member.GetVoter())
+		require.Equal(t, member.GetId() == leader.address, member.GetLeader())
+	}
+}
+
+func TestRaftApplyInvalidCommand(t *testing.T) {
+	t.Parallel()
+
+	members := startRaftCluster(t, 1)
+	leader := waitForLeader(t, members)
+	clusterClient := newTestClusterClient(t, leader.address)
+
+	existing := sample.NewLaptop()
+	require.NoError(t, leader.store.Save(existing))
+
+	invalid := sample.NewLaptop()
+	invalid.Cpu = nil
+	withoutId := sample.NewLaptop()
+	withoutId.Id = ""
+
+	// the commands are checked like the requests of the laptop service
+	commands := []*pb.LaptopCommand{
+		{},
+		{Command: &pb.LaptopCommand_Save{Save: invalid}},
+		{Command: &pb.LaptopCommand_Save{Save: withoutId}},
+		{Command: &pb.LaptopCommand_Update{}},
+		{Command: &pb.LaptopCommand_DeleteId{DeleteId: "not-a-uuid"}},
+		{Command: &pb.LaptopCommand_SaveAll{SaveAll: &pb.SaveLaptops{Laptops: []*pb.Laptop{sample.NewLaptop(), invalid}}}},
+	}
+	for _, command := range commands {
+		_, err := clusterClient.ApplyCommand(context.Background(), &pb.ApplyCommandRequest{Command: command})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "%v", command)
+	}
+	require.Len(t, listLaptops(t, leader.local), 1)
+
+	laptop := sample.NewLaptop()
+	_, err := clusterClient.ApplyCommand(context.Background(), &pb.ApplyCommandRequest{
+		Command: &pb.LaptopCommand{Command: &pb.LaptopCommand_Save{Save: laptop}},
+	})
+	require.NoError(t, err)
+	require.Len(t, listLaptops(t, leader.local), 2)
+}
+
+func TestRaftPartition(t *testing.T) {
+	t.Parallel()
+
+	members := startRaftCluster(t, 3)
+	oldLeader := waitForLeader(t, members)
+	majority := otherMembers(members, oldLeader)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, oldLeader.store.Save(laptop))
+	for _, member := range majority {
+		requireReplicated(t, oldLeader.local, member.local)
+	}
+
+	partition(oldLeader, majority)
+
+	// the majority elects a new leader and keeps accepting the writes
+	newLeader := waitForLeader(t, majority)
+	require.NotEqual(t, oldLeader.address, newLeader.address)
+
+	saved := sample.NewLaptop()
+	require.NoError(t, majority[0].store.Save(saved))
+	laptop.Name = "Updated"
+	require.NoError(t, majority[1].store.Update(laptop))
+
+	// the minority cannot commit anything
+	lost := sample.NewLaptop()
+	require.Error(t, oldLeader.store.Save(lost))
+	require.Eventually(t, func() bool { return oldLeader.store.Ready() != nil }, 5*time.Second, 10*time.Millisecond)
+
+	heal(members)
+
+	// the old leader follows the new one and drops its uncommitted write
+	require.Equal(t, newLeader, waitForLeader(t, members))
+	for _, member := range members {
+		requireReplicated(t, newLeader.local, member.local)
+	}
+
+	found, err := oldLeader.local.FindById(lost.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	found, err = oldLeader.local.FindById(saved.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.NoError(t, oldLeader.store.Ready())
+}
+
+func TestRaftMembership(t *testing.T) {
+	t.Parallel()
+
+	members := startRaftCluster(t, 3)
+	leader := waitForLeader(t, members)
+	followers := otherMembers(members, leader)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, leader.store.Save(sample.NewLaptop()))
+	}
+
+	// the log is compacted, a new member can only catch up with the snapshot
+	require.NoError(t, leader.store.Raft.Snapshot().Error())
+
+	joining := newTestRaftMember(t)
+	members = append(members, joining)
+	connect(members)
+
+	clusterClient := newTestClusterClient(t, followers[0].address)
+	_, err := clusterClient.AddMember(context.Background(), &pb.AddMemberRequest{
+		Id:          joining.address,
+		RaftAddress: string(joining.transport.LocalAddr()),
+	})
+	require.NoError(t, err)
+
+	requireReplicated(t, leader.local, joining.local)
+	snapshots, err := joining.snapshots.List()
+	require.NoError(t, err)
+	require.NotEmpty(t, snapshots)
+
+	// the new member forwards the writes like the others
+	require.NoError(t, joining.store.Save(sample.NewLaptop()))
+
+	_, err = clusterClient.RemoveMember(context.Background(), &pb.RemoveMemberRequest{Id: followers[1].address})
+	require.NoError(t, err)
+
+	res, err := clusterClient.ListMembers(context.Background(), &pb.ListMembersRequest{})
+	require.NoError(t, err)
+	ids := []string{}
+	for _, member := range res.GetMembers() {
+		ids = append(ids, member.GetId())
+	}
+	require.ElementsMatch(t, []string{leader.address, followers[0].address, joining.address}, ids)
+
+	// the removed member no longer receives the writes
+	laptop := sample.NewLaptop()
+	require.NoError(t, leader.store.Save(laptop))
+	requireReplicated(t, leader.local, joining.local)
+	found, err := followers[1].local.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+
+	_, err = clusterClient.AddMember(context.Background(), &pb.AddMemberRequest{Id: joining.address})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+type testRaftMember struct {
+	address   string // of the laptop and cluster services, the raft id of the member
+	store     *service.RaftLaptopStore
+	local     *service.EventLaptopStore
+	transport *raft.InmemTransport
+	snapshots *raft.InmemSnapshotStore
+}
+
+// startRaftCluster bootstraps the first member and adds the others, without connecting them over the network:
+// the raft messages go through in-memory transports which can be disconnected to partition the cluster
+func startRaftCluster(t *testing.T, size int) []*testRaftMember {
+	members := []*testRaftMember{}
+	for i := 0; i < size; i++ {
+		members = append(members, newTestRaftMember(t))
+	}
+	connect(members)
+
+	require.NoError(t, members[0].store.Bootstrap())
+	waitForLeader(t, members[:1])
+
+	for _, member := range members[1:] {
+		require.NoError(t, members[0].store.AddMember(member.address, string(member.transport.LocalAddr())))
+	}
+	return members
+}
+
+func newTestRaftMember(t *testing.T) *testRaftMember {
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	_, transport := raft.NewInmemTransport("")
+	snapshots := raft.NewInmemSnapshotStore()
+	logs := raft.NewInmemStore()
+
+	config := raft.DefaultConfig()
+	config.HeartbeatTimeout = 200 * time.Millisecond
+	config.ElectionTimeout = 200 * time.Millisecond
+	config.LeaderLeaseTimeout = 100 * time.Millisecond
+	config.CommitTimeout = 5 * time.Millisecond
+	config.TrailingLogs = 1
+	config.LogOutput = io.Discard
+
+	local := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(100))
+	store, err := service.NewRaftLaptopStore(local, service.RaftConfig{
+		ID:            listener.Addr().String(),
+		Transport:     transport,
+		LogStore:      logs,
+		StableStore:   logs,
+		SnapshotStore: snapshots,
+		Raft:          config,
+	})
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, service.NewLaptopServer(store, nil, nil))
+	pb.RegisterClusterServiceServer(grpcServer, service.NewClusterServer(store))
+	go grpcServer.Serve(listener)
+
+	t.Cleanup(func() {
+		grpcServer.Stop()
+		store.Shutdown()
+	})
+
+	return &testRaftMember{
+		address:   listener.Addr().String(),
+		store:     store,
+		local:     local,
+		transport: transport,
+		snapshots: snapshots,
+	}
+}
+
+func newTestClusterClient(t *testing.T, address string) pb.ClusterServiceClient {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewClusterServiceClient(conn)
+}
+
+// waitForLeader waits until one of the members is the leader and the others know it
+func waitForLeader(t *testing.T, members []*testRaftMember) *testRaftMember {
+	var leader *testRaftMember
+	require.Eventually(t, func() bool {
+		leader = nil
+		for _, member := range members {
+			if member.store.Raft.State() == raft.Leader {
+				leader = member
+			}
+		}
+		if leader == nil {
+			return false
+		}
+
+		for _, member := range members {
+			_, leaderId := member.store.Raft.LeaderWithID()
+			if string(leaderId) != leader.address {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 10*time.Millisecond)
+
+	return leader
+}
+
+func otherMembers(members []*testRaftMember, excluded *testRaftMember) []*testRaftMember {
+	others := []*testRaftMember{}
+	for _, member := range members {
+		if member != excluded {
+			others = append(others, member)
+		}
+	}
+	return others
+}
+
+func connect(members []*testRaftMember) {
+	for _, member := range members {
+		for _, other := range members {
+			if member != other {
+				member.transport.Connect(other.transport.LocalAddr(), other.transport)
+			}
+		}
+	}
+}
+
+// partition cuts the member off from the others, in both directions
+func partition(member *testRaftMember, others []*testRaftMember) {
+	for _, other := range others {
+		member.transport.Disconnect(other.transport.LocalAddr())
+		other.transport.Disconnect(member.transport.LocalAddr())
+	}
+}
+
+func heal(members []*testRaftMember) {
+	connect(members)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/serializer"
+	"github.com/hashicorp/raft"
+	"google.golang.org/protobuf/proto"
+)
+
+// laptopStateMachine applies the committed commands to the local store, raft calls it with one command at a time.
+// Every member applies the same commands in the same order, so a command fails the same way on all of them
+type laptopStateMachine struct {
+	store LaptopStore
+}
+
+// Apply returns the error of the command, which is returned to the writer by the leader
+func (fsm *laptopStateMachine) Apply(entry *raft.Log) interface{} {
+	command := &pb.LaptopCommand{}
+	err := proto.Unmarshal(entry.Data, command)
+	if err != nil {
+		return fmt.Errorf("cannot decode command %d : %w", entry.Index, err)
+	}
+
+	return applyLaptopCommand(fsm.store, command)
+}
+
+// Snapshot copies the laptops of the local store, they are written by Persist while the next commands are applied
+func (fsm *laptopStateMachine) Snapshot() (raft.FSMSnapshot, error) {
+	lister, ok := fsm.store.(LaptopLister)
+	if !ok {
+		return nil, ErrListNotSupported
+	}
+
+	laptops := []*pb.Laptop{}
+	err := lister.List(func(laptop *pb.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot snapshot laptops : %w", err)
+	}
+
+	return &laptopSnapshot{laptops: laptops}, nil
+}
+
+// Restore replaces the laptops of the local store with the ones of the snapshot
+func (fsm *laptopStateMachine) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+
+	laptops := []*pb.Laptop{}
+	_, err := serializer.ImportLaptops(snapshot, serializer.FormatBinary, func(laptop *pb.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read snapshot : %w", err)
+	}
+
+	return replaceLaptops(fsm.store, laptops)
+}
+
+func applyLaptopCommand(store LaptopStore, command *pb.LaptopCommand) error {
+	switch cmd := command.GetCommand().(type) {
+	case *pb.LaptopCommand_Save:
+		return store.Save(cmd.Save)
+	case *pb.LaptopCommand_Update:
+		return store.Update(cmd.Update)
+	case *pb.LaptopCommand_DeleteId:
+		return store.Delete(cmd.DeleteId)
+	case *pb.LaptopCommand_SaveAll:
+		return saveAllLaptops(store, cmd.SaveAll.GetLaptops())
+	default:
+		return fmt.Errorf("unknown laptop command %T", cmd)
+	}
+}
+
+// saveAllLaptops saves every laptop or none of them
+func saveAllLaptops(store LaptopStore, laptops []*pb.Laptop) error {
+	if transactional, ok := store.(TransactionalLaptopStore); ok {
+		tx, err := transactional.Begin()
+		if err != nil {
+			return err
+		}
+
+		for _, laptop := range laptops {
+			if err := tx.Save(laptop); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		return tx.Commit()
+	}
+
+	// the state machine is the only writer of the local store, nothing is saved between the checks and the saves
+	ids := make(map[string]bool, len(laptops))
+	for _, laptop := range laptops {
+		existing, err := store.FindById(laptop.GetId())
+		if err != nil {
+			return err
+		}
+		if existing != nil || ids[laptop.GetId()] {
+			return ErrAlreadyExists
+		}
+		ids[laptop.GetId()] = true
+	}
+
+	for _, laptop := range laptops {
+		if err := store.Save(laptop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// laptopSnapshot is written in the binary catalog format, varint length-delimited laptops
+type laptopSnapshot struct {
+	laptops []*pb.Laptop
+}
+
+func (snapshot *laptopSnapshot) Persist(sink raft.SnapshotSink) error {
+	_, err := serializer.ExportLaptops(sink, serializer.FormatBinary, func(found func(*pb.Laptop) error) error {
+		for _, laptop := range snapshot.laptops {
+			if err := found(laptop); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		sink.Cancel()
+		return fmt.Errorf("cannot persist snapshot : %w", err)
+	}
+
+	return sink.Close()
+}
+
+func (snapshot *laptopSnapshot) Release() {}
@@ -0,0 +1,40 @@
+package service
+
+import "sync"
+
+type RatingStore interface {
+	Add(laptopID string, score float64) (*Rating, error) // returns the updated rating of the laptop
+}
+
+type Rating struct {
+	Count uint32
+	Sum   float64
+}
+
+type InMemoryRatingStore struct {
+	mutex  sync.RWMutex
+	Rating map[string]*Rating
+}
+
+func NewInMemoryRatingStore() *InMemoryRatingStore {
+	return &InMemoryRatingStore{
+		Rating: make(map[string]*Rating),
+	}
+}
+
+func (store *InMemoryRatingStore) Add(laptopID string, score float64) (*Rating, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	rating := store.Rating[laptopID]
+	if rating == nil {
+		rating = &Rating{}
+		store.Rating[laptopID] = rating
+	}
+
+	rating.Count++
+	rating.Sum += score
+
+	// return a copy, the stored rating keeps changing with the other streams
+	return &Rating{Count: rating.Count, Sum: rating.Sum}, nil
+}
@@ -0,0 +1,43 @@
+package service_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryRatingStoreConcurrentAdd(t *testing.T) {
+	t.Parallel()
+
+	store := service.NewInMemoryRatingStore()
+
+	const streams = 10
+	const ratingsPerStream = 100
+
+	// the errors are checked by the test goroutine, require cannot stop the test from another one
+	errs := make(chan error, streams*ratingsPerStream)
+	wg := sync.WaitGroup{}
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(score float64) {
+			defer wg.Done()
+			for j := 0; j < ratingsPerStream; j++ {
+				_, err := store.Add("laptop-id", score)
+				errs <- err
+			}
+		}(float64(i%2 + 1)) // half of the streams rate 1, the other half rate 2
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	rating, err := store.Add("laptop-id", 1.5)
+	require.NoError(t, err)
+	require.EqualValues(t, streams*ratingsPerStream+1, rating.Count)
+	require.InDelta(t, 1.5, rating.Sum/float64(rating.Count), 1e-9)
+}
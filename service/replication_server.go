@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const snapshotBatchSize = 100 // laptops per snapshot part
+
+// ReplicationServer streams the mutations of the leader store to the followers.
+// A follower resumes from the token of the last event it applied, or receives a snapshot of the store
+// if it has none or if the events after its token are no longer kept
+type ReplicationServer struct {
+	pb.UnimplementedReplicationServiceServer
+	LaptopStore *EventLaptopStore
+}
+
+func NewReplicationServer(laptopStore *EventLaptopStore) *ReplicationServer {
+	return &ReplicationServer{LaptopStore: laptopStore}
+}
+
+func (server *ReplicationServer) Replicate(req *pb.ReplicateRequest, stream pb.ReplicationService_ReplicateServer) error {
+	resumeToken := req.GetResumeToken()
+	logf(pb.LogLevel_INFO, "receive replicate request with resume token : %q", resumeToken)
+
+	var subscription *Subscription
+	if resumeToken != "" {
+		var err error
+		subscription, err = server.LaptopStore.Subscribe(resumeToken)
+		if err != nil && !errors.Is(err, ErrResumeTokenExpired) && !errors.Is(err, ErrInvalidResumeToken) {
+			return logError(status.Errorf(codes.Internal, "cannot replicate laptops : %v", err))
+		}
+		if err != nil {
+			// the token of a previous leader or too old, the follower has to start over
+			logf(pb.LogLevel_INFO, "follower cannot resume, sending a snapshot : %v", err)
+		}
+	}
+
+	if subscription == nil {
+		var err error
+		subscription, err = server.sendSnapshot(stream)
+		if err != nil {
+			return err
+		}
+	}
+	defer subscription.Close()
+
+	for {
+		event, err := subscription.Next(stream.Context())
+		if err != nil {
+			if errors.Is(err, ErrSubscriberTooSlow) {
+				return logError(status.Errorf(codes.ResourceExhausted, "cannot replicate laptops : %v", err))
+			}
+			return contextError(stream.Context())
+		}
+
+		err = stream.Send(&pb.ReplicateResponse{
+			Data: &pb.ReplicateResponse_Event{Event: eventProto(event)},
+		})
+		if err != nil {
+			return logError(streamError("cannot send laptop event", err))
+		}
+	}
+}
+
+// sendSnapshot sends every laptop of the store in parts, and subscribes to the events following them
+func (server *ReplicationServer) sendSnapshot(stream pb.ReplicationService_ReplicateServer) (*Subscription, error) {
+	laptops := []*pb.Laptop{}
+	resumeToken, subscription, err := server.LaptopStore.SubscribeSnapshot(func(laptop *pb.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	if err != nil {
+		return nil, logError(storeError("cannot snapshot laptops", err))
+	}
+
+	for start := 0; ; start += snapshotBatchSize {
+		end := start + snapshotBatchSize
+		if end > len(laptops) {
+			end = len(laptops)
+		}
+
+		snapshot := &pb.LaptopSnapshot{Laptops: laptops[start:end], Last: end == len(laptops)}
+		if snapshot.Last {
+			snapshot.ResumeToken = resumeToken
+		}
+
+		err := stream.Send(&pb.ReplicateResponse{
+			Data: &pb.ReplicateResponse_Snapshot{Snapshot: snapshot},
+		})
+		if err != nil {
+			subscription.Close()
+			return nil, logError(streamError("cannot send laptop snapshot", err))
+		}
+
+		if snapshot.Last {
+			logf(pb.LogLevel_INFO, "sent snapshot of %d laptops", len(laptops))
+			return subscription, nil
+		}
+	}
+}
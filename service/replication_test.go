@@ -0,0 +1,252 @@
+package service_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReplication(t *testing.T) {
+	t.Parallel()
+
+	leaderStore := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(100))
+	leaderAddress := startReplicatedServer(t, leaderStore, leaderStore)
+
+	// the laptops saved before the follower starts are received with the snapshot
+	laptops := []*pb.Laptop{}
+	for i := 0; i < 3; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, leaderStore.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+
+	follower, followerLocal := newTestFollower(t, leaderAddress)
+	require.Error(t, follower.Ready())
+	stopFollower := runFollower(follower)
+	defer stopFollower()
+	followerAddress := startReplicatedServer(t, follower, followerLocal)
+
+	// a follower can replicate another follower
+	chained, _ := newTestFollower(t, followerAddress)
+	stopChained := runFollower(chained)
+	defer stopChained()
+
+	requireReplicated(t, leaderStore, follower)
+	requireReplicated(t, leaderStore, chained)
+	require.NoError(t, follower.Ready())
+
+	leaderClient := newTestLaptopClient(t, leaderAddress)
+	_, err := leaderClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.NoError(t, err)
+
+	laptops[0].Name = "Updated"
+	_, err = leaderClient.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: laptops[0]})
+	require.NoError(t, err)
+
+	_, err = leaderClient.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: laptops[1].GetId()})
+	require.NoError(t, err)
+
+	requireReplicated(t, leaderStore, follower)
+	requireReplicated(t, leaderStore, chained)
+
+	// the follower serves the searches
+	followerClient := newTestLaptopClient(t, followerAddress)
+	stream, err := followerClient.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{Filter: &pb.Filter{MaxPriceUsd: 5000}})
+	require.NoError(t, err)
+	found := 0
+	for {
+		_, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		found++
+	}
+	require.Equal(t, 3, found)
+
+	// and rejects the writes with the address of the leader
+	_, err = followerClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	requireNotLeader(t, err, leaderAddress)
+
+	_, err = followerClient.UpdateLaptop(context.Background(), &pb.UpdateLaptopRequest{Laptop: laptops[0]})
+	requireNotLeader(t, err, leaderAddress)
+
+	_, err = followerClient.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: laptops[0].GetId()})
+	requireNotLeader(t, err, leaderAddress)
+}
+
+func TestReplicationCatchUp(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		historySize int
+	}{
+		{name: "resume", historySize: 100},
+		{name: "snapshot", historySize: 2}, // the events missed by the follower are no longer kept
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			leaderStore := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(tc.historySize))
+			leaderAddress := startReplicatedServer(t, leaderStore, leaderStore)
+
+			laptops := []*pb.Laptop{}
+			for i := 0; i < 3; i++ {
+				laptop := sample.NewLaptop()
+				require.NoError(t, leaderStore.Save(laptop))
+				laptops = append(laptops, laptop)
+			}
+
+			follower, _ := newTestFollower(t, leaderAddress)
+			stopFollower := runFollower(follower)
+			requireReplicated(t, leaderStore, follower)
+			stopFollower()
+
+			// the follower falls behind
+			resumeToken := follower.ResumeToken()
+			for i := 0; i < 3; i++ {
+				require.NoError(t, leaderStore.Save(sample.NewLaptop()))
+			}
+			laptops[0].Name = "Updated"
+			require.NoError(t, leaderStore.Update(laptops[0]))
+			require.NoError(t, leaderStore.Delete(laptops[1].GetId()))
+
+			stopFollower = runFollower(follower)
+			defer stopFollower()
+
+			requireReplicated(t, leaderStore, follower)
+			require.NotEqual(t, resumeToken, follower.ResumeToken())
+		})
+	}
+}
+
+func TestReplicationLeaderRestart(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	leaderAddress := listener.Addr().String()
+
+	leaderStore := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(100))
+	require.NoError(t, leaderStore.Save(sample.NewLaptop()))
+	stopLeader := serveReplicatedServer(listener, leaderStore, leaderStore)
+
+	follower, _ := newTestFollower(t, leaderAddress)
+	stopFollower := runFollower(follower)
+	defer stopFollower()
+	requireReplicated(t, leaderStore, follower)
+
+	stopLeader()
+
+	// the tokens of the previous leader are rejected, the follower gets a snapshot of the new one
+	restartedStore := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(100))
+	require.NoError(t, restartedStore.Save(sample.NewLaptop()))
+
+	listener, err = net.Listen("tcp", leaderAddress)
+	require.NoError(t, err)
+	stopLeader = serveReplicatedServer(listener, restartedStore, restartedStore)
+	defer stopLeader()
+
+	requireReplicated(t, restartedStore, follower)
+}
+
+// startReplicatedServer serves the laptop service with the store, and the replication service of the event store
+func startReplicatedServer(t *testing.T, laptopStore service.LaptopStore, eventStore *service.EventLaptopStore) string {
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+
+	t.Cleanup(serveReplicatedServer(listener, laptopStore, eventStore))
+	return listener.Addr().String()
+}
+
+func serveReplicatedServer(listener net.Listener, laptopStore service.LaptopStore, eventStore *service.EventLaptopStore) func() {
+	grpcServer := grpc.NewServer()
+	pb.RegisterLaptopServiceServer(grpcServer, service.NewLaptopServer(laptopStore, nil, nil))
+	pb.RegisterReplicationServiceServer(grpcServer, service.NewReplicationServer(eventStore))
+
+	go grpcServer.Serve(listener)
+	return grpcServer.Stop
+}
+
+// newTestFollower returns a follower of the leader, and its local store which publishes the replicated mutations
+func newTestFollower(t *testing.T, leaderAddress string) (*service.FollowerLaptopStore, *service.EventLaptopStore) {
+	conn, err := grpc.Dial(leaderAddress, grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	local := service.NewEventLaptopStore(service.NewInMemoryLaptopStore(), service.NewEventBus(100))
+	follower := service.NewFollowerLaptopStore(local, leaderAddress, conn)
+	follower.RetryInterval = 10 * time.Millisecond
+
+	return follower, local
+}
+
+// runFollower runs the replication until the returned function is called
+func runFollower(follower *service.FollowerLaptopStore) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		follower.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func requireReplicated(t *testing.T, leader service.LaptopLister, follower service.LaptopLister) {
+	require.Eventually(t, func() bool {
+		expected := listLaptops(t, leader)
+		actual := listLaptops(t, follower)
+		if len(expected) != len(actual) {
+			return false
+		}
+		for id, laptop := range expected {
+			if !proto.Equal(laptop, actual[id]) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func listLaptops(t *testing.T, store service.LaptopLister) map[string]*pb.Laptop {
+	laptops := make(map[string]*pb.Laptop)
+	err := store.List(func(laptop *pb.Laptop) error {
+		laptops[laptop.GetId()] = laptop
+		return nil
+	})
+	require.NoError(t, err)
+	return laptops
+}
+
+func requireNotLeader(t *testing.T, err error, leaderAddress string) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+
+	require.NotEmpty(t, st.Details())
+	errorInfo, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, service.ReasonNotLeader, errorInfo.GetReason())
+	require.Equal(t, leaderAddress, errorInfo.GetMetadata()["leader"])
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// ShardedLaptopStore partitions the laptops over several stores by the hash of their ID.
+// A laptop is only read and written in the shard owning its ID, the searches go to every shard
+// at once and their results are merged. The shards must not be changed once laptops are saved
+type ShardedLaptopStore struct {
+	Shards []LaptopStore
+	Rates  money.RateProvider // compares the prices of the shards in the currency of the filter, nil if every price is in USD
+}
+
+// NewShardedLaptopStore fails without shards, there would be no store to own the laptops
+func NewShardedLaptopStore(shards ...LaptopStore) (*ShardedLaptopStore, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("sharded store needs at least one shard")
+	}
+	return &ShardedLaptopStore{Shards: shards}, nil
+}
+
+// shard returns the store owning the laptop ID
+func (store *ShardedLaptopStore) shard(laptopId string) LaptopStore {
+	hash := fnv.New32a()
+	hash.Write([]byte(laptopId))
+	return store.Shards[hash.Sum32()%uint32(len(store.Shards))]
+}
+
+func (store *ShardedLaptopStore) Save(laptop *pb.Laptop) error {
+	return store.shard(laptop.GetId()).Save(laptop)
+}
+
+func (store *ShardedLaptopStore) Update(laptop *pb.Laptop) error {
+	return store.shard(laptop.GetId()).Update(laptop)
+}
+
+func (store *ShardedLaptopStore) Delete(laptopId string) error {
+	return store.shard(laptopId).Delete(laptopId)
+}
+
+func (store *ShardedLaptopStore) FindById(laptopId string) (*pb.Laptop, error) {
+	return store.shard(laptopId).FindById(laptopId)
+}
+
+// Search searches every shard concurrently. found is called by the calling goroutine one laptop at a time,
+// the laptops of a shard in the order it found them. When found returns an error, a shard fails,
+// or the context is done, the searches of every shard are cancelled and Search returns once they all stopped
+func (store *ShardedLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var searchErr error
+	fail := func(err error) {
+		mutex.Lock()
+		if searchErr == nil {
+			searchErr = err
+		}
+		mutex.Unlock()
+		cancel()
+	}
+
+	results := make(chan *pb.Laptop)
+	var wg sync.WaitGroup
+	for i, shard := range store.Shards {
+		wg.Add(1)
+		go func(i int, shard LaptopStore) {
+			defer wg.Done()
+
+			err := shard.Search(ctx, filter, func(laptop *pb.Laptop) error {
+				select {
+				case results <- laptop:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				fail(fmt.Errorf("cannot search shard %d : %w", i, err))
+			}
+		}(i, shard)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// after a failure the results still sent by the shards being cancelled are dropped
+	stopped := false
+	for laptop := range results {
+		if stopped {
+			continue
+		}
+		if err := found(laptop); err != nil {
+			fail(err)
+			stopped = true
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	return searchErr
+}
+
+// SearchSorted merges the laptops of the shards, each of them finding its laptops in the order of the options.
+// Every shard finds at most the limit of the options, and they are all cancelled once the limit is reached
+func (store *ShardedLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options SearchOptions, found func(*pb.Laptop) error) error {
+	if options.Order == pb.SearchLaptopRequest_UNSORTED {
+		return limitSearch(options.Limit, found, func(found func(*pb.Laptop) error) error {
+			return store.Search(ctx, filter, found)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var searchErr error
+	fail := func(err error) {
+		mutex.Lock()
+		if searchErr == nil {
+			searchErr = err
+		}
+		mutex.Unlock()
+		cancel()
+	}
+
+	streams := make([]chan *pb.Laptop, len(store.Shards))
+	var wg sync.WaitGroup
+	for i, shard := range store.Shards {
+		streams[i] = make(chan *pb.Laptop)
+		wg.Add(1)
+		go func(i int, shard LaptopStore, stream chan<- *pb.Laptop) {
+			defer wg.Done()
+			defer close(stream)
+
+			err := searchLaptops(ctx, shard, filter, options, store.Rates, func(laptop *pb.Laptop) error {
+				select {
+				case stream <- laptop:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				fail(fmt.Errorf("cannot search shard %d : %w", i, err))
+			}
+		}(i, shard, streams[i])
+	}
+
+	// the heap holds the next laptop of every shard which still has laptops
+	_, currency := filterMaxPrice(filter)
+	heads := &shardHeads{order: options.Order}
+	next := func(shard int) {
+		if laptop, ok := <-streams[shard]; ok {
+			heap.Push(heads, shardHead{pricedLaptop: newPricedLaptop(laptop, currency, store.Rates), shard: shard})
+		}
+	}
+	for i := range streams {
+		next(i)
+	}
+
+	count := 0
+	for heads.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			fail(err)
+			break
+		}
+
+		head := heap.Pop(heads).(shardHead)
+		if err := found(head.laptop); err != nil {
+			fail(err)
+			break
+		}
+
+		count++
+		if count == options.Limit {
+			fail(errSearchStopped)
+			break
+		}
+		next(head.shard)
+	}
+
+	cancel()
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if errors.Is(searchErr, errSearchStopped) {
+		return nil
+	}
+	return searchErr
+}
+
+// shardHead is the next laptop found by a shard
+type shardHead struct {
+	pricedLaptop
+	shard int
+}
+
+// shardHeads is a heap of the next laptops of the shards in the order of the search, the ties are broken by shard
+type shardHeads struct {
+	heads []shardHead
+	order pb.SearchLaptopRequest_PriceOrder
+}
+
+func (h *shardHeads) Len() int { return len(h.heads) }
+
+func (h *shardHeads) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if a.before(b.pricedLaptop, h.order) != b.before(a.pricedLaptop, h.order) {
+		return a.before(b.pricedLaptop, h.order)
+	}
+	return a.shard < b.shard
+}
+
+func (h *shardHeads) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+
+func (h *shardHeads) Push(x interface{}) { h.heads = append(h.heads, x.(shardHead)) }
+
+func (h *shardHeads) Pop() interface{} {
+	head := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return head
+}
+
+// List goes through the laptops of one shard after the other
+func (store *ShardedLaptopStore) List(found func(*pb.Laptop) error) error {
+	for i, shard := range store.Shards {
+		lister, ok := shard.(LaptopLister)
+		if !ok {
+			return fmt.Errorf("shard %d : %w", i, ErrListNotSupported)
+		}
+
+		err := lister.List(found)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ready returns the error of the first shard which is not ready
+func (store *ShardedLaptopStore) Ready() error {
+	for i, shard := range store.Shards {
+		if checker, ok := shard.(ReadinessChecker); ok {
+			if err := checker.Ready(); err != nil {
+				return fmt.Errorf("shard %d is not ready : %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Stats adds up the statistics of the shards which report them
+func (store *ShardedLaptopStore) Stats() (StoreStats, error) {
+	stats := StoreStats{}
+	for i, shard := range store.Shards {
+		reporter, ok := shard.(StatsReporter)
+		if !ok {
+			continue
+		}
+
+		shardStats, err := reporter.Stats()
+		if err != nil {
+			return StoreStats{}, fmt.Errorf("cannot get stats of shard %d : %w", i, err)
+		}
+		stats.Count += shardStats.Count
+		stats.Bytes += shardStats.Bytes
+	}
+	return stats, nil
+}
@@ -0,0 +1,289 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedLaptopStore(t *testing.T) {
+	t.Parallel()
+
+	shards := []*service.InMemoryLaptopStore{
+		service.NewInMemoryLaptopStore(),
+		service.NewInMemoryLaptopStore(),
+		service.NewInMemoryLaptopStore(),
+	}
+	store, err := service.NewShardedLaptopStore(shards[0], shards[1], shards[2])
+	require.NoError(t, err)
+
+	laptops := []*pb.Laptop{}
+	for i := 0; i < 30; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+	require.ErrorIs(t, store.Save(laptops[0]), service.ErrAlreadyExists)
+
+	// every laptop is kept by a single shard
+	for _, laptop := range laptops {
+		owners := 0
+		for _, shard := range shards {
+			if found, _ := shard.FindById(laptop.GetId()); found != nil {
+				owners++
+			}
+		}
+		require.Equal(t, 1, owners)
+	}
+	for _, shard := range shards {
+		require.NotZero(t, shard.Len())
+	}
+
+	laptops[0].Name = "Updated"
+	require.NoError(t, store.Update(laptops[0]))
+	found, err := store.FindById(laptops[0].GetId())
+	require.NoError(t, err)
+	require.Equal(t, "Updated", found.GetName())
+
+	require.NoError(t, store.Delete(laptops[1].GetId()))
+	require.ErrorIs(t, store.Delete(laptops[1].GetId()), service.ErrNotFound)
+	laptops = append(laptops[:1], laptops[2:]...)
+
+	// the search merges the laptops of every shard
+	ids := []string{}
+	err = store.Search(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, func(laptop *pb.Laptop) error {
+		ids = append(ids, laptop.GetId())
+		return nil
+	})
+	require.NoError(t, err)
+
+	expected := []string{}
+	for _, laptop := range laptops {
+		expected = append(expected, laptop.GetId())
+	}
+	require.ElementsMatch(t, expected, ids)
+
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(expected)), stats.Count)
+	require.Len(t, listLaptops(t, store), len(expected))
+	require.NoError(t, store.Ready())
+}
+
+func TestShardedLaptopStoreWithoutShards(t *testing.T) {
+	t.Parallel()
+
+	store, err := service.NewShardedLaptopStore()
+	require.Error(t, err)
+	require.Nil(t, store)
+}
+
+func TestShardedLaptopStoreSearchStops(t *testing.T) {
+	t.Parallel()
+
+	shards := []service.LaptopStore{}
+	for i := 0; i < 4; i++ {
+		shard := service.NewInMemoryLaptopStore()
+		for j := 0; j < 10; j++ {
+			require.NoError(t, shard.Save(sample.NewLaptop()))
+		}
+		shards = append(shards, shard)
+	}
+	blocked := &blockingLaptopStore{LaptopStore: service.NewInMemoryLaptopStore(), laptop: sample.NewLaptop()}
+	store, err := service.NewShardedLaptopStore(append(shards, blocked)...)
+	require.NoError(t, err)
+
+	// a limit reached by the caller stops every shard
+	errLimit := errors.New("limit reached")
+	calls := 0
+	err = store.Search(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, func(laptop *pb.Laptop) error {
+		calls++
+		if calls == 5 {
+			return errLimit
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, errLimit)
+	require.Equal(t, 5, calls)
+	require.Equal(t, int32(1), atomic.LoadInt32(&blocked.cancelled))
+
+	// and so does the cancellation of the search
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = store.Search(ctx, &pb.Filter{MaxPriceUsd: 5000}, func(laptop *pb.Laptop) error { return nil })
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&blocked.cancelled))
+
+	// a failing shard cancels the others
+	failing := &failingLaptopStore{LaptopStore: service.NewInMemoryLaptopStore()}
+	store, err = service.NewShardedLaptopStore(blocked, failing)
+	require.NoError(t, err)
+	err = store.Search(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, func(laptop *pb.Laptop) error { return nil })
+	require.ErrorIs(t, err, errShardFailure)
+	require.Equal(t, int32(3), atomic.LoadInt32(&blocked.cancelled))
+}
+
+func TestShardedLaptopStoreSearchOrder(t *testing.T) {
+	t.Parallel()
+
+	// the shard which cannot sort its laptops is sorted by the sharded store
+	store, err := service.NewShardedLaptopStore(
+		service.NewInMemoryLaptopStore(),
+		service.NewInMemoryLaptopStore(),
+		struct{ service.LaptopStore }{service.NewInMemoryLaptopStore()},
+	)
+	require.NoError(t, err)
+
+	prices := []float64{}
+	for i := 0; i < 30; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, store.Save(laptop))
+		prices = append(prices, laptop.GetPriceUsd())
+	}
+	sort.Float64s(prices)
+
+	descending := make([]float64, len(prices))
+	for i, price := range prices {
+		descending[len(prices)-1-i] = price
+	}
+
+	testCases := []struct {
+		name     string
+		options  service.SearchOptions
+		expected []float64
+	}{
+		{"ascending", service.SearchOptions{Order: pb.SearchLaptopRequest_ASCENDING}, prices},
+		{"descending", service.SearchOptions{Order: pb.SearchLaptopRequest_DESCENDING}, descending},
+		{"ascending limit", service.SearchOptions{Order: pb.SearchLaptopRequest_ASCENDING, Limit: 7}, prices[:7]},
+		{"descending limit", service.SearchOptions{Order: pb.SearchLaptopRequest_DESCENDING, Limit: 7}, descending[:7]},
+	}
+
+	for _, tc := range testCases {
+		found := []float64{}
+		err := store.SearchSorted(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, tc.options, func(laptop *pb.Laptop) error {
+			found = append(found, laptop.GetPriceUsd())
+			return nil
+		})
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, found, tc.name)
+	}
+
+	count := 0
+	err = store.SearchSorted(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, service.SearchOptions{Limit: 4}, func(laptop *pb.Laptop) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	// the server streams the merged laptops of the shards
+	laptopClient := newTestLaptopClient(t, startTestLaptopServer(t, store, nil, nil))
+	stream, err := laptopClient.SearchLaptop(context.Background(), &pb.SearchLaptopRequest{
+		Filter:     &pb.Filter{MaxPriceUsd: 5000},
+		PriceOrder: pb.SearchLaptopRequest_ASCENDING,
+		Limit:      5,
+	})
+	require.NoError(t, err)
+
+	found := []float64{}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		found = append(found, res.GetLaptop().GetPriceUsd())
+	}
+	require.Equal(t, prices[:5], found)
+}
+
+func TestShardedLaptopStoreSearchLimit(t *testing.T) {
+	t.Parallel()
+
+	// the shards never run out of laptops, only the limit stops them
+	odd := &endlessLaptopStore{first: 1}
+	even := &endlessLaptopStore{first: 2}
+	store, err := service.NewShardedLaptopStore(odd, even)
+	require.NoError(t, err)
+
+	prices := []float64{}
+	options := service.SearchOptions{Order: pb.SearchLaptopRequest_ASCENDING, Limit: 10}
+	err = store.SearchSorted(context.Background(), &pb.Filter{MaxPriceUsd: 5000}, options, func(laptop *pb.Laptop) error {
+		prices = append(prices, laptop.GetPriceUsd())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, prices)
+	require.Equal(t, int32(1), atomic.LoadInt32(&odd.cancelled))
+	require.Equal(t, int32(1), atomic.LoadInt32(&even.cancelled))
+
+	// so does the cancellation of the search
+	ctx, cancel := context.WithCancel(context.Background())
+	options.Limit = 0
+	err = store.SearchSorted(ctx, &pb.Filter{MaxPriceUsd: 5000}, options, func(laptop *pb.Laptop) error {
+		if laptop.GetPriceUsd() == 20 {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int32(2), atomic.LoadInt32(&odd.cancelled))
+	require.Equal(t, int32(2), atomic.LoadInt32(&even.cancelled))
+}
+
+// endlessLaptopStore finds laptops priced first, first + 2, first + 4... in ascending order until the search is cancelled
+type endlessLaptopStore struct {
+	service.LaptopStore
+	first     float64
+	cancelled int32
+}
+
+func (store *endlessLaptopStore) SearchSorted(ctx context.Context, filter *pb.Filter, options service.SearchOptions, found func(*pb.Laptop) error) error {
+	for price := store.first; ; price += 2 {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = price
+		laptop.Price = nil
+
+		if err := found(laptop); err != nil {
+			atomic.AddInt32(&store.cancelled, 1)
+			return err
+		}
+	}
+}
+
+// blockingLaptopStore finds its laptop, then blocks until the search is cancelled
+type blockingLaptopStore struct {
+	service.LaptopStore
+	laptop    *pb.Laptop
+	cancelled int32
+}
+
+func (store *blockingLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+	if err := found(store.laptop); err != nil {
+		atomic.AddInt32(&store.cancelled, 1)
+		return err
+	}
+
+	<-ctx.Done()
+	atomic.AddInt32(&store.cancelled, 1)
+	return ctx.Err()
+}
+
+var errShardFailure = errors.New("shard failure")
+
+type failingLaptopStore struct {
+	service.LaptopStore
+}
+
+func (store *failingLaptopStore) Search(ctx context.Context, filter *pb.Filter, found func(*pb.Laptop) error) error {
+	return errShardFailure
+}
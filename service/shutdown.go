@@ -0,0 +1,32 @@
+package service
+
+import (
+	"time"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/grpc"
+)
+
+// StopGracefully waits for in-flight RPCs to finish for at most the drain timeout,
+// after that the remaining RPCs are cancelled. It returns false if the drain timed out.
+func StopGracefully(grpcServer *grpc.Server, drainTimeout time.Duration) bool {
+	stopped := make(chan struct{})
+
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return true
+	case <-timer.C:
+		logf(pb.LogLevel_WARN, "drain timeout of %v exceeded, cancelling in-flight requests", drainTimeout)
+		grpcServer.Stop()
+		<-stopped
+		return false
+	}
+}
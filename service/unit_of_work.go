@@ -0,0 +1,183 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+)
+
+// UnitOfWork groups writes to the laptop and the image stores which are kept together by Commit,
+// or undone together by Rollback. The laptops are saved in a transaction when the laptop store supports them,
+// the other laptop writes are applied right away and undone by compensating writes on rollback.
+// The images are written in a transaction when the image store supports them, otherwise they cannot be undone
+type UnitOfWork struct {
+	laptopStore LaptopStore
+	imageStore  ImageStore
+	laptopTx    LaptopTx // nil until a laptop is saved, or if the laptop store has no transactions
+	imageTx     ImageTx  // nil if the image store has no transactions
+	undo        []func() error
+	done        bool
+}
+
+func NewUnitOfWork(laptopStore LaptopStore, imageStore ImageStore) (*UnitOfWork, error) {
+	uow := &UnitOfWork{laptopStore: laptopStore, imageStore: imageStore}
+
+	if transactional, ok := imageStore.(TransactionalImageStore); ok {
+		tx, err := transactional.Begin()
+		if err != nil {
+			return nil, fmt.Errorf("cannot begin image transaction : %w", err)
+		}
+		uow.imageTx = tx
+	}
+
+	return uow, nil
+}
+
+// RequireLaptopTx begins the transaction of the laptops right away so that none of them is visible before
+// the commit, it returns ErrTxNotSupported if the laptop store has no transactions
+func (uow *UnitOfWork) RequireLaptopTx() error {
+	if uow.done {
+		return ErrTxDone
+	}
+
+	if err := uow.beginLaptopTx(); err != nil {
+		return err
+	}
+	if uow.laptopTx == nil {
+		return ErrTxNotSupported
+	}
+	return nil
+}
+
+// beginLaptopTx begins the transaction of the laptops unless it is begun or the laptop store has none
+func (uow *UnitOfWork) beginLaptopTx() error {
+	if uow.laptopTx != nil {
+		return nil
+	}
+
+	transactional, ok := uow.laptopStore.(TransactionalLaptopStore)
+	if !ok {
+		return nil
+	}
+
+	tx, err := transactional.Begin()
+	if err != nil && !errors.Is(err, ErrTxNotSupported) {
+		return err
+	}
+	uow.laptopTx = tx
+	return nil
+}
+
+// SaveLaptop saves the laptop, it is visible once committed if the laptop store has transactions
+func (uow *UnitOfWork) SaveLaptop(laptop *pb.Laptop) error {
+	if uow.done {
+		return ErrTxDone
+	}
+
+	if err := uow.beginLaptopTx(); err != nil {
+		return err
+	}
+
+	if uow.laptopTx != nil {
+		return uow.laptopTx.Save(laptop)
+	}
+
+	err := uow.laptopStore.Save(laptop)
+	if err != nil {
+		return err
+	}
+
+	laptopId := laptop.GetId()
+	uow.undo = append(uow.undo, func() error { return uow.laptopStore.Delete(laptopId) })
+	return nil
+}
+
+// DeleteLaptop deletes the laptop and its images, it returns how many images are deleted
+func (uow *UnitOfWork) DeleteLaptop(laptopId string) (int, error) {
+	if uow.done {
+		return 0, ErrTxDone
+	}
+
+	previous, err := uow.laptopStore.FindById(laptopId)
+	if err != nil {
+		return 0, err
+	}
+	if previous == nil {
+		return 0, ErrNotFound
+	}
+
+	// the images are deleted first, they are restored by the rollback if the laptop cannot be deleted
+	images := 0
+	if uow.imageTx != nil {
+		images, err = uow.imageTx.DeleteByLaptop(laptopId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = uow.laptopStore.Delete(laptopId)
+	if err != nil {
+		return 0, err
+	}
+
+	uow.undo = append(uow.undo, func() error { return uow.laptopStore.Save(previous) })
+	return images, nil
+}
+
+// SaveImage writes the image, it is added to the image store once committed if the store has transactions
+func (uow *UnitOfWork) SaveImage(laptopId string, imageType string, imageData bytes.Buffer) (string, error) {
+	if uow.done {
+		return "", ErrTxDone
+	}
+
+	if uow.imageTx != nil {
+		return uow.imageTx.Save(laptopId, imageType, imageData)
+	}
+	return uow.imageStore.Save(laptopId, imageType, imageData)
+}
+
+// Commit commits the laptops first, everything is rolled back if they cannot be committed.
+// The error of the image commit doesn't undo anything, the images are committed but some files are left behind
+func (uow *UnitOfWork) Commit() error {
+	if uow.done {
+		return ErrTxDone
+	}
+
+	if uow.laptopTx != nil {
+		err := uow.laptopTx.Commit()
+		if err != nil {
+			uow.Rollback()
+			return err
+		}
+	}
+	uow.done = true
+
+	if uow.imageTx != nil {
+		return uow.imageTx.Commit()
+	}
+	return nil
+}
+
+// Rollback undoes the writes in the reverse order, it does nothing after Commit
+func (uow *UnitOfWork) Rollback() {
+	if uow.done {
+		return
+	}
+	uow.done = true
+
+	if uow.laptopTx != nil {
+		uow.laptopTx.Rollback()
+	}
+
+	for i := len(uow.undo) - 1; i >= 0; i-- {
+		if err := uow.undo[i](); err != nil {
+			logf(pb.LogLevel_ERROR, "cannot undo laptop write of a rolled back unit of work : %v", err)
+		}
+	}
+
+	if uow.imageTx != nil {
+		uow.imageTx.Rollback()
+	}
+}
@@ -0,0 +1,208 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeleteLaptopDeletesImages(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	deleted := sample.NewLaptop()
+	kept := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(deleted))
+	require.NoError(t, laptopStore.Save(kept))
+
+	deletedImages := []string{}
+	for i := 0; i < 2; i++ {
+		imageId, err := imageStore.Save(deleted.GetId(), ".png", *bytes.NewBufferString("image"))
+		require.NoError(t, err)
+		deletedImages = append(deletedImages, imageId)
+	}
+	keptImage, err := imageStore.Save(kept.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+
+	laptopClient := newTestLaptopClient(t, startTestLaptopServer(t, laptopStore, imageStore, nil))
+	_, err = laptopClient.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: deleted.GetId()})
+	require.NoError(t, err)
+
+	for _, imageId := range deletedImages {
+		stream, err := laptopClient.DownloadImage(context.Background(), &pb.DownloadImageRequest{ImageId: imageId})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.NotFound, status.Code(err))
+	}
+
+	require.Len(t, imageStore.Images, 1)
+	require.NotNil(t, imageStore.Images[keptImage])
+	requireImageFiles(t, imageFolder, imageStore.Images[keptImage].Path)
+}
+
+func TestDeleteLaptopFailureRestoresImages(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	laptopStore := &failingDeleteLaptopStore{InMemoryLaptopStore: service.NewInMemoryLaptopStore()}
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	imageId, err := imageStore.Save(laptop.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+
+	laptopClient := newTestLaptopClient(t, startTestLaptopServer(t, laptopStore, imageStore, nil))
+	_, err = laptopClient.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: laptop.GetId()})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	require.NotNil(t, imageStore.Images[imageId])
+	requireImageFiles(t, imageFolder, imageStore.Images[imageId].Path)
+
+	_, err = laptopClient.DeleteLaptop(context.Background(), &pb.DeleteLaptopRequest{Id: sample.NewLaptop().GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestUnitOfWork(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	// the rollback removes the laptop and the image file
+	uow, err := service.NewUnitOfWork(laptopStore, imageStore)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, uow.SaveLaptop(laptop))
+	imageId, err := uow.SaveImage(laptop.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+
+	// the files of the images being saved are not compacted
+	result, err := imageStore.Compact()
+	require.NoError(t, err)
+	require.Zero(t, result.Removed)
+	require.Len(t, listImageFiles(t, imageFolder), 1)
+
+	uow.Rollback()
+	require.Zero(t, laptopStore.Len())
+	require.Nil(t, imageStore.Images[imageId])
+	requireImageFiles(t, imageFolder)
+	require.ErrorIs(t, uow.Commit(), service.ErrTxDone)
+
+	// the commit makes both visible
+	uow, err = service.NewUnitOfWork(laptopStore, imageStore)
+	require.NoError(t, err)
+	require.NoError(t, uow.SaveLaptop(laptop))
+	imageId, err = uow.SaveImage(laptop.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+	require.Nil(t, imageStore.Images[imageId])
+	require.NoError(t, uow.Commit())
+
+	found, err := laptopStore.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.NotNil(t, imageStore.Images[imageId])
+
+	// the images are not kept when the laptops cannot be committed
+	uow, err = service.NewUnitOfWork(laptopStore, imageStore)
+	require.NoError(t, err)
+	other := sample.NewLaptop()
+	require.NoError(t, uow.SaveLaptop(other))
+	otherImage, err := uow.SaveImage(other.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+	require.NoError(t, laptopStore.Save(other)) // saved concurrently
+
+	require.ErrorIs(t, uow.Commit(), service.ErrAlreadyExists)
+	require.Nil(t, imageStore.Images[otherImage])
+	requireImageFiles(t, imageFolder, imageStore.Images[imageId].Path)
+}
+
+func TestUnitOfWorkWithoutLaptopTransactions(t *testing.T) {
+	t.Parallel()
+
+	imageFolder := t.TempDir()
+	laptopStore := &nonTransactionalLaptopStore{LaptopStore: service.NewInMemoryLaptopStore()}
+	imageStore := service.NewDiskImageStore(imageFolder)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	imageId, err := imageStore.Save(laptop.GetId(), ".png", *bytes.NewBufferString("image"))
+	require.NoError(t, err)
+
+	// the writes applied right away are undone by compensating writes
+	uow, err := service.NewUnitOfWork(laptopStore, imageStore)
+	require.NoError(t, err)
+
+	saved := sample.NewLaptop()
+	require.NoError(t, uow.SaveLaptop(saved))
+	images, err := uow.DeleteLaptop(laptop.GetId())
+	require.NoError(t, err)
+	require.Equal(t, 1, images)
+
+	found, err := laptopStore.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+	require.NoFileExists(t, imageStore.Images[imageId].Path) // kept aside until the commit
+
+	uow.Rollback()
+
+	found, err = laptopStore.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	found, err = laptopStore.FindById(saved.GetId())
+	require.NoError(t, err)
+	require.Nil(t, found)
+	require.NotNil(t, imageStore.Images[imageId])
+	requireImageFiles(t, imageFolder, imageStore.Images[imageId].Path)
+}
+
+// requireImageFiles checks the files of the image folder, including the hidden ones
+func requireImageFiles(t *testing.T, imageFolder string, paths ...string) {
+	expected := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		expected = append(expected, info.Name())
+	}
+	require.ElementsMatch(t, expected, listImageFiles(t, imageFolder))
+}
+
+func listImageFiles(t *testing.T, imageFolder string) []string {
+	entries, err := os.ReadDir(imageFolder)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+var errDeleteFailure = errors.New("delete failure")
+
+type failingDeleteLaptopStore struct {
+	*service.InMemoryLaptopStore
+}
+
+func (store *failingDeleteLaptopStore) Delete(laptopId string) error {
+	return errDeleteFailure
+}
+
+// nonTransactionalLaptopStore hides the transactions of the wrapped store
+type nonTransactionalLaptopStore struct {
+	service.LaptopStore
+}
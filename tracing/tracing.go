@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// NewExporter creates a span exporter of the given kind.
+// The endpoint is only used by the otlp exporter and should point to an OTLP/gRPC collector.
+func NewExporter(ctx context.Context, kind string, endpoint string) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		return otlptracegrpc.New(
+			ctx,
+			otlptracegrpc.WithEndpoint(endpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", kind)
+	}
+}
+
+// NewTracerProvider creates a tracer provider that batches spans to the exporter.
+// A nil exporter gives a provider which records spans but never exports them.
+func NewTracerProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	}
+
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Second)))
+	}
+
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Setup installs a global tracer provider and the W3C trace context propagator,
+// so the otelgrpc interceptors can carry spans through gRPC metadata.
+// The returned function flushes the remaining spans and must be called before exit.
+func Setup(ctx context.Context, serviceName string, kind string, endpoint string) (func(context.Context) error, error) {
+	exporter, err := NewExporter(ctx, kind, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trace exporter: %w", err)
+	}
+
+	provider := NewTracerProvider(exporter, serviceName)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
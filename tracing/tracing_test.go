@@ -0,0 +1,151 @@
+package tracing_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/daffarg/grpc-pcbook/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// testCollector is an in-process stand-in for an OTLP collector, it keeps every span it receives
+type testCollector struct {
+	collectortrace.UnimplementedTraceServiceServer
+	mutex sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (collector *testCollector) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	for _, resourceSpans := range req.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			collector.spans = append(collector.spans, scopeSpans.GetSpans()...)
+		}
+	}
+
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+func (collector *testCollector) spansByName() map[string][]*tracepb.Span {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	spans := make(map[string][]*tracepb.Span)
+	for _, span := range collector.spans {
+		spans[span.GetName()] = append(spans[span.GetName()], span)
+	}
+	return spans
+}
+
+func TestTracingThroughGRPC(t *testing.T) {
+	collector := &testCollector{}
+	collectorAddress := startTestCollector(t, collector)
+
+	shutdown, err := tracing.Setup(context.Background(), "pcbook-test", tracing.ExporterOTLP, collectorAddress)
+	require.NoError(t, err)
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	serverAddress := startTestLaptopServer(t, laptopStore)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	laptop := sample.NewLaptop()
+	_, err = laptopClient.CreateLaptop(context.Background(), &pb.CreateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+
+	ctx, rootSpan := otel.Tracer("test").Start(context.Background(), "test.SearchLaptop")
+	stream, err := laptopClient.SearchLaptop(ctx, &pb.SearchLaptopRequest{
+		Filter: &pb.Filter{MaxPriceUsd: 5000},
+	})
+	require.NoError(t, err)
+
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	rootSpan.End()
+
+	// flush all remaining spans to the collector
+	require.NoError(t, shutdown(context.Background()))
+
+	spans := collector.spansByName()
+	for _, name := range []string{"LaptopStore.Save", "LaptopStore.Search", "LaptopStore.deepCopy", "SearchLaptop.Send"} {
+		require.NotEmpty(t, spans[name], "missing span %s", name)
+	}
+
+	// the server spans should belong to the trace started by the client
+	traceId := spans["test.SearchLaptop"][0].GetTraceId()
+	require.Equal(t, traceId, spans["pb.LaptopService/SearchLaptop"][0].GetTraceId())
+	require.Equal(t, traceId, spans["LaptopStore.Search"][0].GetTraceId())
+	require.Equal(t, traceId, spans["SearchLaptop.Send"][0].GetTraceId())
+}
+
+func TestNewExporterUnknownKind(t *testing.T) {
+	t.Parallel()
+
+	_, err := tracing.NewExporter(context.Background(), "jaeger", "")
+	require.Error(t, err)
+
+	exporter, err := tracing.NewExporter(context.Background(), tracing.ExporterNone, "")
+	require.NoError(t, err)
+	require.Nil(t, exporter)
+}
+
+func startTestCollector(t *testing.T, collector *testCollector) string {
+	grpcServer := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(grpcServer, collector)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+func startTestLaptopServer(t *testing.T, laptopStore service.LaptopStore) string {
+	laptopServer := service.NewLaptopServer(laptopStore, nil, nil)
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	)
+	pb.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+func newTestLaptopClient(t *testing.T, serverAddress string) pb.LaptopServiceClient {
+	conn, err := grpc.Dial(
+		serverAddress,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewLaptopServiceClient(conn)
+}
@@ -0,0 +1,182 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/daffarg/grpc-pcbook/memunit"
+	"github.com/daffarg/grpc-pcbook/money"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// rule checks a single field of a message, the field is relative to the message path
+type rule[T any] struct {
+	field       string
+	description string
+	valid       func(T) bool
+}
+
+var laptopRules = []rule[*pb.Laptop]{
+	{"name", "must not be empty", func(laptop *pb.Laptop) bool { return laptop.GetName() != "" }},
+	{"brand", "must not be empty", func(laptop *pb.Laptop) bool { return laptop.GetBrand() != "" }},
+	{"price_usd", "must not be negative", func(laptop *pb.Laptop) bool { return laptop.GetPriceUsd() >= 0 }},
+	{"weight_kg", "must be greater than 0", func(laptop *pb.Laptop) bool {
+		weight, ok := laptop.GetWeight().(*pb.Laptop_WeightKg)
+		return !ok || weight.WeightKg > 0
+	}},
+	{"weight_lb", "must be greater than 0", func(laptop *pb.Laptop) bool {
+		weight, ok := laptop.GetWeight().(*pb.Laptop_WeightLb)
+		return !ok || weight.WeightLb > 0
+	}},
+}
+
+var cpuRules = []rule[*pb.CPU]{
+	{"number_cores", "must be greater than 0", func(cpu *pb.CPU) bool { return cpu.GetNumberCores() > 0 }},
+	{"number_threads", "must not be less than number_cores", func(cpu *pb.CPU) bool {
+		return cpu.GetNumberThreads() >= cpu.GetNumberCores()
+	}},
+	{"min_ghz", "must be greater than 0", func(cpu *pb.CPU) bool { return cpu.GetMinGhz() > 0 }},
+	{"max_ghz", "must not be less than min_ghz", func(cpu *pb.CPU) bool { return cpu.GetMaxGhz() >= cpu.GetMinGhz() }},
+}
+
+var gpuRules = []rule[*pb.GPU]{
+	{"min_ghz", "must be greater than 0", func(gpu *pb.GPU) bool { return gpu.GetMinGhz() > 0 }},
+	{"max_ghz", "must not be less than min_ghz", func(gpu *pb.GPU) bool { return gpu.GetMaxGhz() >= gpu.GetMinGhz() }},
+}
+
+var storageRules = []rule[*pb.Storage]{
+	{"driver", "must be HDD or SSD", func(storage *pb.Storage) bool {
+		return storage.GetDriver() == pb.Storage_HDD || storage.GetDriver() == pb.Storage_SSD
+	}},
+}
+
+var memoryRules = []rule[*pb.Memory]{
+	{"value", "must be greater than 0", func(memory *pb.Memory) bool { return memory.GetValue() > 0 }},
+	{"value", "must not overflow 64 bits once converted to bits", func(memory *pb.Memory) bool {
+		_, err := memunit.ToBits(memory)
+		return err == nil || !memunit.ValidUnit(memory.GetUnit())
+	}},
+	{"unit", "must be a known unit", func(memory *pb.Memory) bool { return memunit.ValidUnit(memory.GetUnit()) }},
+}
+
+var moneyRules = []rule[*pb.Money]{
+	{"currency_code", "must be an ISO 4217 currency code", func(price *pb.Money) bool {
+		return money.ValidCurrencyCode(price.GetCurrencyCode())
+	}},
+	{"units", "must not be negative", func(price *pb.Money) bool { return price.GetUnits() >= 0 }},
+	{"nanos", "must be between 0 and 999999999", func(price *pb.Money) bool {
+		return price.GetNanos() >= 0 && price.GetNanos() < 1e9
+	}},
+}
+
+var filterRules = []rule[*pb.Filter]{
+	{"max_price_usd", "must not be negative", func(filter *pb.Filter) bool { return filter.GetMaxPriceUsd() >= 0 }},
+	{"min_cpu_ghz", "must not be negative", func(filter *pb.Filter) bool { return filter.GetMinCpuGhz() >= 0 }},
+	{"min_ram.unit", "must be a known unit", func(filter *pb.Filter) bool {
+		return filter.GetMinRam().GetValue() == 0 || memunit.ValidUnit(filter.GetMinRam().GetUnit())
+	}},
+}
+
+var screenRules = []rule[*pb.Screen]{
+	{"size_inch", "must be greater than 0", func(screen *pb.Screen) bool { return screen.GetSizeInch() > 0 }},
+	{"resolution.width", "must be greater than 0", func(screen *pb.Screen) bool {
+		return screen.GetResolution() == nil || screen.GetResolution().GetWidth() > 0
+	}},
+	{"resolution.height", "must be greater than 0", func(screen *pb.Screen) bool {
+		return screen.GetResolution() == nil || screen.GetResolution().GetHeight() > 0
+	}},
+}
+
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(path string, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: path, Description: description})
+}
+
+func check[T any](v *violations, path string, message T, rules []rule[T]) {
+	for _, rule := range rules {
+		if !rule.valid(message) {
+			v.add(join(path, rule.field), rule.description)
+		}
+	}
+}
+
+func join(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// ValidateLaptop returns a violation for every invalid field of the laptop, nil if the laptop is valid,
+// the fields are prefixed with the path of the laptop in the request
+func ValidateLaptop(path string, laptop *pb.Laptop) []*errdetails.BadRequest_FieldViolation {
+	v := violations{}
+
+	if laptop == nil {
+		v.add(path, "is required")
+		return v
+	}
+
+	check(&v, path, laptop, laptopRules)
+
+	if laptop.GetCpu() == nil {
+		v.add(join(path, "cpu"), "is required")
+	} else {
+		check(&v, join(path, "cpu"), laptop.GetCpu(), cpuRules)
+	}
+
+	validateMemory(&v, join(path, "ram"), laptop.GetRam())
+
+	if laptop.GetPrice() != nil {
+		check(&v, join(path, "price"), laptop.GetPrice(), moneyRules)
+	}
+
+	for i, gpu := range laptop.GetGpus() {
+		gpuPath := join(path, fmt.Sprintf("gpus[%d]", i))
+		check(&v, gpuPath, gpu, gpuRules)
+		validateMemory(&v, join(gpuPath, "memory"), gpu.GetMemory())
+	}
+
+	for i, storage := range laptop.GetStorages() {
+		storagePath := join(path, fmt.Sprintf("storages[%d]", i))
+		check(&v, storagePath, storage, storageRules)
+		validateMemory(&v, join(storagePath, "memory"), storage.GetMemory())
+	}
+
+	if laptop.GetScreen() != nil {
+		check(&v, join(path, "screen"), laptop.GetScreen(), screenRules)
+	}
+
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func validateMemory(v *violations, path string, memory *pb.Memory) {
+	if memory == nil {
+		v.add(path, "is required")
+		return
+	}
+	check(v, path, memory, memoryRules)
+}
+
+// ValidateFilter returns a violation for every invalid field of the filter, a nil filter is valid
+func ValidateFilter(path string, filter *pb.Filter) []*errdetails.BadRequest_FieldViolation {
+	if filter == nil {
+		return nil
+	}
+
+	v := violations{}
+	check(&v, path, filter, filterRules)
+
+	if filter.GetMaxPrice() != nil {
+		check(&v, join(path, "max_price"), filter.GetMaxPrice(), moneyRules)
+	}
+
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
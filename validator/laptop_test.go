@@ -0,0 +1,115 @@
+package validator_test
+
+import (
+	"testing"
+
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLaptop(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		modify func(laptop *pb.Laptop)
+		fields []string
+	}{
+		{
+			name:   "valid",
+			modify: func(laptop *pb.Laptop) {},
+		},
+		{
+			name:   "negative_price",
+			modify: func(laptop *pb.Laptop) { laptop.PriceUsd = -10 },
+			fields: []string{"laptop.price_usd"},
+		},
+		{
+			name: "zero_cores",
+			modify: func(laptop *pb.Laptop) {
+				laptop.Cpu.NumberCores = 0
+			},
+			fields: []string{"laptop.cpu.number_cores"},
+		},
+		{
+			name: "threads_less_than_cores",
+			modify: func(laptop *pb.Laptop) {
+				laptop.Cpu.NumberCores = 8
+				laptop.Cpu.NumberThreads = 4
+			},
+			fields: []string{"laptop.cpu.number_threads"},
+		},
+		{
+			name: "min_ghz_greater_than_max_ghz",
+			modify: func(laptop *pb.Laptop) {
+				laptop.Cpu.MinGhz = 4.5
+				laptop.Cpu.MaxGhz = 3.5
+			},
+			fields: []string{"laptop.cpu.max_ghz"},
+		},
+		{
+			name:   "unknown_ram_unit",
+			modify: func(laptop *pb.Laptop) { laptop.Ram.Unit = pb.Memory_UNKNOWN },
+			fields: []string{"laptop.ram.unit"},
+		},
+		{
+			name:   "undefined_ram_unit",
+			modify: func(laptop *pb.Laptop) { laptop.Ram.Unit = pb.Memory_Unit(42) },
+			fields: []string{"laptop.ram.unit"},
+		},
+		{
+			name: "missing_cpu_and_ram",
+			modify: func(laptop *pb.Laptop) {
+				laptop.Cpu = nil
+				laptop.Ram = nil
+			},
+			fields: []string{"laptop.cpu", "laptop.ram"},
+		},
+		{
+			name: "every_offending_field",
+			modify: func(laptop *pb.Laptop) {
+				laptop.Name = ""
+				laptop.Weight = &pb.Laptop_WeightKg{WeightKg: 0}
+				laptop.Gpus = []*pb.GPU{sample.NewGPU(), {MinGhz: 2, MaxGhz: 1}}
+				laptop.Storages = []*pb.Storage{{Driver: pb.Storage_UNKNOWN, Memory: &pb.Memory{Value: 0, Unit: pb.Memory_GIGABYTE}}}
+			},
+			fields: []string{
+				"laptop.name",
+				"laptop.weight_kg",
+				"laptop.gpus[1].max_ghz",
+				"laptop.gpus[1].memory",
+				"laptop.storages[0].driver",
+				"laptop.storages[0].memory.value",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			laptop := sample.NewLaptop()
+			tc.modify(laptop)
+
+			violations := validator.ValidateLaptop("laptop", laptop)
+
+			fields := []string{}
+			for _, violation := range violations {
+				require.NotEmpty(t, violation.GetDescription())
+				fields = append(fields, violation.GetField())
+			}
+			require.ElementsMatch(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestValidateNilLaptop(t *testing.T) {
+	t.Parallel()
+
+	violations := validator.ValidateLaptop("laptop", nil)
+	require.Len(t, violations, 1)
+	require.Equal(t, "laptop", violations[0].GetField())
+}
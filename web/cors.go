@@ -0,0 +1,77 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost}
+
+	// headers sent by the Connect and gRPC-Web browser clients
+	corsAllowedHeaders = []string{
+		"Content-Type",
+		"Connect-Protocol-Version",
+		"Connect-Timeout-Ms",
+		"Connect-Accept-Encoding",
+		"Connect-Content-Encoding",
+		"Grpc-Timeout",
+		"X-Grpc-Web",
+		"X-User-Agent",
+	}
+
+	// headers the browser clients need to read the status of the call
+	corsExposedHeaders = []string{
+		"Grpc-Status",
+		"Grpc-Message",
+		"Grpc-Status-Details-Bin",
+		"Connect-Content-Encoding",
+		"Content-Encoding",
+	}
+)
+
+// withCORS lets browsers from the allowed origins call the handler
+func withCORS(next http.Handler, allowedOrigins []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := isAllowedOrigin(origin, allowedOrigins)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !allowed {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isAllowedOrigin(origin string, allowedOrigins []string) bool {
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == "*" || strings.EqualFold(allowedOrigin, origin) {
+			return true
+		}
+	}
+	return false
+}
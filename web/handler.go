@@ -0,0 +1,347 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/pb/pbconnect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewHandler serves the LaptopService over the Connect, gRPC-Web and gRPC protocols on a single port,
+// with HTTP/1.1 and cleartext HTTP/2. The calls are delegated to the gRPC laptop server,
+// and browsers from the allowed origins can call it directly, "*" allows every origin.
+func NewHandler(laptopServer pb.LaptopServiceServer, allowedOrigins []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(pbconnect.NewLaptopServiceHandler(&laptopHandler{server: laptopServer}))
+
+	return h2c.NewHandler(withCORS(mux, allowedOrigins), &http2.Server{})
+}
+
+// laptopHandler adapts the gRPC laptop server to the Connect handler interface
+type laptopHandler struct {
+	server pb.LaptopServiceServer
+}
+
+func (handler *laptopHandler) CreateLaptop(ctx context.Context, req *connect.Request[pb.CreateLaptopRequest]) (*connect.Response[pb.CreateLaptopResponse], error) {
+	ctx = incomingContext(ctx, req.Header())
+
+	res, err := handler.server.CreateLaptop(ctx, req.Msg)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (handler *laptopHandler) SearchLaptop(ctx context.Context, req *connect.Request[pb.SearchLaptopRequest], stream *connect.ServerStream[pb.SearchLaptopResponse]) error {
+	serverStream := &searchLaptopStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, req.Header()),
+			header:  stream.ResponseHeader(),
+			trailer: stream.ResponseTrailer(),
+		},
+		stream: stream,
+	}
+
+	return connectError(handler.server.SearchLaptop(req.Msg, serverStream))
+}
+
+func (handler *laptopHandler) GetLaptop(ctx context.Context, req *connect.Request[pb.GetLaptopRequest]) (*connect.Response[pb.GetLaptopResponse], error) {
+	ctx = incomingContext(ctx, req.Header())
+
+	res, err := handler.server.GetLaptop(ctx, req.Msg)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (handler *laptopHandler) UploadImage(ctx context.Context, stream *connect.ClientStream[pb.UploadImageRequest]) (*connect.Response[pb.UploadImageResponse], error) {
+	serverStream := &uploadImageStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, stream.RequestHeader()),
+			header:  make(http.Header),
+			trailer: make(http.Header),
+		},
+		stream: stream,
+	}
+
+	err := handler.server.UploadImage(serverStream)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	res := connect.NewResponse(serverStream.res)
+	copyHeader(res.Header(), serverStream.header)
+	copyHeader(res.Trailer(), serverStream.trailer)
+
+	return res, nil
+}
+
+func (handler *laptopHandler) DownloadImage(ctx context.Context, req *connect.Request[pb.DownloadImageRequest], stream *connect.ServerStream[pb.DownloadImageResponse]) error {
+	serverStream := &downloadImageStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, req.Header()),
+			header:  stream.ResponseHeader(),
+			trailer: stream.ResponseTrailer(),
+		},
+		stream: stream,
+	}
+
+	return connectError(handler.server.DownloadImage(req.Msg, serverStream))
+}
+
+func (handler *laptopHandler) RateLaptop(ctx context.Context, stream *connect.BidiStream[pb.RateLaptopRequest, pb.RateLaptopResponse]) error {
+	serverStream := &rateLaptopStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, stream.RequestHeader()),
+			header:  stream.ResponseHeader(),
+			trailer: stream.ResponseTrailer(),
+		},
+		stream: stream,
+	}
+
+	return connectError(handler.server.RateLaptop(serverStream))
+}
+
+func (handler *laptopHandler) UpdateLaptop(ctx context.Context, req *connect.Request[pb.UpdateLaptopRequest]) (*connect.Response[pb.UpdateLaptopResponse], error) {
+	ctx = incomingContext(ctx, req.Header())
+
+	res, err := handler.server.UpdateLaptop(ctx, req.Msg)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (handler *laptopHandler) DeleteLaptop(ctx context.Context, req *connect.Request[pb.DeleteLaptopRequest]) (*connect.Response[pb.DeleteLaptopResponse], error) {
+	ctx = incomingContext(ctx, req.Header())
+
+	res, err := handler.server.DeleteLaptop(ctx, req.Msg)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (handler *laptopHandler) WatchLaptops(ctx context.Context, req *connect.Request[pb.WatchLaptopsRequest], stream *connect.ServerStream[pb.WatchLaptopsResponse]) error {
+	serverStream := &watchLaptopsStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, req.Header()),
+			header:  stream.ResponseHeader(),
+			trailer: stream.ResponseTrailer(),
+		},
+		stream: stream,
+	}
+
+	return connectError(handler.server.WatchLaptops(req.Msg, serverStream))
+}
+
+func (handler *laptopHandler) BulkCreateLaptops(ctx context.Context, stream *connect.ClientStream[pb.BulkCreateLaptopsRequest]) (*connect.Response[pb.BulkCreateLaptopsResponse], error) {
+	serverStream := &bulkCreateLaptopsStream{
+		serverStream: serverStream{
+			ctx:     incomingContext(ctx, stream.RequestHeader()),
+			header:  make(http.Header),
+			trailer: make(http.Header),
+		},
+		stream: stream,
+	}
+
+	err := handler.server.BulkCreateLaptops(serverStream)
+	if err != nil {
+		return nil, connectError(err)
+	}
+
+	res := connect.NewResponse(serverStream.res)
+	copyHeader(res.Header(), serverStream.header)
+	copyHeader(res.Trailer(), serverStream.trailer)
+
+	return res, nil
+}
+
+// serverStream implements the grpc.ServerStream methods used by the laptop server,
+// the gRPC metadata is written to the Connect headers and trailers
+type serverStream struct {
+	ctx     context.Context
+	header  http.Header
+	trailer http.Header
+}
+
+func (stream *serverStream) SetHeader(md metadata.MD) error {
+	addMetadata(stream.header, md)
+	return nil
+}
+
+func (stream *serverStream) SendHeader(md metadata.MD) error {
+	return stream.SetHeader(md)
+}
+
+func (stream *serverStream) SetTrailer(md metadata.MD) {
+	addMetadata(stream.trailer, md)
+}
+
+func (stream *serverStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *serverStream) SendMsg(m interface{}) error {
+	return errors.New("SendMsg is not supported")
+}
+
+func (stream *serverStream) RecvMsg(m interface{}) error {
+	return errors.New("RecvMsg is not supported")
+}
+
+type searchLaptopStream struct {
+	serverStream
+	stream *connect.ServerStream[pb.SearchLaptopResponse]
+}
+
+func (stream *searchLaptopStream) Send(res *pb.SearchLaptopResponse) error {
+	return stream.stream.Send(res)
+}
+
+type uploadImageStream struct {
+	serverStream
+	stream *connect.ClientStream[pb.UploadImageRequest]
+	res    *pb.UploadImageResponse
+}
+
+func (stream *uploadImageStream) Recv() (*pb.UploadImageRequest, error) {
+	if stream.stream.Receive() {
+		return stream.stream.Msg(), nil
+	}
+
+	if err := stream.stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func (stream *uploadImageStream) SendAndClose(res *pb.UploadImageResponse) error {
+	stream.res = res
+	return nil
+}
+
+type downloadImageStream struct {
+	serverStream
+	stream *connect.ServerStream[pb.DownloadImageResponse]
+}
+
+func (stream *downloadImageStream) Send(res *pb.DownloadImageResponse) error {
+	return stream.stream.Send(res)
+}
+
+type bulkCreateLaptopsStream struct {
+	serverStream
+	stream *connect.ClientStream[pb.BulkCreateLaptopsRequest]
+	res    *pb.BulkCreateLaptopsResponse
+}
+
+func (stream *bulkCreateLaptopsStream) Recv() (*pb.BulkCreateLaptopsRequest, error) {
+	if stream.stream.Receive() {
+		return stream.stream.Msg(), nil
+	}
+
+	if err := stream.stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func (stream *bulkCreateLaptopsStream) SendAndClose(res *pb.BulkCreateLaptopsResponse) error {
+	stream.res = res
+	return nil
+}
+
+type rateLaptopStream struct {
+	serverStream
+	stream *connect.BidiStream[pb.RateLaptopRequest, pb.RateLaptopResponse]
+}
+
+func (stream *rateLaptopStream) Recv() (*pb.RateLaptopRequest, error) {
+	return stream.stream.Receive()
+}
+
+func (stream *rateLaptopStream) Send(res *pb.RateLaptopResponse) error {
+	return stream.stream.Send(res)
+}
+
+type watchLaptopsStream struct {
+	serverStream
+	stream *connect.ServerStream[pb.WatchLaptopsResponse]
+}
+
+func (stream *watchLaptopsStream) Send(res *pb.WatchLaptopsResponse) error {
+	return stream.stream.Send(res)
+}
+
+// connectError converts a gRPC status error to a Connect error with the same code
+func connectError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	connectErr := connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+
+	// keep the google.rpc error details so that the browser clients get them as well
+	for _, detail := range st.Proto().GetDetails() {
+		message, err := detail.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+
+		errorDetail, err := connect.NewErrorDetail(message)
+		if err != nil {
+			continue
+		}
+		connectErr.AddDetail(errorDetail)
+	}
+
+	return connectErr
+}
+
+// incomingContext exposes the request headers as gRPC metadata, like a gRPC server does
+func incomingContext(ctx context.Context, header http.Header) context.Context {
+	md := make(metadata.MD, len(header))
+	for key, values := range header {
+		md.Append(strings.ToLower(key), values...)
+	}
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func addMetadata(header http.Header, md metadata.MD) {
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+}
+
+func copyHeader(dst http.Header, src http.Header) {
+	for key, values := range src {
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package web_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/daffarg/grpc-pcbook/pb"
+	"github.com/daffarg/grpc-pcbook/pb/pbconnect"
+	"github.com/daffarg/grpc-pcbook/sample"
+	"github.com/daffarg/grpc-pcbook/service"
+	"github.com/daffarg/grpc-pcbook/web"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestWebCreateLaptopWithConnect(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	webServer := startTestWebServer(t, laptopStore, nil)
+	laptopClient := pbconnect.NewLaptopServiceClient(webServer.Client(), webServer.URL)
+
+	laptop := sample.NewLaptop()
+	res, err := laptopClient.CreateLaptop(context.Background(), connect.NewRequest(&pb.CreateLaptopRequest{Laptop: laptop}))
+	require.NoError(t, err)
+	require.Equal(t, laptop.GetId(), res.Msg.GetId())
+
+	savedLaptop, err := laptopStore.FindById(laptop.GetId())
+	require.NoError(t, err)
+	require.NotNil(t, savedLaptop)
+
+	// the gRPC status code is kept
+	invalidLaptop := sample.NewLaptop()
+	invalidLaptop.Id = "invalid-uuid"
+	_, err = laptopClient.CreateLaptop(context.Background(), connect.NewRequest(&pb.CreateLaptopRequest{Laptop: invalidLaptop}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+	// and so are the error details
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	require.NotEmpty(t, connectErr.Details())
+
+	detail, err := connectErr.Details()[0].Value()
+	require.NoError(t, err)
+	errorInfo, ok := detail.(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, service.ReasonInvalidLaptopId, errorInfo.GetReason())
+}
+
+func TestWebSearchLaptop(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	expectedIds := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+		expectedIds[laptop.GetId()] = true
+	}
+
+	webServer := startTestWebServer(t, laptopStore, nil)
+
+	testCases := []struct {
+		name    string
+		options []connect.ClientOption
+	}{
+		{name: "connect"},
+		{name: "grpc_web", options: []connect.ClientOption{connect.WithGRPCWeb()}},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// browsers use HTTP/1.1, so does the default test server client
+			laptopClient := pbconnect.NewLaptopServiceClient(webServer.Client(), webServer.URL, test.options...)
+
+			stream, err := laptopClient.SearchLaptop(context.Background(), connect.NewRequest(&pb.SearchLaptopRequest{
+				Filter: &pb.Filter{MaxPriceUsd: 5000},
+			}))
+			require.NoError(t, err)
+
+			found := 0
+			for stream.Receive() {
+				require.Contains(t, expectedIds, stream.Msg().GetLaptop().GetId())
+				found++
+			}
+			require.NoError(t, stream.Err())
+			require.NoError(t, stream.Close())
+			require.Equal(t, len(expectedIds), found)
+		})
+	}
+}
+
+func TestWebUploadImageWithGRPCOverH2C(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore := service.NewDiskImageStore(t.TempDir())
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	webServer := startTestWebServer(t, laptopStore, imageStore)
+
+	// cleartext HTTP/2 client on the same port
+	h2cClient := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+	laptopClient := pbconnect.NewLaptopServiceClient(h2cClient, webServer.URL, connect.WithGRPC())
+
+	stream := laptopClient.UploadImage(context.Background())
+	require.NoError(t, stream.Send(&pb.UploadImageRequest{
+		Data: &pb.UploadImageRequest_Info{Info: &pb.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".png"}},
+	}))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, stream.Send(&pb.UploadImageRequest{
+			Data: &pb.UploadImageRequest_ChunkData{ChunkData: make([]byte, 1024)},
+		}))
+	}
+
+	res, err := stream.CloseAndReceive()
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Msg.GetId())
+	require.EqualValues(t, 3*1024, res.Msg.GetSize())
+}
+
+func TestWebCORS(t *testing.T) {
+	t.Parallel()
+
+	webServer := startTestWebServer(t, service.NewInMemoryLaptopStore(), nil)
+	url := webServer.URL + pbconnect.LaptopServiceSearchLaptopProcedure
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+
+		res, err := webServer.Client().Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := preflight("http://frontend.example.com")
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Equal(t, "http://frontend.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	require.Contains(t, res.Header.Get("Access-Control-Allow-Headers"), "X-Grpc-Web")
+
+	res = preflight("http://evil.example.com")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func startTestWebServer(t *testing.T, laptopStore service.LaptopStore, imageStore service.ImageStore) *httptest.Server {
+	laptopServer := service.NewLaptopServer(laptopStore, imageStore, nil)
+	webServer := httptest.NewServer(web.NewHandler(laptopServer, []string{"http://frontend.example.com"}))
+	t.Cleanup(webServer.Close)
+
+	return webServer
+}